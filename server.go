@@ -1,13 +1,28 @@
 package main
 
 import (
+	"audit"
 	"auth"
+	"breach"
+	"bulk"
+	"context"
 	"db"
 	"email"
+	"errors"
+	"flag"
 	"fmt"
+	"geoip"
 	"log"
+	"os"
+	"path/filepath"
+	"purge"
 	"router"
+	"scim"
 	"signer"
+	"sms"
+	"strings"
+	"types"
+	"webhook"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +34,28 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	//Run schema migrations instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	//Bulk import or export accounts instead of running the server
+	if len(os.Args) > 1 && (os.Args[1] == "import" || os.Args[1] == "export") {
+		run := importAccounts
+		if os.Args[1] == "export" {
+			run = exportAccounts
+		}
+		if err := run(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	//Create JWT signer
 	signer := &signer.JWTSigner{}
 	if err := signer.Init(); err != nil {
@@ -26,26 +63,236 @@ func main() {
 		return
 	}
 
+	//Setup breached password screening
+	screener, err := breach.Screener{}.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	types.BreachScreener = screener
+
 	//Connect to database
-	db, err := db.MySQL{}.Init()
+	db, err := db.Open()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
 	//Setup email instance
-	emailer := email.Emailer{}.Init()
+	emailer, err := email.Emailer{}.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	//Setup text message and phone call codes
+	sender, err := sms.Sender{}.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	//Open the GeoIP database devices and sessions are located with
+	locator, err := geoip.Database{}.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	//Start delivering webhooks
+	webhook.Dispatcher{}.Init(db)
+
+	//Start sending queued emails
+	email.Outbox{}.Init(db, emailer.Transport)
+
+	//Setup audit log
+	auditor := audit.Auditor{}.Init(db)
+
+	//Start purging deleted accounts
+	purge.Purger{}.Init(db, auditor)
+
+	//Read how logins are scored
+	risk, err := auth.Risk{}.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	//Create authentication class
-	authentication := auth.Authenticate{}.Init(signer, db, emailer)
+	authentication := auth.Authenticate{}.Init(signer, db, emailer, auditor, locator, risk)
+
+	//Read who can register
+	registration, err := auth.Registration{}.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	//Read which operations need a recent login
+	stepUp, err := auth.StepUp{}.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	//Create authorization class
-	authorization := auth.Authorize{}.Init(signer, db, emailer)
+	authorization := auth.Authorize{}.Init(signer, db, emailer, auditor, registration, sender, stepUp)
+
+	//Setup SCIM provisioning
+	provisioner := scim.Provisioner{}.Init(db, auditor)
 
 	//Start router
-	err = router.Router{}.Init(authentication, authorization)
+	err = router.Router{}.Init(authentication, authorization, provisioner)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 }
+
+//migrate - usage: migrate [-dry-run] [status]
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print the SQL that would run without applying it")
+	flags.Parse(args)
+
+	store, err := db.Connect()
+	if err != nil {
+		return err
+	}
+
+	if flags.Arg(0) == "status" {
+		status, err := store.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Current version: %d\nLatest version: %d\n", status.Current, status.Latest)
+		for _, m := range status.Pending {
+			fmt.Printf("Pending: %04d_%s\n", m.Version, m.Name)
+		}
+		return nil
+	}
+
+	return store.Migrate(*dryRun, os.Stdout)
+}
+
+//importAccounts - usage: import [-format csv|json] [-dry-run] [-invite] file
+func importAccounts(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "csv or json, taken from the file extension by default")
+	dryRun := flags.Bool("dry-run", false, "validate every row and report without creating anything")
+	invite := flags.Bool("invite", false, "email each row an invitation instead of creating the account")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: import [-format csv|json] [-dry-run] [-invite] file")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(flags.Arg(0)), ".")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rows, err := bulk.ReadAccounts(file, *format)
+	if err != nil {
+		return err
+	}
+
+	//Plain text passwords are screened the same as when registering
+	screener, err := breach.Screener{}.Init()
+	if err != nil {
+		return err
+	}
+	types.BreachScreener = screener
+
+	store, err := connectSchema()
+	if err != nil {
+		return err
+	}
+
+	registration, err := auth.Registration{}.Init()
+	if err != nil {
+		return err
+	}
+	if *invite && !registration.InvitationsEnabled() {
+		return errors.New("Invitations cannot be sent while registration is closed")
+	}
+
+	emailer, err := email.Emailer{}.Init()
+	if err != nil {
+		return err
+	}
+
+	report, err := bulk.Importer{}.Init(store, emailer, registration.InvitationDuration).Import(context.Background(), rows, *dryRun, *invite, "")
+	if err != nil {
+		return err
+	}
+
+	event := &types.AuditEvent{Type: types.AuditAccountImport, UserAgent: "cli"}
+	event.Detail = fmt.Sprintf("created %d, invited %d, valid %d, failed %d", report.Created, report.Invited, report.Valid, report.Failed)
+	audit.Auditor{}.Init(store).Record(event, "", nil)
+
+	for _, row := range report.Rows {
+		if row.Status == types.ImportFailed {
+			fmt.Printf("Row %d (%s): %s\n", row.Row, row.Email, row.Reason)
+		}
+	}
+	fmt.Println(event.Detail)
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d rows failed", report.Failed)
+	}
+	return nil
+}
+
+//exportAccounts - usage: export [-format csv|json] [-password-hashes] [-deleted] [file]. Writes to stdout without a file
+func exportAccounts(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "csv", "csv or json")
+	hashes := flags.Bool("password-hashes", false, "include bcrypt password hashes so the accounts can be imported elsewhere")
+	deleted := flags.Bool("deleted", false, "export soft deleted accounts instead")
+	flags.Parse(args)
+
+	store, err := connectSchema()
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if flags.NArg() > 0 {
+		out, err = os.Create(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	request := &types.ExportAccountsRequest{Format: *format, PasswordHashes: *hashes}
+	request.Search.Deleted = *deleted
+	request.Search.Sort = types.AccountSortCreated
+
+	err = bulk.Importer{}.Init(store, nil, 0).Export(context.Background(), out, request)
+
+	event := &types.AuditEvent{Type: types.AuditAccountExport, UserAgent: "cli"}
+	if *hashes {
+		event.Detail = "with password hashes"
+	}
+	audit.Auditor{}.Init(store).Record(event, "", err)
+
+	return err
+}
+
+//connectSchema - connects to the SQL database for a command, refusing to run against a schema that is out of date
+func connectSchema() (*db.SQLStore, error) {
+	store, err := db.Connect()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.CheckSchema(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
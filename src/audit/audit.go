@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"context"
+	"dao"
+	"db"
+	"fmt"
+	"os"
+	"time"
+	"types"
+
+	"github.com/google/uuid"
+)
+
+//Auditor - records security events
+type Auditor struct {
+	DB db.Store
+}
+
+//Init - start audit service
+func (a Auditor) Init(db db.Store) *Auditor {
+	a.DB = db
+	return &a
+}
+
+//Record - saves an event with an outcome based on the result of the action.
+//An error means the action failed, a non empty res means it was rejected.
+//Failures to save are logged but never fail the action being audited.
+//Events are saved outside the request context so a client hanging up cannot stop its attempt being recorded.
+func (a *Auditor) Record(event *types.AuditEvent, res string, err error) {
+	event.ID = uuid.New().String()
+	event.Created = time.Now()
+
+	switch {
+	case err != nil:
+		event.Outcome = types.AuditOutcomeFailure
+		if event.Detail == "" {
+			event.Detail = err.Error()
+		}
+	case res != "":
+		event.Outcome = types.AuditOutcomeRejected
+		if event.Detail == "" {
+			event.Detail = res
+		}
+	default:
+		event.Outcome = types.AuditOutcomeSuccess
+	}
+
+	if err := (dao.AuditDAO{}).SaveEvent(context.Background(), event, a.DB); err != nil {
+		fmt.Fprintln(os.Stderr, "Audit Error: "+err.Error())
+	}
+}
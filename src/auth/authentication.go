@@ -1,11 +1,19 @@
 package auth
 
 import (
+	"audit"
+	"context"
 	"dao"
 	"db"
 	"email"
 	"errors"
+	"fmt"
+	"geoip"
+	"os"
 	"signer"
+	"strconv"
+	"strings"
+	"time"
 	"types"
 	"utils"
 
@@ -14,27 +22,93 @@ import (
 
 //Authenticate - Authenticate class
 type Authenticate struct {
-	DB      *db.MySQL
+	DB      db.Store
 	Sign    *signer.JWTSigner
 	Emailer *email.Emailer
+	Audit   *audit.Auditor
+	//GeoIP - locates the ips devices and sessions are used from
+	GeoIP *geoip.Database
+	//Risk - scores logins and decides if they are allowed
+	Risk *Risk
+	//MagicLinkDuration - how long a magic login link works for, from MAGIC_LINK_MINUTES
+	MagicLinkDuration time.Duration
+	//LoginCode settings from LOGIN_CODE_DIGITS, LOGIN_CODE_MINUTES and LOGIN_CODE_ATTEMPTS
+	LoginCodeDigits   int
+	LoginCodeDuration time.Duration
+	LoginCodeAttempts int
+	//LoginCodeFailures - wrong codes an account can enter in a day across every code it is sent, from LOGIN_CODE_MAX_FAILURES
+	LoginCodeFailures int
+	//Login codes and magic links an account can be sent an hour and a day, each, from EMAIL_LOGIN_HOURLY_LIMIT and EMAIL_LOGIN_DAILY_LIMIT
+	EmailLoginHourlyLimit int
+	EmailLoginDailyLimit  int
 }
 
 //Init - Start authentication service
-func (auth Authenticate) Init(jwt *signer.JWTSigner, db *db.MySQL, emailer *email.Emailer) *Authenticate {
+func (auth Authenticate) Init(jwt *signer.JWTSigner, db db.Store, emailer *email.Emailer, auditor *audit.Auditor, geo *geoip.Database, risk *Risk) *Authenticate {
 	auth.DB = db
 	auth.Sign = jwt
 	auth.Emailer = emailer
+	auth.Audit = auditor
+	auth.GeoIP = geo
+	auth.Risk = risk
+
+	minutes, err := strconv.Atoi(os.Getenv("MAGIC_LINK_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 15
+	}
+	auth.MagicLinkDuration = time.Duration(minutes) * time.Minute
+
+	digits, err := strconv.Atoi(os.Getenv("LOGIN_CODE_DIGITS"))
+	if err != nil || digits < 6 || digits > 8 {
+		digits = 6
+	}
+	auth.LoginCodeDigits = digits
+
+	minutes, err = strconv.Atoi(os.Getenv("LOGIN_CODE_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 10
+	}
+	auth.LoginCodeDuration = time.Duration(minutes) * time.Minute
+
+	attempts, err := strconv.Atoi(os.Getenv("LOGIN_CODE_ATTEMPTS"))
+	if err != nil || attempts <= 0 {
+		attempts = 5
+	}
+	auth.LoginCodeAttempts = attempts
+
+	failures, err := strconv.Atoi(os.Getenv("LOGIN_CODE_MAX_FAILURES"))
+	if err != nil || failures <= 0 {
+		failures = 10
+	}
+	auth.LoginCodeFailures = failures
+
+	hourly, err := strconv.Atoi(os.Getenv("EMAIL_LOGIN_HOURLY_LIMIT"))
+	if err != nil || hourly <= 0 {
+		hourly = 5
+	}
+	auth.EmailLoginHourlyLimit = hourly
+
+	daily, err := strconv.Atoi(os.Getenv("EMAIL_LOGIN_DAILY_LIMIT"))
+	if err != nil || daily <= 0 {
+		daily = 10
+	}
+	auth.EmailLoginDailyLimit = daily
+
 	return &auth
 }
 
 //RefreshAccessToken - attempts to refresh an access token
-func (auth Authenticate) RefreshAccessToken(tokens *types.AuthTokens) (string, error) {
+func (auth Authenticate) RefreshAccessToken(ctx context.Context, tokens *types.AuthTokens) (newToken string, err error) {
+	event := &types.AuditEvent{Type: types.AuditRefresh}
+	event.SetClient(tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
 	if tokens.RefreshToken == "" || tokens.AccessToken == "" {
 		return "", errors.New("refresh token or access token is empty")
 	}
 
 	//Verify the current access token.
-	_, err := auth.Sign.VerifyAccessToken(tokens.AccessToken)
+	_, err = auth.Sign.VerifyAccessToken(tokens.AccessToken)
 	if err != nil {
 		//Check if the access token is valid but has expired
 		if e, ok := err.(*jwt.ValidationError); ok && e.Errors != jwt.ValidationErrorExpired {
@@ -49,8 +123,13 @@ func (auth Authenticate) RefreshAccessToken(tokens *types.AuthTokens) (string, e
 		return "", err
 	}
 
+	//Impersonation tokens are short lived on purpose and can never be refreshed
+	if oldClaims.Act != nil {
+		return "", errors.New("impersonation tokens cannot be refreshed")
+	}
+
 	//Grab the refresh token provided by the user
-	token, err := dao.TokenDAO{}.GetRefreshToken(tokens.RefreshToken, auth.DB)
+	token, err := dao.TokenDAO{}.GetRefreshToken(ctx, tokens.RefreshToken, auth.DB)
 	if err != nil {
 		return "", err
 	}
@@ -66,27 +145,33 @@ func (auth Authenticate) RefreshAccessToken(tokens *types.AuthTokens) (string, e
 	}
 
 	//Get the account attached to the refresh token
-	account, err := dao.AccountDAO{}.GetAccountByID(token.AccountID, auth.DB)
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, token.AccountID, auth.DB)
 	if err != nil {
 		return "", err
 	}
+	event.ActorID = token.AccountID
 
 	//No account was found
 	if account == nil {
 		return "", errors.New("no account found from refresh token account id")
 	}
+	event.ActorEmail = account.Email
 
-	//Account has been disabled
+	//Account has been disabled or deleted
 	if account.Disabled {
 		return "", errors.New("Account is disabled: " + account.Email)
 	}
+	if account.Deleted != nil {
+		return "", errors.New("Account is deleted: " + account.Email)
+	}
 
 	//Fetch accounts permissions
 	account.GetAccountPermissions()
 
 	//If a device is attached to the refresh token or account has 2FA enabled then make sure it is still existing and active
+	var device *types.Device
 	if token.DeviceID != "" || account.TwoFA {
-		device, err := dao.DeviceDAO{}.GetDevice(token.DeviceID, auth.DB)
+		device, err = dao.DeviceDAO{}.GetDevice(ctx, token.DeviceID, auth.DB)
 		if err != nil {
 			return "", err
 		}
@@ -112,19 +197,35 @@ func (auth Authenticate) RefreshAccessToken(tokens *types.AuthTokens) (string, e
 		Roles:     account.Roles,
 	}
 
-	//Generate the access token
-	newToken, err := auth.Sign.CreateAccessToken(accountInfo)
+	//Generate the access token. It is as recent and strong as the login that started the session
+	newToken, err = auth.Sign.CreateAccessToken(accountInfo, oldClaims.Authentication())
 	if err != nil {
 		return "", err
 	}
 
+	//Record where the session and its device are being used from
+	client := auth.describeClient(tokens.Client)
+	err = dao.TokenDAO{}.TouchRefreshToken(ctx, token, client, auth.DB)
+	if err != nil {
+		return "", err
+	}
+	if device != nil {
+		err = dao.DeviceDAO{}.TouchDevice(ctx, device, client, auth.DB)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	return newToken, nil
 }
 
 //Login - Checks if login is valid
-func (auth Authenticate) Login(login *types.Login) (*types.LoginResponse, error) {
+func (auth Authenticate) Login(ctx context.Context, login *types.Login) (response *types.LoginResponse, err error) {
+	event := &types.AuditEvent{Type: types.AuditLogin, ActorEmail: login.Email}
+	event.SetClient(login.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
 
-	account, err := dao.AccountDAO{}.GetAccountByEmail(login.Email, auth.DB)
+	account, err := dao.AccountDAO{}.GetAccountByEmail(ctx, login.Email, auth.DB)
 	if err != nil {
 		return nil, err
 	}
@@ -133,11 +234,15 @@ func (auth Authenticate) Login(login *types.Login) (*types.LoginResponse, error)
 	if account == nil {
 		return nil, errors.New("email not found: " + login.Email)
 	}
+	event.ActorID = account.ID
 
-	//Account has been disabled
+	//Account has been disabled or deleted
 	if account.Disabled {
 		return nil, errors.New("Account is disabled: " + account.Email)
 	}
+	if account.Deleted != nil {
+		return nil, errors.New("Account is deleted: " + account.Email)
+	}
 
 	//Check if password matches hash
 	valid := utils.CheckPasswordHash(login.Password, account.Password)
@@ -145,8 +250,31 @@ func (auth Authenticate) Login(login *types.Login) (*types.LoginResponse, error)
 		return nil, errors.New("Invalid Password Attempt: " + account.FirstName + " " + account.LastName)
 	}
 
+	return auth.issueTokens(ctx, account, login.DeviceID, login.Client, signer.MethodPassword, event)
+}
+
+//issueTokens - signs tokens for an account that proved who it is with the method given. Admins, 2FA accounts and risky logins only get them on an active device,
+//otherwise a device is created and its code emailed. The device and session record the client they were used from
+func (auth Authenticate) issueTokens(ctx context.Context, account *types.Account, deviceID string, client types.Client, method string, event *types.AuditEvent) (*types.LoginResponse, error) {
 	//Get account roles
 	account.GetAccountPermissions()
+	details := auth.describeClient(client)
+
+	//Score the login against how the account usually logs in
+	risk, err := auth.assessRisk(ctx, account, deviceID, details)
+	if err != nil {
+		return nil, err
+	}
+
+	//Blocked logins fail the same as a wrong password so the caller cannot tell, the account is told instead
+	if risk.Decision == RiskBlock {
+		event.Detail = risk.String()
+		if err := auth.Emailer.LoginAlert(ctx, account, details, true, auth.DB); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("Login blocked: " + account.Email)
+	}
+	alert := risk.Decision != RiskAllow
 
 	accountInfo := &signer.AccountInfo{
 		ID:        account.ID,
@@ -156,76 +284,330 @@ func (auth Authenticate) Login(login *types.Login) (*types.LoginResponse, error)
 		Roles:     account.Roles,
 	}
 
-	//If account is ADMIN or above or 2FA is enabled then make sure device is verified.
-	if utils.Contains("ADMIN", account.Roles) || account.TwoFA {
+	//If account is ADMIN or above, 2FA is enabled or the login is risky then make sure device is verified.
+	if utils.Contains("ADMIN", account.Roles) || account.TwoFA || risk.Decision == RiskSecondFactor {
 
-		dm := dao.DeviceDAO{}
+		var device *types.Device
+		var tokens *signer.SignedResponse
 
-		device, err := dm.GetDevice(login.DeviceID, auth.DB)
-		if err != nil {
-			return nil, err
-		}
+		//Find or create the device and save its refresh token as one unit
+		err := auth.DB.InTx(ctx, func(tx db.Store) error {
+			dm := dao.DeviceDAO{}
 
-		//No device was found, need to create one.
-		if err == nil && device == nil {
-			device, err = dm.CreateDevice(account, auth.DB)
+			var err error
+			device, err = dm.GetDevice(ctx, deviceID, tx)
 			if err != nil {
-				return nil, err
+				return err
 			}
-		}
 
-		//If the device does not belong to the account.
-		//Create a new one for the account.
-		if account.ID != device.AccountID {
-			device, err = dm.CreateDevice(account, auth.DB)
-			if err != nil {
-				return nil, err
+			//No device was found, or it does not belong to the account.
+			//Create a new one for the account.
+			if device == nil || account.ID != device.AccountID {
+				device, err = dm.CreateDevice(ctx, account, details, tx)
+				if err != nil {
+					return err
+				}
+			} else if err = dm.TouchDevice(ctx, device, details, tx); err != nil {
+				return err
 			}
-		}
 
-		//If device is not setup, then only send device info
-		if !device.Active {
-			//Send new device email
-			err := auth.Emailer.NewDeviceEmail(account, device)
-			if err != nil {
-				return nil, errors.New("New Device Email failed sending : " + err.Error())
+			//Device is not setup yet, no tokens until it is. Queue the code so it is only sent if the device is saved
+			if !device.Active {
+				return auth.Emailer.NewDeviceEmail(ctx, account, device, tx)
 			}
 
-			return &types.LoginResponse{DeviceActive: device.Active, DeviceID: device.ID, Tokens: nil}, nil
-		}
+			//Device is setup, send device info and JWT tokens
+			tokens, err = auth.Sign.SignNewJWT(accountInfo, &signer.Authentication{Time: time.Now(), Methods: []string{method, signer.MethodMFA}})
+			if err != nil {
+				return err
+			}
 
-		//Device is setup, send device info and JWT tokens
-		tokens, err := auth.Sign.SignNewJWT(accountInfo)
+			//Save refresh token to DB
+			_, err = dao.TokenDAO{}.SaveRefreshToken(ctx, account, tokens, device.ID, details, tx)
+			if err != nil || !alert {
+				return err
+			}
+			return auth.Emailer.LoginAlert(ctx, account, details, false, tx)
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		//Save refresh token to DB
-		_, err = dao.TokenDAO{}.SaveRefreshToken(account, tokens, device.ID, auth.DB)
-		if err != nil {
-			return nil, err
+		//If device is not setup, then only send device info
+		if !device.Active {
+			event.Detail = risk.String() + ", device activation required"
+			return &types.LoginResponse{DeviceActive: device.Active, DeviceID: device.ID, Tokens: nil}, nil
 		}
 
+		event.Detail = risk.String()
 		return &types.LoginResponse{DeviceActive: device.Active, DeviceID: device.ID, Tokens: tokens}, nil
 	}
 
-	tokens, err := auth.Sign.SignNewJWT(accountInfo)
+	tokens, err := auth.Sign.SignNewJWT(accountInfo, &signer.Authentication{Time: time.Now(), Methods: []string{method}})
 	if err != nil {
 		return nil, err
 	}
 
-	//Save refresh token to DB
-	_, err = dao.TokenDAO{}.SaveRefreshToken(account, tokens, "", auth.DB)
+	//Save refresh token to DB, with the alert if the login was unusual
+	err = auth.DB.InTx(ctx, func(tx db.Store) error {
+		_, err := dao.TokenDAO{}.SaveRefreshToken(ctx, account, tokens, "", details, tx)
+		if err != nil || !alert {
+			return err
+		}
+		return auth.Emailer.LoginAlert(ctx, account, details, false, tx)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	event.Detail = risk.String()
 	return &types.LoginResponse{DeviceActive: true, DeviceID: "", Tokens: tokens}, nil
 }
 
+//describeClient - parses the user agent of a client and locates its ip. A failed lookup only loses the location
+func (auth Authenticate) describeClient(client types.Client) *types.ClientDetails {
+	//Same limit as the audit log column
+	if len(client.UserAgent) > 512 {
+		client.UserAgent = client.UserAgent[:512]
+	}
+
+	details := &types.ClientDetails{Client: client}
+	details.Browser, details.OS = utils.ParseUserAgent(client.UserAgent)
+
+	location, err := auth.GeoIP.Lookup(client.IP)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GeoIP Error: "+err.Error())
+	}
+	if location != nil {
+		details.Country = location.Country
+		details.Location = location.String()
+	}
+	return details
+}
+
+//RequestMagicLink - emails a single use login link if the email belongs to an account that can log in.
+//The nonce returned binds the link to the browser asking for it. It is returned either way so the response does not show whether the account exists
+func (auth Authenticate) RequestMagicLink(ctx context.Context, request *types.MagicLinkRequest) (nonce string, err error) {
+	res := ""
+	event := &types.AuditEvent{Type: types.AuditMagicLinkSend, ActorEmail: request.Email}
+	event.SetClient(request.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	nonce, err = utils.RandomSecret(32)
+	if err != nil {
+		return "", err
+	}
+
+	account, err := dao.AccountDAO{}.GetAccountByEmail(ctx, request.Email, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	//Nothing is sent, but the caller cannot tell
+	if account == nil {
+		res = "email not found: " + request.Email
+		return nonce, nil
+	}
+	event.ActorID = account.ID
+	if account.Disabled || account.Deleted != nil {
+		res = "Account cannot log in: " + account.Email
+		return nonce, nil
+	}
+
+	res, err = auth.checkSendLimit(ctx, types.AuditMagicLinkSend, account.ID)
+	if err != nil {
+		return "", err
+	}
+	if res != "" {
+		return nonce, nil
+	}
+
+	//Queue the link with the link it sends so it is only emailed if saved
+	err = auth.DB.InTx(ctx, func(tx db.Store) error {
+		link, token, err := dao.MagicLinkDAO{}.CreateMagicLink(ctx, account.ID, nonce, auth.MagicLinkDuration, tx)
+		if err != nil {
+			return err
+		}
+		return auth.Emailer.MagicLink(ctx, account, link, token, tx)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return nonce, nil
+}
+
+//MagicLogin - logs in with a magic link from the browser that requested it. Devices and tokens are handled the same as Login
+func (auth Authenticate) MagicLogin(ctx context.Context, request *types.MagicLoginRequest) (response *types.LoginResponse, res string, err error) {
+	event := &types.AuditEvent{Type: types.AuditMagicLinkLogin}
+	event.SetClient(request.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	link, res, err := dao.MagicLinkDAO{}.UseMagicLink(ctx, request.ID, request.Token, request.Nonce, auth.DB)
+	if err != nil || res != "" {
+		return nil, res, err
+	}
+	event.ActorID = link.AccountID
+
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, link.AccountID, auth.DB)
+	if err != nil {
+		return nil, "", err
+	}
+	if account == nil {
+		return nil, "", errors.New("no account found for magic link: " + link.ID)
+	}
+	event.ActorEmail = account.Email
+
+	//Account has been disabled or deleted since the link was sent
+	if account.Disabled {
+		return nil, "", errors.New("Account is disabled: " + account.Email)
+	}
+	if account.Deleted != nil {
+		return nil, "", errors.New("Account is deleted: " + account.Email)
+	}
+
+	response, err = auth.issueTokens(ctx, account, request.DeviceID, request.Client, signer.MethodOTP, event)
+	if err != nil {
+		return nil, "", err
+	}
+	return response, "", nil
+}
+
+//RequestLoginCode - emails a login code if the email belongs to an account that can log in.
+//Responds the same either way so it does not show whether the account exists
+func (auth Authenticate) RequestLoginCode(ctx context.Context, request *types.LoginCodeRequest) (err error) {
+	res := ""
+	event := &types.AuditEvent{Type: types.AuditLoginCodeSend, ActorEmail: request.Email}
+	event.SetClient(request.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := dao.AccountDAO{}.GetAccountByEmail(ctx, request.Email, auth.DB)
+	if err != nil {
+		return err
+	}
+
+	//Nothing is sent, but the caller cannot tell
+	if account == nil {
+		res = "email not found: " + request.Email
+		return nil
+	}
+	event.ActorID = account.ID
+	if account.Disabled || account.Deleted != nil {
+		res = "Account cannot log in: " + account.Email
+		return nil
+	}
+
+	//Each code comes with fresh attempts, so this also limits how many guesses can be made
+	res, err = auth.checkSendLimit(ctx, types.AuditLoginCodeSend, account.ID)
+	if err != nil || res != "" {
+		return err
+	}
+
+	//Queue the email with the code it sends so it is only emailed if saved
+	return auth.DB.InTx(ctx, func(tx db.Store) error {
+		code, secret, err := dao.LoginCodeDAO{}.CreateLoginCode(ctx, account.ID, auth.LoginCodeDigits, auth.LoginCodeDuration, tx)
+		if err != nil {
+			return err
+		}
+		return auth.Emailer.LoginCode(ctx, account, code, secret, tx)
+	})
+}
+
+//checkSendLimit - returns a reason if the account has been sent too many login emails of the type given in the last hour or day
+func (auth Authenticate) checkSendLimit(ctx context.Context, eventType string, accountID string) (string, error) {
+	now := time.Now()
+	hourly, err := auth.countEvents(ctx, eventType, accountID, types.AuditOutcomeSuccess, now.Add(-1*time.Hour), auth.EmailLoginHourlyLimit)
+	if err != nil {
+		return "", err
+	}
+	daily, err := auth.countEvents(ctx, eventType, accountID, types.AuditOutcomeSuccess, now.Add(-24*time.Hour), auth.EmailLoginDailyLimit)
+	if err != nil {
+		return "", err
+	}
+	if hourly >= auth.EmailLoginHourlyLimit || daily >= auth.EmailLoginDailyLimit {
+		return "Too many login emails sent to this account, try again later", nil
+	}
+	return "", nil
+}
+
+//countEvents - returns how many events of a type and outcome an account has had since the time given, counting no further than limit
+func (auth Authenticate) countEvents(ctx context.Context, eventType string, accountID string, outcome string, since time.Time, limit int) (int, error) {
+	events, _, err := dao.AuditDAO{}.SearchEvents(ctx, &types.AuditSearchRequest{
+		Type:    eventType,
+		ActorID: accountID,
+		Outcome: outcome,
+		Since:   since,
+		Limit:   limit,
+	}, auth.DB)
+	if err != nil {
+		return 0, err
+	}
+	return len(*events), nil
+}
+
+//CodeLogin - logs in with an emailed login code. Devices and tokens are handled the same as Login
+func (auth Authenticate) CodeLogin(ctx context.Context, request *types.CodeLoginRequest) (response *types.LoginResponse, res string, err error) {
+	event := &types.AuditEvent{Type: types.AuditLoginCodeLogin, ActorEmail: request.Email}
+	event.SetClient(request.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := dao.AccountDAO{}.GetAccountByEmail(ctx, request.Email, auth.DB)
+	if err != nil {
+		return nil, "", err
+	}
+
+	//Same reason as a wrong code so it does not show whether the account exists
+	if account == nil {
+		event.Detail = "email not found: " + request.Email
+		return nil, "Code is invalid or has expired", nil
+	}
+	event.ActorID = account.ID
+
+	//Asking for a new code resets its attempts, so wrong codes are also capped across all of them
+	failures, err := auth.countEvents(ctx, types.AuditLoginCodeLogin, account.ID, types.AuditOutcomeRejected, time.Now().Add(-24*time.Hour), auth.LoginCodeFailures)
+	if err != nil {
+		return nil, "", err
+	}
+	if failures >= auth.LoginCodeFailures {
+		return nil, "Too many wrong codes, try again later", nil
+	}
+
+	res, err = dao.LoginCodeDAO{}.UseLoginCode(ctx, account.ID, strings.TrimSpace(request.Code), auth.LoginCodeAttempts, auth.DB)
+	if err != nil || res != "" {
+		return nil, res, err
+	}
+
+	//Account has been disabled or deleted since the code was sent
+	if account.Disabled {
+		return nil, "", errors.New("Account is disabled: " + account.Email)
+	}
+	if account.Deleted != nil {
+		return nil, "", errors.New("Account is deleted: " + account.Email)
+	}
+
+	response, err = auth.issueTokens(ctx, account, request.DeviceID, request.Client, signer.MethodOTP, event)
+	if err != nil {
+		return nil, "", err
+	}
+	return response, "", nil
+}
+
 //Logout - removes users session from system
-func (auth Authenticate) Logout(tokens *types.AuthTokens) error {
-	err := dao.TokenDAO{}.DeleteRefreshToken(tokens, auth.DB)
+func (auth Authenticate) Logout(ctx context.Context, tokens *types.AuthTokens) (err error) {
+	event := &types.AuditEvent{Type: types.AuditLogout}
+	event.SetClient(tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	//Find out who is logging out before the token is gone
+	token, err := dao.TokenDAO{}.GetRefreshToken(ctx, tokens.RefreshToken, auth.DB)
+	if err != nil {
+		return err
+	}
+	if token != nil {
+		event.ActorID = token.AccountID
+	}
+
+	err = dao.TokenDAO{}.DeleteRefreshToken(ctx, tokens, auth.DB)
 	if err != nil {
 		return err
 	}
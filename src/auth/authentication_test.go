@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"audit"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"db"
+	"email"
+	"encoding/pem"
+	"geoip"
+	"os"
+	"path/filepath"
+	"signer"
+	"testing"
+	"time"
+	"types"
+	"utils"
+)
+
+//testPassword - a password every test account is created with
+const testPassword = "abc12345Zq!x"
+
+//newTestSigner - returns a signer with a fresh key pair
+func newTestSigner(t *testing.T) *signer.JWTSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOKENS_PRIVATE_KEY", privatePath)
+	t.Setenv("TOKENS_PUBLIC_KEY", publicPath)
+
+	jwt := &signer.JWTSigner{}
+	if err := jwt.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return jwt
+}
+
+//newTestAuthenticate - returns an Authenticate on an empty memory store with emails kept in memory
+func newTestAuthenticate(t *testing.T) (*Authenticate, *db.Memory) {
+	t.Setenv("EMAIL_TRANSPORT", "memory")
+	t.Setenv("GEOIP_FILE", "")
+	t.Setenv("RISK_POLICY_FILE", "")
+	t.Setenv("RISK_IP_LIST", "")
+
+	store := db.Memory{}.Init()
+	emailer, err := email.Emailer{}.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	geo, err := geoip.Database{}.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	risk, err := Risk{}.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Authenticate{}.Init(newTestSigner(t), store, emailer, audit.Auditor{}.Init(store), geo, risk), store
+}
+
+//newTestAccount - saves an account with testPassword
+func newTestAccount(t *testing.T, store db.Store, id string, role int) *types.Account {
+	hash, err := utils.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := &types.Account{ID: id, Email: id + "@example.com", Password: hash, FirstName: "Test", LastName: "Account", Phone: "555-555-1234", Role: role, Created: time.Now()}
+	if err := store.InsertAccount(context.Background(), account); err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	auth, store := newTestAuthenticate(t)
+
+	newTestAccount(t, store, "user", 100)
+	newTestAccount(t, store, "admin", 999)
+	disabled := newTestAccount(t, store, "disabled", 100)
+	if err := store.SetDisabled(ctx, disabled.ID, true, "test"); err != nil {
+		t.Fatal(err)
+	}
+	deleted := newTestAccount(t, store, "deleted", 100)
+	now := time.Now()
+	if err := store.SetDeleted(ctx, deleted.ID, &now); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		tokens   bool
+		device   bool
+		fails    bool
+	}{
+		{name: "right password", email: "user@example.com", password: testPassword, tokens: true},
+		{name: "wrong password", email: "user@example.com", password: testPassword + "x", fails: true},
+		{name: "unknown email", email: "nobody@example.com", password: testPassword, fails: true},
+		{name: "disabled account", email: "disabled@example.com", password: testPassword, fails: true},
+		{name: "deleted account", email: "deleted@example.com", password: testPassword, fails: true},
+		{name: "admin needs a device", email: "admin@example.com", password: testPassword, device: true},
+	}
+
+	for _, test := range tests {
+		response, err := auth.Login(ctx, &types.Login{Email: test.email, Password: test.password})
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if (response.Tokens != nil) != test.tokens {
+			t.Errorf("%s: got tokens %v, expected %v", test.name, response.Tokens != nil, test.tokens)
+		}
+		if (response.DeviceID != "" && !response.DeviceActive) != test.device {
+			t.Errorf("%s: got device activation %v, expected %v", test.name, !response.DeviceActive, test.device)
+		}
+	}
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	ctx := context.Background()
+	auth, store := newTestAuthenticate(t)
+
+	account := newTestAccount(t, store, "user", 100)
+	newTestAccount(t, store, "other", 100)
+
+	login := func(email string) *signer.SignedResponse {
+		response, err := auth.Login(ctx, &types.Login{Email: email, Password: testPassword})
+		if err != nil || response.Tokens == nil {
+			t.Fatalf("login %s: %v", email, err)
+		}
+		return response.Tokens
+	}
+	tokens := login("user@example.com")
+	other := login("other@example.com")
+
+	tests := []struct {
+		name    string
+		access  string
+		refresh string
+		fails   bool
+	}{
+		{name: "valid tokens", access: tokens.AccessToken, refresh: tokens.RefreshToken},
+		{name: "no refresh token", access: tokens.AccessToken, fails: true},
+		{name: "unknown refresh token", access: tokens.AccessToken, refresh: "unknown", fails: true},
+		{name: "access token of another account", access: other.AccessToken, refresh: tokens.RefreshToken, fails: true},
+		{name: "tampered access token", access: tokens.AccessToken + "x", refresh: tokens.RefreshToken, fails: true},
+	}
+
+	for _, test := range tests {
+		token, err := auth.RefreshAccessToken(ctx, &types.AuthTokens{AccessToken: test.access, RefreshToken: test.refresh})
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		claims, err := auth.Sign.VerifyAccessToken(token)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if claims.ID != account.ID {
+			t.Errorf("%s: token is for %s, expected %s", test.name, claims.ID, account.ID)
+		}
+	}
+}
@@ -1,67 +1,381 @@
 package auth
 
 import (
+	"audit"
+	"bulk"
+	"context"
+	"crypto/subtle"
 	"dao"
 	"db"
 	"email"
+	"encoding/json"
 	"errors"
+	"io"
+	"net/url"
 	"signer"
+	"sms"
+	"strconv"
+	"strings"
+	"time"
 	"types"
 	"utils"
+
+	"github.com/dgrijalva/jwt-go"
 )
 
 //Authorize - Authorize class
 type Authorize struct {
-	DB      *db.MySQL
-	Sign    *signer.JWTSigner
-	Emailer *email.Emailer
+	DB           db.Store
+	Sign         *signer.JWTSigner
+	Emailer      *email.Emailer
+	Audit        *audit.Auditor
+	Registration *Registration
+	SMS          *sms.Sender
+	StepUp       *StepUp
 }
 
 //Init - Start Authorize service
-func (auth Authorize) Init(jwt *signer.JWTSigner, db *db.MySQL, emailer *email.Emailer) *Authorize {
+func (auth Authorize) Init(jwt *signer.JWTSigner, db db.Store, emailer *email.Emailer, auditor *audit.Auditor, registration *Registration, sender *sms.Sender, stepUp *StepUp) *Authorize {
 	auth.DB = db
 	auth.Sign = jwt
 	auth.Emailer = emailer
+	auth.Audit = auditor
+	auth.Registration = registration
+	auth.SMS = sender
+	auth.StepUp = stepUp
 	return &auth
 }
 
-//CheckAccessToken - verifies access token is valid
-func (auth Authorize) CheckAccessToken(tokens *types.AuthTokens) (*signer.AccessClaims, error) {
+//newEvent - creates an audit event for a request
+func (auth Authorize) newEvent(eventType string, client types.Client) *types.AuditEvent {
+	event := &types.AuditEvent{Type: eventType}
+	event.SetClient(client)
+	return event
+}
+
+//CheckAccessToken - verifies access token is valid. Personal access tokens are accepted too if they have the scope given,
+//ScopeSession only accepts a login session
+func (auth Authorize) CheckAccessToken(ctx context.Context, tokens *types.AuthTokens, scope string) (*signer.AccessClaims, error) {
+	if (dao.PersonalTokenDAO{}).IsPersonalToken(tokens.AccessToken) {
+		return auth.checkPersonalToken(ctx, tokens.AccessToken, scope)
+	}
+
 	result, err := auth.Sign.VerifyAccessToken(tokens.AccessToken)
 	if err != nil {
 		return nil, err
 	}
 
+	if result.Act != nil {
+		if scope == types.ScopeSession {
+			return nil, errors.New("Impersonation tokens cannot be used for this request")
+		}
+
+		//Stopping an impersonation revokes its token straight away
+		active, err := dao.ImpersonationDAO{}.IsActive(ctx, result.Id, auth.DB)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, utils.ErrInvalidToken
+		}
+	}
+
+	//Disabling or deleting an account revokes its tokens straight away, not when they expire
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, result.ID, auth.DB)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.Disabled || account.Deleted != nil {
+		return nil, utils.ErrInvalidToken
+	}
+
 	return result, nil
 }
 
-//RegisterAccount - register a new account
-func (auth Authorize) RegisterAccount(tokens *types.AuthTokens, newAccount *types.Account) (string, error) {
+//checkPersonalToken - verifies a personal access token and returns the current details of its account as claims
+func (auth Authorize) checkPersonalToken(ctx context.Context, bearer string, scope string) (*signer.AccessClaims, error) {
+	token, err := dao.PersonalTokenDAO{}.VerifyToken(ctx, bearer, auth.DB)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, utils.ErrInvalidToken
+	}
+
+	if scope == types.ScopeSession {
+		return nil, errors.New("Personal access tokens cannot be used for this request")
+	}
+	if !token.HasScope(scope) {
+		return nil, errors.New("Personal access token is missing the " + scope + " scope")
+	}
+
+	//Tokens stop working as soon as their account cannot log in
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, token.AccountID, auth.DB)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.Disabled || account.Deleted != nil {
+		return nil, utils.ErrInvalidToken
+	}
+	account.GetAccountPermissions()
+
+	return &signer.AccessClaims{
+		StandardClaims: &jwt.StandardClaims{ExpiresAt: token.Expires.Unix(), Id: token.ID},
+		AccountInfo: &signer.AccountInfo{
+			ID:        account.ID,
+			FirstName: account.FirstName,
+			LastName:  account.LastName,
+			Email:     account.Email,
+			Roles:     account.Roles,
+		},
+	}, nil
+}
+
+//RegisterAccount - register a new account. Admins can create accounts in any registration mode, everyone else needs an invitation or an allowed email
+func (auth Authorize) RegisterAccount(ctx context.Context, tokens *types.AuthTokens, request *types.RegisterRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditRegister, tokens.Client)
+	event.ActorEmail = request.Email
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	newAccount := &request.Account
+
+	admin := false
+	if tokens.AccessToken != "" {
+		account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+		if err != nil {
+			return "", err
+		}
+		event.SetActor(account)
+		admin = utils.Contains("ADMIN", account.Roles)
+	}
+
+	var invitation *types.Invitation
+	if request.InvitationID != "" {
+		if !auth.Registration.InvitationsEnabled() {
+			return "Registration is closed", nil
+		}
+
+		invitation, err = dao.InvitationDAO{}.GetInvitation(ctx, request.InvitationID, auth.DB)
+		if err != nil {
+			return "", err
+		}
+		if invitation == nil || subtle.ConstantTimeCompare([]byte(invitation.Token), []byte(request.InvitationToken)) != 1 {
+			return "Invitation is invalid", nil
+		}
+		if invitation.Accepted != nil {
+			return "Invitation has already been used", nil
+		}
+		if invitation.Expires.Before(time.Now()) {
+			return "Invitation has expired", nil
+		}
+		if !strings.EqualFold(invitation.Email, newAccount.Email) {
+			return "Invitation was sent to a different email", nil
+		}
+	}
+
+	if !admin {
+		if res := auth.Registration.Allows(newAccount.Email, invitation != nil); res != "" {
+			return res, nil
+		}
 
-	//Get newAccount Roles
-	newAccount.GetAccountPermissions()
+		//The organization decides the SCIM directory, groups and branding an account belongs to, so only an admin or an invitation sets it
+		newAccount.Organization = ""
+	}
 
-	res, err := dao.AccountDAO{}.CreateAccount(newAccount, auth.DB)
+	res, err = dao.AccountDAO{}.CreateAccount(ctx, newAccount, invitation, auth.DB)
 	if err != nil {
 		return "", err
 	}
+	if event.ActorID == "" {
+		event.ActorID = newAccount.ID
+	}
+	event.TargetID = newAccount.ID
 
 	return res, nil
 }
 
+//ImportAccounts - creates accounts, or sends invitations, from CSV or JSON rows and reports on each row
+func (auth Authorize) ImportAccounts(ctx context.Context, tokens *types.AuthTokens, request *types.ImportAccountsRequest) (report *types.ImportReport, res string, err error) {
+	event := auth.newEvent(types.AuditAccountImport, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return nil, "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	if request.Invite && !auth.Registration.InvitationsEnabled() {
+		return nil, "Invitations cannot be sent while registration is closed", nil
+	}
+
+	rows := request.Accounts
+	if request.Format == types.BulkFormatCSV {
+		rows, err = bulk.ReadAccounts(strings.NewReader(request.Data), types.BulkFormatCSV)
+		if err != nil {
+			return nil, "Invalid CSV: " + err.Error(), nil
+		}
+	} else if request.Format != types.BulkFormatJSON {
+		return nil, "Unknown format: " + request.Format, nil
+	}
+	if len(rows) > types.BulkMaxImportRows {
+		return nil, "Imports are limited to " + strconv.Itoa(types.BulkMaxImportRows) + " rows", nil
+	}
+
+	report, err = bulk.Importer{}.Init(auth.DB, auth.Emailer, auth.Registration.InvitationDuration).Import(ctx, rows, request.DryRun, request.Invite, account.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	event.Detail = "created " + strconv.Itoa(report.Created) + ", invited " + strconv.Itoa(report.Invited) + ", valid " + strconv.Itoa(report.Valid) + ", failed " + strconv.Itoa(report.Failed)
+
+	return report, "", nil
+}
+
+//ExportAccounts - writes every account matching the search as CSV or JSON
+func (auth Authorize) ExportAccounts(ctx context.Context, tokens *types.AuthTokens, request *types.ExportAccountsRequest, w io.Writer) (err error) {
+	event := auth.newEvent(types.AuditAccountExport, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+	if request.PasswordHashes {
+		event.Detail = "with password hashes"
+
+		//Hashes can be cracked offline, so they need a fresh login with a second factor
+		if err := auth.StepUp.Check(account, StepUpPasswordHashes); err != nil {
+			return err
+		}
+	}
+
+	return bulk.Importer{}.Init(auth.DB, auth.Emailer, auth.Registration.InvitationDuration).Export(ctx, w, request)
+}
+
+//CreateInvitation - invites an email to register and sends it the invitation
+func (auth Authorize) CreateInvitation(ctx context.Context, tokens *types.AuthTokens, request *types.InvitationRequest) (invitation *types.Invitation, res string, err error) {
+	event := auth.newEvent(types.AuditInviteCreate, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return nil, "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	if !auth.Registration.InvitationsEnabled() {
+		return nil, "Invitations cannot be sent while registration is closed", nil
+	}
+
+	//Queue the invitation email with the invitation so nobody is invited without being told
+	err = auth.DB.InTx(ctx, func(tx db.Store) error {
+		invitation, res, err = dao.InvitationDAO{}.CreateInvitation(ctx, request, account.ID, auth.Registration.InvitationDuration, tx)
+		if err != nil || res != "" {
+			return err
+		}
+		return auth.Emailer.InviteAccount(ctx, invitation, tx)
+	})
+	if err != nil || res != "" {
+		return nil, res, err
+	}
+	event.TargetID = invitation.ID
+
+	return invitation, "", nil
+}
+
+//GetInvitations - returns invitations, newest first
+func (auth Authorize) GetInvitations(ctx context.Context, tokens *types.AuthTokens, request *types.InvitationsRequest) (invitations *[]types.Invitation, err error) {
+	event := auth.newEvent(types.AuditInviteList, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	return dao.InvitationDAO{}.GetInvitations(ctx, request, auth.DB)
+}
+
+//RevokeInvitation - removes an invitation that has not been used yet
+func (auth Authorize) RevokeInvitation(ctx context.Context, tokens *types.AuthTokens, request *types.InvitationIDRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditInviteRevoke, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	invitation, err := dao.InvitationDAO{}.GetInvitation(ctx, request.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+	if invitation == nil {
+		return "", errors.New("No invitation was found: " + request.ID)
+	}
+	if invitation.Accepted != nil {
+		return "Invitation has already been used", nil
+	}
+
+	err = dao.InvitationDAO{}.RevokeInvitation(ctx, invitation, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
 //DeleteAccount - deletes an account
-func (auth Authorize) DeleteAccount(tokens *types.AuthTokens, del *types.DeleteAccountRequest) (string, error) {
-	account, err := auth.CheckAccessToken(tokens)
+func (auth Authorize) DeleteAccount(ctx context.Context, tokens *types.AuthTokens, del *types.DeleteAccountRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditAccountDelete, tokens.Client)
+	event.TargetID = del.ID
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
 	if err != nil {
 		return "", err
 	}
+	event.SetActor(account)
 
 	//Only Accounts with ADMIN privliges can make this request
 	if !utils.Contains("ADMIN", account.Roles) {
 		return "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
 	}
 
-	delAccount, err := dao.AccountDAO{}.GetAccountByID(del.ID, auth.DB)
+	if err := auth.StepUp.Check(account, event.Type); err != nil {
+		return "", err
+	}
+
+	delAccount, err := dao.AccountDAO{}.GetAccountByID(ctx, del.ID, auth.DB)
 	if err != nil {
 		return "", err
 	}
@@ -74,8 +388,133 @@ func (auth Authorize) DeleteAccount(tokens *types.AuthTokens, del *types.DeleteA
 	delAccount.GetAccountPermissions()
 
 	//DO delete checking here. If user requesting is allowed to delete this account
+	if delAccount.ID == account.ID {
+		return "You cannot delete your own account", nil
+	}
+	if delAccount.Deleted != nil {
+		return "Account is already deleted", nil
+	}
+
+	err = dao.AccountDAO{}.DeleteAccount(ctx, delAccount, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+//RestoreAccount - brings back a deleted account before it is purged
+func (auth Authorize) RestoreAccount(ctx context.Context, tokens *types.AuthTokens, request *types.AccountIDRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditAccountRestore, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, res, err) }()
 
-	err = dao.AccountDAO{}.DeleteAccount(delAccount, auth.DB)
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	restoreAccount, err := dao.AccountDAO{}.GetAccountByID(ctx, request.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	//Purged accounts are gone for good
+	if restoreAccount == nil {
+		return "", errors.New("No account found")
+	}
+
+	if restoreAccount.Deleted == nil {
+		return "Account is not deleted", nil
+	}
+
+	err = dao.AccountDAO{}.RestoreAccount(ctx, restoreAccount, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+//DisableAccount - stops an account from logging in until it is enabled
+func (auth Authorize) DisableAccount(ctx context.Context, tokens *types.AuthTokens, request *types.DisableAccountRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditAccountDisable, tokens.Client)
+	event.TargetID = request.ID
+	event.Detail = request.Reason
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	disableAccount, err := dao.AccountDAO{}.GetAccountByID(ctx, request.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	if disableAccount == nil {
+		return "", errors.New("No account found")
+	}
+
+	if disableAccount.ID == account.ID {
+		return "You cannot disable your own account", nil
+	}
+	if disableAccount.Disabled {
+		return "Account is already disabled", nil
+	}
+
+	res, err = dao.AccountDAO{}.DisableAccount(ctx, disableAccount, request.Reason, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	return res, nil
+}
+
+//EnableAccount - lets a disabled account log in again
+func (auth Authorize) EnableAccount(ctx context.Context, tokens *types.AuthTokens, request *types.AccountIDRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditAccountEnable, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	enableAccount, err := dao.AccountDAO{}.GetAccountByID(ctx, request.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	if enableAccount == nil {
+		return "", errors.New("No account found")
+	}
+
+	if !enableAccount.Disabled {
+		return "Account is not disabled", nil
+	}
+
+	err = dao.AccountDAO{}.EnableAccount(ctx, enableAccount, auth.DB)
 	if err != nil {
 		return "", err
 	}
@@ -84,13 +523,13 @@ func (auth Authorize) DeleteAccount(tokens *types.AuthTokens, del *types.DeleteA
 }
 
 //GetAccount - returns the account of the user requesting
-func (auth Authorize) GetAccount(tokens *types.AuthTokens) (interface{}, error) {
-	result, err := auth.CheckAccessToken(tokens)
+func (auth Authorize) GetAccount(ctx context.Context, tokens *types.AuthTokens) (interface{}, error) {
+	result, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAccountRead)
 	if err != nil {
 		return nil, err
 	}
 
-	account, err := dao.AccountDAO{}.GetAccountByID(result.ID, auth.DB)
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, result.ID, auth.DB)
 	if err != nil {
 		return nil, err
 	}
@@ -103,37 +542,50 @@ func (auth Authorize) GetAccount(tokens *types.AuthTokens) (interface{}, error)
 	account.GetAccountPermissions()
 	account.HideImportant()
 
+	if result.Act != nil {
+		account.ImpersonatedBy = &types.Impersonator{ID: result.Act.Sub, Email: result.Act.Email}
+	}
+
 	return account, nil
 }
 
-//GetAccounts - returns accounts from the role given. role 0 will get all accounts
-func (auth Authorize) GetAccounts(tokens *types.AuthTokens, roles []int) (*[]types.Account, error) {
-	account, err := auth.CheckAccessToken(tokens)
+//GetAccounts - returns a page of accounts matching the search
+func (auth Authorize) GetAccounts(ctx context.Context, tokens *types.AuthTokens, search *types.AccountSearchRequest) (response *types.AccountsResponse, err error) {
+	event := auth.newEvent(types.AuditAccountList, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
 	if err != nil {
 		return nil, err
 	}
+	event.SetActor(account)
 
 	//Only Accounts with Admin privliges can make this request
 	if !utils.Contains("ADMIN", account.Roles) {
 		return nil, errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
 	}
 
-	accounts, err := dao.AccountDAO{}.GetAccounts(roles, auth.DB)
+	response, err = dao.AccountDAO{}.SearchAccounts(ctx, search, auth.DB)
 	if err != nil {
 		return nil, err
 	}
 
-	return accounts, nil
+	return response, nil
 }
 
 //UpdateSettings - update requesting account settings
-func (auth Authorize) UpdateSettings(tokens *types.AuthTokens, updatedAccount *types.Account) (string, error) {
-	account, err := auth.CheckAccessToken(tokens)
+func (auth Authorize) UpdateSettings(ctx context.Context, tokens *types.AuthTokens, updatedAccount *types.Account) (res string, err error) {
+	event := auth.newEvent(types.AuditSettingsUpdate, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAccountWrite)
 	if err != nil {
 		return "", err
 	}
+	event.SetActor(account)
+	event.TargetID = account.ID
 
-	res, err := dao.AccountDAO{}.UpdateSettings(updatedAccount, account.ID, auth.DB)
+	res, err = dao.AccountDAO{}.UpdateSettings(ctx, updatedAccount, account.ID, auth.DB)
 	if err != nil {
 		return "", err
 	}
@@ -142,20 +594,30 @@ func (auth Authorize) UpdateSettings(tokens *types.AuthTokens, updatedAccount *t
 }
 
 //UpdateAccount - update account settings for another user
-func (auth Authorize) UpdateAccount(tokens *types.AuthTokens, updatedAccount *types.Account) (string, error) {
-	account, err := auth.CheckAccessToken(tokens)
+func (auth Authorize) UpdateAccount(ctx context.Context, tokens *types.AuthTokens, updatedAccount *types.Account) (res string, err error) {
+	event := auth.newEvent(types.AuditAccountUpdate, tokens.Client)
+	event.TargetID = updatedAccount.ID
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
 	if err != nil {
 		return "", err
 	}
+	event.SetActor(account)
 
 	//Only Accounts with ADMIN privliges can make this request
 	if !utils.Contains("ADMIN", account.Roles) {
 		return "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
 	}
 
+	//Changing the email of an account is enough to take it over through recovery
+	if err := auth.StepUp.Check(account, event.Type); err != nil {
+		return "", err
+	}
+
 	dao := dao.AccountDAO{}
 
-	accountData, err := dao.GetAccountByID(updatedAccount.ID, auth.DB)
+	accountData, err := dao.GetAccountByID(ctx, updatedAccount.ID, auth.DB)
 	if err != nil {
 		return "", err
 	}
@@ -175,12 +637,14 @@ func (auth Authorize) UpdateAccount(tokens *types.AuthTokens, updatedAccount *ty
 	accountData.Phone = updatedAccount.Phone
 	accountData.Email = updatedAccount.Email
 	accountData.Role = updatedAccount.Role
+	accountData.Organization = updatedAccount.Organization
+	accountData.Language = updatedAccount.Language
 
 	if accountData.Role == 0 { //default role
 		accountData.Role = 100
 	}
 
-	res, err := dao.UpdateAccount(accountData, auth.DB)
+	res, err = dao.UpdateAccount(ctx, accountData, auth.DB)
 	if err != nil {
 		return "", err
 	}
@@ -189,9 +653,12 @@ func (auth Authorize) UpdateAccount(tokens *types.AuthTokens, updatedAccount *ty
 }
 
 //ActivateDevice - activates a device for the requesting user.
-func (auth Authorize) ActivateDevice(deviceActivation *types.ActivateDevice) error {
+func (auth Authorize) ActivateDevice(ctx context.Context, deviceActivation *types.ActivateDevice) (err error) {
+	event := auth.newEvent(types.AuditDeviceActivate, deviceActivation.Client)
+	event.TargetID = deviceActivation.DeviceID
+	defer func() { auth.Audit.Record(event, "", err) }()
 
-	device, err := dao.DeviceDAO{}.GetDevice(deviceActivation.DeviceID, auth.DB)
+	device, err := dao.DeviceDAO{}.GetDevice(ctx, deviceActivation.DeviceID, auth.DB)
 	if err != nil {
 		return err
 	}
@@ -199,17 +666,25 @@ func (auth Authorize) ActivateDevice(deviceActivation *types.ActivateDevice) err
 	if device == nil {
 		return errors.New("No device was found")
 	}
+	event.ActorID = device.AccountID
 
 	if device.Active {
 		return errors.New("Device is already active")
 	}
 
+	//The code may have been sent to the phone of the account instead of emailed
 	if device.Code != deviceActivation.Code {
-		return errors.New("Invalid Code")
+		_, res, err := dao.PhoneCodeDAO{}.UsePhoneCode(ctx, device.AccountID, device.ID, strings.TrimSpace(deviceActivation.Code), auth.SMS.Attempts, auth.DB)
+		if err != nil {
+			return err
+		}
+		if res != "" {
+			return errors.New("Invalid Code: " + res)
+		}
 	}
 
 	//Activate device
-	err = dao.DeviceDAO{}.ActivateDevice(deviceActivation.DeviceID, auth.DB)
+	err = dao.DeviceDAO{}.ActivateDevice(ctx, device, auth.DB)
 	if err != nil {
 		return err
 	}
@@ -217,95 +692,348 @@ func (auth Authorize) ActivateDevice(deviceActivation *types.ActivateDevice) err
 	return nil
 }
 
-//RecoverAccount - activates a device
-func (auth Authorize) RecoverAccount(recoveryRequest *types.RecoveryRequest) error {
-	account, err := dao.AccountDAO{}.GetAccountByEmail(recoveryRequest.Email, auth.DB)
+//SendDevicePhoneCode - sends a code that activates a device to the verified phone of its account, for when email is not an option
+func (auth Authorize) SendDevicePhoneCode(ctx context.Context, request *types.PhoneCodeRequest) (response *types.PhoneCodeResponse, res string, err error) {
+	event := auth.newEvent(types.AuditPhoneCodeSend, request.Client)
+	event.TargetID = request.DeviceID
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	device, err := dao.DeviceDAO{}.GetDevice(ctx, request.DeviceID, auth.DB)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	if account == nil {
-		return errors.New("Account not found: " + recoveryRequest.Email)
+	if device == nil {
+		return nil, "", errors.New("No device was found")
 	}
+	event.ActorID = device.AccountID
 
-	recovery, err := dao.RecoverDAO{}.CreateRecovery(account, auth.DB)
-	if err != nil {
-		return err
+	if device.Active {
+		return nil, "", errors.New("Device is already active")
 	}
 
-	//Send recovery email
-	err = auth.Emailer.RecoverAccount(recovery)
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, device.AccountID, auth.DB)
 	if err != nil {
-		return errors.New("Recovery Email failed sending : " + err.Error())
+		return nil, "", err
 	}
-
-	return nil
-}
-
-//GetRecovery - returns a recovery
-func (auth Authorize) GetRecovery(recovery *types.Recovery) (*types.Recovery, error) {
-	rec, err := dao.RecoverDAO{}.GetRecovery(recovery, auth.DB)
-	if err != nil {
-		return nil, err
+	if account == nil {
+		return nil, "", errors.New("Account not found: " + device.AccountID)
 	}
-	if rec == nil {
-		return nil, errors.New("No recovery was found: " + recovery.ID)
+
+	//Only a number the account has proven it has can stand in for its email
+	phone, err := auth.SMS.Normalize(account.Phone)
+	if err != nil || phone != account.VerifiedPhone {
+		return nil, "Account has no verified phone number", nil
 	}
-	return rec, nil
+
+	return auth.sendPhoneCode(ctx, account.ID, phone, device.ID, request.Channel)
 }
 
-//FinishRecovery - completes a recovery request
-func (auth Authorize) FinishRecovery(recovery *types.FinalRecoveryRequest) (string, error) {
-	rec, err := dao.RecoverDAO{}.GetRecovery(&types.Recovery{ID: recovery.ID}, auth.DB)
+//SendPhoneVerification - sends a code to the phone number of the requesting account to prove it is theirs
+func (auth Authorize) SendPhoneVerification(ctx context.Context, tokens *types.AuthTokens, request *types.PhoneCodeRequest) (response *types.PhoneCodeResponse, res string, err error) {
+	event := auth.newEvent(types.AuditPhoneCodeSend, request.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	claims, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
+	event.SetActor(claims)
+	event.TargetID = claims.ID
 
-	if rec == nil {
-		return "", errors.New("No recovery was found: " + recovery.ID)
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, claims.ID, auth.DB)
+	if err != nil {
+		return nil, "", err
 	}
 
-	account, err := dao.AccountDAO{}.GetAccountByID(rec.AccountID, auth.DB)
+	phone, err := auth.SMS.Normalize(account.Phone)
 	if err != nil {
-		return "", err
+		return nil, err.Error(), nil
 	}
-	if account == nil {
-		return "", errors.New("No account was found: " + rec.AccountID)
+	if phone == account.VerifiedPhone {
+		return nil, "Phone number is already verified", nil
 	}
 
-	account.Password = recovery.Password
+	return auth.sendPhoneCode(ctx, account.ID, phone, types.PhoneCodeVerify, request.Channel)
+}
 
-	//Validate that the password is correct
-	if err := account.CheckPassword(); err != nil {
-		return err.Error(), nil
-	}
+//VerifyPhone - marks the phone number of the requesting account verified with the code sent to it
+func (auth Authorize) VerifyPhone(ctx context.Context, tokens *types.AuthTokens, request *types.PhoneVerifyRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditPhoneVerify, request.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
 
-	//Hash the password
-	hash, err := utils.HashPassword(account.Password)
+	claims, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
 	if err != nil {
 		return "", err
 	}
+	event.SetActor(claims)
+	event.TargetID = claims.ID
 
-	//Set password to  account object
-	account.Password = hash
-
-	res, err := dao.RecoverDAO{}.FinishRecovery(account, recovery, rec, auth.DB)
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, claims.ID, auth.DB)
 	if err != nil {
 		return "", err
 	}
 
-	return res, nil
-}
-
-//ChangeAccountPassword - update requesting account password
-func (auth Authorize) ChangeAccountPassword(tokens *types.AuthTokens, passwordRequest *types.UpdateAccountPassword) (string, error) {
-	accountClams, err := auth.CheckAccessToken(tokens)
+	phone, err := auth.SMS.Normalize(account.Phone)
 	if err != nil {
-		return "", err
+		return err.Error(), nil
 	}
 
-	//Get account from JWT claims
-	account, err := dao.AccountDAO{}.GetAccountByID(accountClams.ID, auth.DB)
+	code, res, err := dao.PhoneCodeDAO{}.UsePhoneCode(ctx, account.ID, types.PhoneCodeVerify, strings.TrimSpace(request.Code), auth.SMS.Attempts, auth.DB)
+	if err != nil || res != "" {
+		return res, err
+	}
+
+	//Phone was changed after the code was sent
+	if code.Phone != phone {
+		return "Phone number has changed, request a new code", nil
+	}
+
+	return "", dao.PhoneCodeDAO{}.SetVerifiedPhone(ctx, account, phone, auth.DB)
+}
+
+//SendReauthenticateCode - sends a code to the verified phone of the requesting account to re-authenticate with
+func (auth Authorize) SendReauthenticateCode(ctx context.Context, tokens *types.AuthTokens, request *types.PhoneCodeRequest) (response *types.PhoneCodeResponse, res string, err error) {
+	event := auth.newEvent(types.AuditPhoneCodeSend, request.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	claims, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
+	if err != nil {
+		return nil, "", err
+	}
+	event.SetActor(claims)
+	event.TargetID = claims.ID
+
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, claims.ID, auth.DB)
+	if err != nil {
+		return nil, "", err
+	}
+
+	phone, err := auth.SMS.Normalize(account.Phone)
+	if err != nil || phone != account.VerifiedPhone {
+		return nil, "Account has no verified phone number", nil
+	}
+
+	return auth.sendPhoneCode(ctx, account.ID, phone, types.PhoneCodeStepUp, request.Channel)
+}
+
+//Reauthenticate - re-authenticates the requesting account with its password or a code sent to its verified phone.
+//Returns a short lived access token that is fresh enough for operations needing a recent login. Methods already used in the session still count
+func (auth Authorize) Reauthenticate(ctx context.Context, tokens *types.AuthTokens, request *types.ReauthenticateRequest) (token string, res string, err error) {
+	event := auth.newEvent(types.AuditStepUp, request.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	claims, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
+	if err != nil {
+		return "", "", err
+	}
+	event.SetActor(claims)
+	event.TargetID = claims.ID
+
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, claims.ID, auth.DB)
+	if err != nil {
+		return "", "", err
+	}
+
+	//Account can no longer log in
+	if account == nil || account.Disabled || account.Deleted != nil {
+		return "", "", utils.ErrInvalidToken
+	}
+
+	method := ""
+	switch {
+	case request.Password != "":
+		if !utils.CheckPasswordHash(request.Password, account.Password) {
+			return "", "Password is wrong", nil
+		}
+		method = signer.MethodPassword
+	case request.Code != "":
+		phone, err := auth.SMS.Normalize(account.Phone)
+		if err != nil || phone != account.VerifiedPhone {
+			return "", "Account has no verified phone number", nil
+		}
+
+		code, res, err := dao.PhoneCodeDAO{}.UsePhoneCode(ctx, account.ID, types.PhoneCodeStepUp, strings.TrimSpace(request.Code), auth.SMS.Attempts, auth.DB)
+		if err != nil || res != "" {
+			return "", res, err
+		}
+		if code.Phone != phone {
+			return "", "Phone number has changed, request a new code", nil
+		}
+
+		method = signer.MethodSMS
+		if code.Channel == types.PhoneChannelVoice {
+			method = signer.MethodVoice
+		}
+	default:
+		return "", "Password or code is required", nil
+	}
+	event.Detail = method
+
+	methods := []string{}
+	if previous := claims.Authentication(); previous != nil {
+		methods = append(methods, previous.Methods...)
+	}
+	if !utils.Contains(method, methods) {
+		methods = append(methods, method)
+	}
+
+	account.GetAccountPermissions()
+	token, err = auth.Sign.CreateElevatedToken(&signer.AccountInfo{
+		ID:        account.ID,
+		FirstName: account.FirstName,
+		LastName:  account.LastName,
+		Email:     account.Email,
+		Roles:     account.Roles,
+	}, &signer.Authentication{Time: time.Now(), Methods: methods})
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, "", nil
+}
+
+//sendPhoneCode - sends a new code for a purpose to a number, unless the number has been sent too many already
+func (auth Authorize) sendPhoneCode(ctx context.Context, accountID string, phone string, purpose string, channel string) (*types.PhoneCodeResponse, string, error) {
+	if channel == "" {
+		channel = types.PhoneChannelSMS
+	}
+	if channel != types.PhoneChannelSMS && channel != types.PhoneChannelVoice {
+		return nil, "Invalid channel: " + channel, nil
+	}
+
+	//Limits are per number so an attacker cannot run up texts to someone with many accounts
+	now := time.Now()
+	hourly, err := dao.PhoneCodeDAO{}.CountPhoneCodes(ctx, phone, now.Add(-1*time.Hour), auth.DB)
+	if err != nil {
+		return nil, "", err
+	}
+	daily, err := dao.PhoneCodeDAO{}.CountPhoneCodes(ctx, phone, now.Add(-24*time.Hour), auth.DB)
+	if err != nil {
+		return nil, "", err
+	}
+	if hourly >= auth.SMS.HourlyLimit || daily >= auth.SMS.DailyLimit {
+		return nil, "Too many codes sent to this number, try again later", nil
+	}
+
+	code, secret, err := dao.PhoneCodeDAO{}.CreatePhoneCode(ctx, accountID, phone, purpose, channel, auth.SMS.Digits, auth.SMS.Duration, auth.DB)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := auth.SMS.SendCode(phone, channel, secret); err != nil {
+		return nil, "", err
+	}
+
+	return &types.PhoneCodeResponse{Phone: sms.Mask(phone), Channel: channel, Expires: code.Expires}, "", nil
+}
+
+//RecoverAccount - activates a device
+func (auth Authorize) RecoverAccount(ctx context.Context, recoveryRequest *types.RecoveryRequest) (err error) {
+	event := auth.newEvent(types.AuditRecoveryStart, recoveryRequest.Client)
+	event.ActorEmail = recoveryRequest.Email
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := dao.AccountDAO{}.GetAccountByEmail(ctx, recoveryRequest.Email, auth.DB)
+	if err != nil {
+		return err
+	}
+
+	if account == nil {
+		return errors.New("Account not found: " + recoveryRequest.Email)
+	}
+	event.ActorID = account.ID
+	event.TargetID = account.ID
+
+	//Queue the recovery email with the recovery it links to
+	return auth.DB.InTx(ctx, func(tx db.Store) error {
+		recovery, err := dao.RecoverDAO{}.CreateRecovery(ctx, account, tx)
+		if err != nil {
+			return err
+		}
+		return auth.Emailer.RecoverAccount(ctx, account, recovery, tx)
+	})
+}
+
+//GetRecovery - returns a recovery
+func (auth Authorize) GetRecovery(ctx context.Context, recovery *types.Recovery) (*types.Recovery, error) {
+	rec, err := dao.RecoverDAO{}.GetRecovery(ctx, recovery, auth.DB)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, errors.New("No recovery was found: " + recovery.ID)
+	}
+	return rec, nil
+}
+
+//FinishRecovery - completes a recovery request
+func (auth Authorize) FinishRecovery(ctx context.Context, recovery *types.FinalRecoveryRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditRecoveryFinish, recovery.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	rec, err := dao.RecoverDAO{}.GetRecovery(ctx, &types.Recovery{ID: recovery.ID}, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	if rec == nil {
+		return "", errors.New("No recovery was found: " + recovery.ID)
+	}
+	event.ActorID = rec.AccountID
+	event.ActorEmail = rec.Email
+	event.TargetID = rec.AccountID
+
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, rec.AccountID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+	if account == nil {
+		return "", errors.New("No account was found: " + rec.AccountID)
+	}
+
+	account.Password = recovery.Password
+
+	//Validate that the password is correct
+	if err := account.CheckPassword(); err != nil {
+		return err.Error(), nil
+	}
+
+	//Hash the password
+	hash, err := utils.HashPassword(account.Password)
+	if err != nil {
+		return "", err
+	}
+
+	//Set password to  account object
+	account.Password = hash
+
+	res, err = dao.RecoverDAO{}.FinishRecovery(ctx, account, recovery, rec, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	return res, nil
+}
+
+//ChangeAccountPassword - update requesting account password
+func (auth Authorize) ChangeAccountPassword(ctx context.Context, tokens *types.AuthTokens, passwordRequest *types.UpdateAccountPassword) (res string, err error) {
+	event := auth.newEvent(types.AuditPasswordChange, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	accountClams, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(accountClams)
+	event.TargetID = accountClams.ID
+
+	if err := auth.StepUp.Check(accountClams, event.Type); err != nil {
+		return "", err
+	}
+
+	//Get account from JWT claims
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, accountClams.ID, auth.DB)
 	if err != nil {
 		return "", err
 	}
@@ -315,10 +1043,778 @@ func (auth Authorize) ChangeAccountPassword(tokens *types.AuthTokens, passwordRe
 		return "Old Password is wrong", nil
 	}
 
-	res, err := dao.AccountDAO{}.ChangeAccountPassword(account, passwordRequest, auth.DB)
+	res, err = dao.AccountDAO{}.ChangeAccountPassword(ctx, account, passwordRequest, auth.DB)
 	if err != nil {
 		return "", err
 	}
 
 	return res, nil
 }
+
+//SearchAuditEvents - returns a page of audit events matching the search
+func (auth Authorize) SearchAuditEvents(ctx context.Context, tokens *types.AuthTokens, search *types.AuditSearchRequest) (response *types.AuditEventsResponse, err error) {
+	event := auth.newEvent(types.AuditAuditSearch, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	events, next, err := dao.AuditDAO{}.SearchEvents(ctx, search, auth.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.AuditEventsResponse{Events: events, NextCursor: next}, nil
+}
+
+//ExportAuditEvents - writes every audit event matching the search to w as JSON lines
+func (auth Authorize) ExportAuditEvents(ctx context.Context, tokens *types.AuthTokens, search *types.AuditSearchRequest, w io.Writer) (err error) {
+	event := auth.newEvent(types.AuditAuditExport, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	//Page through the whole result set
+	page := *search
+	page.Limit = types.AuditMaxPageLimit
+	encoder := json.NewEncoder(w)
+	for {
+		events, next, err := dao.AuditDAO{}.SearchEvents(ctx, &page, auth.DB)
+		if err != nil {
+			return err
+		}
+
+		for i := range *events {
+			if err := encoder.Encode(&(*events)[i]); err != nil {
+				return err
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+		page.Cursor = next
+	}
+}
+
+//CreateWebhook - subscribes a url to account and device events. The signing secret is only returned here
+func (auth Authorize) CreateWebhook(ctx context.Context, tokens *types.AuthTokens, request *types.WebhookRequest) (sub *types.WebhookSubscription, res string, err error) {
+	event := auth.newEvent(types.AuditWebhookCreate, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return nil, "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	if err := auth.StepUp.Check(account, event.Type); err != nil {
+		return nil, "", err
+	}
+
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		return nil, "Invalid webhook url: " + request.URL, nil
+	}
+
+	if len(request.Events) == 0 {
+		return nil, "At least one event is required", nil
+	}
+	for _, e := range request.Events {
+		if e != types.WebhookAllEvents && !utils.Contains(e, types.WebhookEvents) {
+			return nil, "Unknown webhook event: " + e, nil
+		}
+	}
+
+	secret, err := utils.RandomSecret(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sub = &types.WebhookSubscription{URL: request.URL, Secret: secret, EventTypes: request.Events}
+	err = dao.WebhookDAO{}.CreateSubscription(ctx, sub, auth.DB)
+	if err != nil {
+		return nil, "", err
+	}
+	event.TargetID = sub.ID
+
+	return sub, "", nil
+}
+
+//GetWebhooks - returns every webhook subscription
+func (auth Authorize) GetWebhooks(ctx context.Context, tokens *types.AuthTokens) (subs *[]types.WebhookSubscription, err error) {
+	event := auth.newEvent(types.AuditWebhookList, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	subs, err = dao.WebhookDAO{}.GetSubscriptions(ctx, auth.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range *subs {
+		(*subs)[i].HideImportant()
+	}
+
+	return subs, nil
+}
+
+//DeleteWebhook - removes a webhook subscription
+func (auth Authorize) DeleteWebhook(ctx context.Context, tokens *types.AuthTokens, request *types.WebhookIDRequest) (err error) {
+	event := auth.newEvent(types.AuditWebhookDelete, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	sub, err := dao.WebhookDAO{}.GetSubscription(ctx, request.ID, auth.DB)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return errors.New("No webhook was found: " + request.ID)
+	}
+
+	return dao.WebhookDAO{}.DeleteSubscription(ctx, sub.ID, auth.DB)
+}
+
+//GetWebhookDeliveries - returns the webhook delivery log
+func (auth Authorize) GetWebhookDeliveries(ctx context.Context, tokens *types.AuthTokens, request *types.WebhookDeliveriesRequest) (deliveries *[]types.WebhookDelivery, err error) {
+	event := auth.newEvent(types.AuditWebhookLog, tokens.Client)
+	event.TargetID = request.SubscriptionID
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	return dao.WebhookDAO{}.GetDeliveries(ctx, request, auth.DB)
+}
+
+//RedeliverWebhook - puts a delivery back on the queue, eg: one that was dead lettered
+func (auth Authorize) RedeliverWebhook(ctx context.Context, tokens *types.AuthTokens, request *types.WebhookIDRequest) (err error) {
+	event := auth.newEvent(types.AuditWebhookRetry, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	delivery, err := dao.WebhookDAO{}.GetDelivery(ctx, request.ID, auth.DB)
+	if err != nil {
+		return err
+	}
+	if delivery == nil {
+		return errors.New("No webhook delivery was found: " + request.ID)
+	}
+
+	//Start the back off over again
+	delivery.Status = types.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttempt = time.Now()
+	delivery.LastError = ""
+
+	return dao.WebhookDAO{}.UpdateDelivery(ctx, delivery, auth.DB)
+}
+
+//ExportData - returns everything stored about the requesting account
+func (auth Authorize) ExportData(ctx context.Context, tokens *types.AuthTokens) (export *types.DataExport, err error) {
+	event := auth.newEvent(types.AuditPrivacyExport, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	claims, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAccountRead)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(claims)
+	event.TargetID = claims.ID
+
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, claims.ID, auth.DB)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, errors.New("No account was found: " + claims.ID)
+	}
+
+	return dao.PrivacyDAO{}.ExportAccount(ctx, account, auth.DB)
+}
+
+//RequestErasure - starts erasing the requesting account. The owner confirms by email and an admin then processes it
+func (auth Authorize) RequestErasure(ctx context.Context, tokens *types.AuthTokens) (res string, err error) {
+	event := auth.newEvent(types.AuditErasureRequest, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	claims, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(claims)
+	event.TargetID = claims.ID
+
+	if err := auth.StepUp.Check(claims, event.Type); err != nil {
+		return "", err
+	}
+
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, claims.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+	if account == nil {
+		return "", errors.New("No account was found: " + claims.ID)
+	}
+
+	//Only one request can be waiting in the queue
+	pending, err := dao.PrivacyDAO{}.HasPendingErasure(ctx, account.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+	if pending {
+		return "An erasure request is already waiting to be processed", nil
+	}
+
+	//Queue the confirmation email with the request it confirms
+	return "", auth.DB.InTx(ctx, func(tx db.Store) error {
+		request, err := dao.PrivacyDAO{}.CreateErasureRequest(ctx, account, tx)
+		if err != nil {
+			return err
+		}
+		return auth.Emailer.ConfirmErasure(ctx, account, request, tx)
+	})
+}
+
+//ConfirmErasure - confirms an erasure request with the token from the email and queues it for an admin
+func (auth Authorize) ConfirmErasure(ctx context.Context, confirm *types.ErasureConfirmRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditErasureConfirm, confirm.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	request, err := dao.PrivacyDAO{}.GetErasureRequest(ctx, confirm.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	if request == nil || subtle.ConstantTimeCompare([]byte(request.Token), []byte(confirm.Token)) != 1 {
+		return "", errors.New("No erasure request was found: " + confirm.ID)
+	}
+	event.ActorID = request.AccountID
+	event.TargetID = request.AccountID
+
+	if request.Status != types.ErasureUnconfirmed {
+		return "Erasure request is already confirmed", nil
+	}
+
+	err = dao.PrivacyDAO{}.ConfirmErasureRequest(ctx, request, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+//GetErasureRequests - returns the erasure request queue
+func (auth Authorize) GetErasureRequests(ctx context.Context, tokens *types.AuthTokens, request *types.ErasureRequestsRequest) (requests *[]types.ErasureRequest, err error) {
+	event := auth.newEvent(types.AuditErasureList, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	return dao.PrivacyDAO{}.GetErasureRequests(ctx, request, auth.DB)
+}
+
+//ProcessErasureRequest - approves and carries out, or rejects, a confirmed erasure request
+func (auth Authorize) ProcessErasureRequest(ctx context.Context, tokens *types.AuthTokens, decision *types.ErasureDecisionRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditErasureProcess, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	admin, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(admin)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", admin.Roles) {
+		return "", errors.New("Invalid Privilges: " + admin.FirstName + " " + admin.LastName)
+	}
+
+	request, err := dao.PrivacyDAO{}.GetErasureRequest(ctx, decision.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+	if request == nil {
+		return "", errors.New("No erasure request was found: " + decision.ID)
+	}
+	event.TargetID = request.AccountID
+
+	if request.Status != types.ErasurePending {
+		return "Erasure request is not waiting to be processed", nil
+	}
+	if request.AccountID == admin.ID {
+		return "You cannot process your own erasure request", nil
+	}
+	if len(decision.Note) > 255 {
+		return "Note must be 255 characters or less", nil
+	}
+
+	if !decision.Approve {
+		event.Detail = "rejected"
+		err = dao.PrivacyDAO{}.RejectErasureRequest(ctx, request, admin.ID, decision.Note, auth.DB)
+		if err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, request.AccountID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+	if account == nil {
+		return "", errors.New("No account was found: " + request.AccountID)
+	}
+
+	event.Detail = "erased"
+	err = dao.PrivacyDAO{}.EraseAccount(ctx, account, request, admin.ID, decision.Note, auth.DB)
+	if err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+//CreateSCIMToken - creates a token a provisioning client uses to manage the accounts of an organization
+func (auth Authorize) CreateSCIMToken(ctx context.Context, tokens *types.AuthTokens, request *types.SCIMTokenRequest) (token *types.SCIMToken, res string, err error) {
+	event := auth.newEvent(types.AuditSCIMTokenNew, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return nil, "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	if err := auth.StepUp.Check(account, event.Type); err != nil {
+		return nil, "", err
+	}
+
+	token, res, err = dao.SCIMDAO{}.CreateToken(ctx, request, auth.DB)
+	if err != nil || res != "" {
+		return nil, res, err
+	}
+	event.TargetID = token.ID
+	event.Detail = token.Organization
+	if token.ManageAdmins {
+		event.Detail += ", manages admins"
+	}
+
+	return token, "", nil
+}
+
+//GetSCIMTokens - returns every SCIM token, without their secrets
+func (auth Authorize) GetSCIMTokens(ctx context.Context, tokens *types.AuthTokens) (scimTokens *[]types.SCIMToken, err error) {
+	event := auth.newEvent(types.AuditSCIMTokenList, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	return dao.SCIMDAO{}.GetTokens(ctx, auth.DB)
+}
+
+//RevokeSCIMToken - removes a SCIM token so its client can no longer provision accounts
+func (auth Authorize) RevokeSCIMToken(ctx context.Context, tokens *types.AuthTokens, request *types.SCIMTokenIDRequest) (err error) {
+	event := auth.newEvent(types.AuditSCIMTokenDrop, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	token, err := dao.SCIMDAO{}.GetToken(ctx, request.ID, auth.DB)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return errors.New("No SCIM token was found: " + request.ID)
+	}
+
+	return dao.SCIMDAO{}.RevokeToken(ctx, token, auth.DB)
+}
+
+//CreatePersonalToken - creates a personal access token for the requesting account. Only admins can give tokens admin scopes
+func (auth Authorize) CreatePersonalToken(ctx context.Context, tokens *types.AuthTokens, request *types.PersonalTokenRequest) (token *types.PersonalToken, res string, err error) {
+	event := auth.newEvent(types.AuditPATCreate, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
+	if err != nil {
+		return nil, "", err
+	}
+	event.SetActor(account)
+
+	if !utils.Contains("ADMIN", account.Roles) {
+		for _, scope := range request.Scopes {
+			if strings.HasPrefix(scope, "admin:") {
+				return nil, "Only admins can create tokens with the " + scope + " scope", nil
+			}
+		}
+	}
+
+	if err := auth.StepUp.Check(account, event.Type); err != nil {
+		return nil, "", err
+	}
+
+	token, res, err = dao.PersonalTokenDAO{}.CreateToken(ctx, account.ID, request, auth.DB)
+	if err != nil || res != "" {
+		return nil, res, err
+	}
+	event.TargetID = token.ID
+	event.Detail = token.Scope
+
+	return token, "", nil
+}
+
+//GetPersonalTokens - returns the personal access tokens of the requesting account, without their secrets
+func (auth Authorize) GetPersonalTokens(ctx context.Context, tokens *types.AuthTokens) (personalTokens *[]types.PersonalToken, err error) {
+	event := auth.newEvent(types.AuditPATList, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(account)
+
+	return dao.PersonalTokenDAO{}.GetTokens(ctx, account.ID, auth.DB)
+}
+
+//RevokePersonalToken - removes one of the requesting account's personal access tokens
+func (auth Authorize) RevokePersonalToken(ctx context.Context, tokens *types.AuthTokens, request *types.PersonalTokenIDRequest) (err error) {
+	event := auth.newEvent(types.AuditPATRevoke, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
+	if err != nil {
+		return err
+	}
+	event.SetActor(account)
+
+	//Tokens of other accounts are treated as missing so their ids cannot be probed
+	token, err := dao.PersonalTokenDAO{}.GetToken(ctx, request.ID, auth.DB)
+	if err != nil {
+		return err
+	}
+	if token == nil || token.AccountID != account.ID {
+		return errors.New("No personal access token was found: " + request.ID)
+	}
+
+	return dao.PersonalTokenDAO{}.RevokeToken(ctx, token, auth.DB)
+}
+
+//Impersonate - lets an admin act as another account with a short lived access token. The token carries the admin as its act claim and has no refresh token
+func (auth Authorize) Impersonate(ctx context.Context, tokens *types.AuthTokens, request *types.ImpersonateRequest) (response *types.ImpersonationResponse, res string, err error) {
+	event := auth.newEvent(types.AuditImpersonate, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	admin, err := auth.CheckAccessToken(ctx, tokens, types.ScopeSession)
+	if err != nil {
+		return nil, "", err
+	}
+	event.SetActor(admin)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", admin.Roles) {
+		return nil, "", errors.New("Invalid Privilges: " + admin.FirstName + " " + admin.LastName)
+	}
+
+	if err := auth.StepUp.Check(admin, event.Type); err != nil {
+		return nil, "", err
+	}
+
+	if request.ID == admin.ID {
+		return nil, "You cannot impersonate yourself", nil
+	}
+
+	target, err := dao.AccountDAO{}.GetAccountByID(ctx, request.ID, auth.DB)
+	if err != nil {
+		return nil, "", err
+	}
+	if target == nil || target.Deleted != nil {
+		return nil, "No account was found: " + request.ID, nil
+	}
+	if target.Disabled {
+		return nil, "Account is disabled: " + target.Email, nil
+	}
+
+	//Impersonating must never grant more than the admin already has
+	if target.Role > types.GetRole(admin.Roles) {
+		return nil, "You cannot impersonate an account with a higher role", nil
+	}
+
+	expires := time.Now().Add(auth.Sign.ImpersonationDuration)
+	impersonation, res, err := dao.ImpersonationDAO{}.StartImpersonation(ctx, admin.ID, target.ID, request.Reason, expires, auth.DB)
+	if err != nil || res != "" {
+		return nil, res, err
+	}
+	event.Detail = impersonation.Reason
+
+	target.GetAccountPermissions()
+	accessToken, err := auth.Sign.CreateImpersonationToken(&signer.AccountInfo{
+		ID:        target.ID,
+		FirstName: target.FirstName,
+		LastName:  target.LastName,
+		Email:     target.Email,
+		Roles:     target.Roles,
+	}, &signer.Actor{Sub: admin.ID, Email: admin.Email}, impersonation.ID, impersonation.Expires)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &types.ImpersonationResponse{AccessToken: accessToken, Expires: impersonation.Expires}, "", nil
+}
+
+//StopImpersonation - ends the impersonation an access token belongs to, which revokes the token
+func (auth Authorize) StopImpersonation(ctx context.Context, tokens *types.AuthTokens) (err error) {
+	event := auth.newEvent(types.AuditImpersonateEnd, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	claims, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAccountRead)
+	if err != nil {
+		return err
+	}
+	if claims.Act == nil {
+		return errors.New("Access token is not an impersonation")
+	}
+
+	//The admin stopped it, so the admin is the actor
+	event.ActorID = claims.Act.Sub
+	event.ActorEmail = claims.Act.Email
+	event.TargetID = claims.ID
+
+	return dao.ImpersonationDAO{}.StopImpersonation(ctx, claims.Id, auth.DB)
+}
+
+//GetOutbox - returns queued and sent emails, newest first. Bodies are never returned
+func (auth Authorize) GetOutbox(ctx context.Context, tokens *types.AuthTokens, request *types.OutboxRequest) (emails *[]types.OutboxEmail, err error) {
+	event := auth.newEvent(types.AuditEmailList, tokens.Client)
+	defer func() { auth.Audit.Record(event, "", err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return nil, err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	return dao.OutboxDAO{}.GetEmails(ctx, request, auth.DB)
+}
+
+//RetryEmail - puts a dead lettered email back on the queue
+func (auth Authorize) RetryEmail(ctx context.Context, tokens *types.AuthTokens, request *types.OutboxIDRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditEmailRetry, tokens.Client)
+	event.TargetID = request.ID
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	email, err := dao.OutboxDAO{}.GetEmail(ctx, request.ID, auth.DB)
+	if err != nil {
+		return "", err
+	}
+	if email == nil {
+		return "No email was found: " + request.ID, nil
+	}
+
+	//Sent emails no longer have a body to send
+	if email.Status != types.EmailDead {
+		return "Only dead emails can be sent again", nil
+	}
+
+	//Start the back off over again
+	email.Status = types.EmailPending
+	email.Attempts = 0
+	email.NextAttempt = time.Now()
+	email.LastError = ""
+
+	return "", dao.OutboxDAO{}.UpdateEmail(ctx, email, auth.DB)
+}
+
+//PreviewEmail - renders an email template without sending it so admins can check it
+func (auth Authorize) PreviewEmail(ctx context.Context, tokens *types.AuthTokens, request *types.EmailPreviewRequest) (preview *types.EmailPreviewResponse, res string, err error) {
+	event := auth.newEvent(types.AuditEmailPreview, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminRead)
+	if err != nil {
+		return nil, "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return nil, "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	message, res := auth.previewEmail(request)
+	if res != "" {
+		return nil, res, nil
+	}
+
+	return &types.EmailPreviewResponse{From: message.From, Subject: message.Subject, HTML: message.HTML, Text: message.Text}, "", nil
+}
+
+//SendTestEmail - renders an email template and sends it to the address given right away
+func (auth Authorize) SendTestEmail(ctx context.Context, tokens *types.AuthTokens, request *types.EmailTestRequest) (res string, err error) {
+	event := auth.newEvent(types.AuditEmailTest, tokens.Client)
+	defer func() { auth.Audit.Record(event, res, err) }()
+
+	account, err := auth.CheckAccessToken(ctx, tokens, types.ScopeAdminWrite)
+	if err != nil {
+		return "", err
+	}
+	event.SetActor(account)
+
+	//Only Accounts with ADMIN privliges can make this request
+	if !utils.Contains("ADMIN", account.Roles) {
+		return "", errors.New("Invalid Privilges: " + account.FirstName + " " + account.LastName)
+	}
+
+	recipient := &types.Account{Email: request.To}
+	if err := recipient.CheckEmail(); err != nil {
+		return err.Error(), nil
+	}
+	if request.Email == "" {
+		request.Email = request.To
+	}
+
+	message, res := auth.previewEmail(&request.EmailPreviewRequest)
+	if res != "" {
+		return res, nil
+	}
+
+	//Report why the transport failed, that is what a test send is for
+	if err := auth.Emailer.SendTest(message, request.To); err != nil {
+		return "Sending failed: " + err.Error(), nil
+	}
+	return "", nil
+}
+
+//previewEmail - renders the email asked for, or returns why it could not be
+func (auth Authorize) previewEmail(request *types.EmailPreviewRequest) (*email.Message, string) {
+	if !utils.Contains(request.Template, email.TemplateNames) {
+		return nil, "Unknown email template: " + request.Template
+	}
+
+	data := &email.Data{Email: request.Email, Name: request.Name, Link: request.Link, Code: request.Code, Blocked: request.Blocked}
+	if request.Expires != nil {
+		data.Expires = *request.Expires
+	}
+
+	//Broken templates are shown to the admin checking them
+	message, err := auth.Emailer.Preview(request.Template, request.Language, request.Organization, data)
+	if err != nil {
+		return nil, "Template error: " + err.Error()
+	}
+	return message, ""
+}
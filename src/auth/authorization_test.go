@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"dao"
+	"testing"
+	"types"
+)
+
+func TestDisablingEndsSessions(t *testing.T) {
+	ctx := context.Background()
+	authenticate, store := newTestAuthenticate(t)
+	authorize := Authorize{DB: store, Sign: authenticate.Sign}
+
+	tests := []struct {
+		name string
+		end  func(account *types.Account) error
+	}{
+		{name: "disabled", end: func(account *types.Account) error {
+			_, err := dao.AccountDAO{}.DisableAccount(ctx, account, "test", store)
+			return err
+		}},
+		{name: "deleted", end: func(account *types.Account) error {
+			return dao.AccountDAO{}.DeleteAccount(ctx, account, store)
+		}},
+	}
+
+	for _, test := range tests {
+		account := newTestAccount(t, store, test.name, 100)
+		response, err := authenticate.Login(ctx, &types.Login{Email: account.Email, Password: testPassword})
+		if err != nil || response.Tokens == nil {
+			t.Fatalf("%s: login failed: %v", test.name, err)
+		}
+		tokens := &types.AuthTokens{AccessToken: response.Tokens.AccessToken, RefreshToken: response.Tokens.RefreshToken}
+
+		if _, err := authorize.CheckAccessToken(ctx, tokens, types.ScopeAccountRead); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := test.end(account); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if _, err := authorize.CheckAccessToken(ctx, tokens, types.ScopeAccountRead); err == nil {
+			t.Errorf("%s: access token still works", test.name)
+		}
+		if _, err := authenticate.RefreshAccessToken(ctx, tokens); err == nil {
+			t.Errorf("%s: refresh token still works", test.name)
+		}
+		token, err := store.GetRefreshToken(ctx, tokens.RefreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != nil {
+			t.Errorf("%s: refresh token was not deleted", test.name)
+		}
+	}
+}
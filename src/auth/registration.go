@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"types"
+	"utils"
+)
+
+//Registration - who can register an account without an admin
+type Registration struct {
+	Mode string
+	//Domains - email domains that can register in domain mode
+	Domains            []string
+	InvitationDuration time.Duration
+}
+
+//Init - reads the registration mode, allowed domains and invitation lifetime
+func (reg Registration) Init() (*Registration, error) {
+	reg.Mode = strings.ToLower(os.Getenv("REGISTRATION_MODE"))
+	if reg.Mode == "" {
+		reg.Mode = types.RegistrationOpen
+	}
+	if !utils.Contains(reg.Mode, types.RegistrationModes) {
+		return nil, errors.New("Unknown REGISTRATION_MODE: " + reg.Mode)
+	}
+
+	for _, domain := range strings.Split(os.Getenv("REGISTRATION_DOMAINS"), ",") {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "@")
+		if domain != "" {
+			reg.Domains = append(reg.Domains, domain)
+		}
+	}
+	if reg.Mode == types.RegistrationDomain && len(reg.Domains) == 0 {
+		return nil, errors.New("REGISTRATION_DOMAINS is required when REGISTRATION_MODE is domain")
+	}
+
+	days, err := strconv.Atoi(os.Getenv("INVITATION_EXPIRY_DAYS"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	reg.InvitationDuration = time.Duration(days) * 24 * time.Hour
+
+	return &reg, nil
+}
+
+//Allows - returns why the email cannot register, or an empty string if it can
+func (reg Registration) Allows(email string, invited bool) string {
+	switch reg.Mode {
+	case types.RegistrationOpen:
+		return ""
+	case types.RegistrationInvite:
+		if invited {
+			return ""
+		}
+		return "Registration is by invitation only"
+	case types.RegistrationDomain:
+		if invited || reg.allowedDomain(email) {
+			return ""
+		}
+		return "Registration is not open to this email domain"
+	default:
+		return "Registration is closed"
+	}
+}
+
+//InvitationsEnabled - checks if invitations can be created and used
+func (reg Registration) InvitationsEnabled() bool {
+	return reg.Mode != types.RegistrationClosed
+}
+
+//allowedDomain - checks if the domain of the email is on the allowlist. Subdomains must be listed separately
+func (reg Registration) allowedDomain(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return false
+	}
+	return utils.Contains(strings.ToLower(email[at+1:]), reg.Domains)
+}
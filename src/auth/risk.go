@@ -0,0 +1,377 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"dao"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"geoip"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"types"
+	"utils"
+)
+
+//Risk signals a login can raise
+const (
+	RiskNewDevice        = "newDevice"
+	RiskUnverifiedDevice = "unverifiedDevice"
+	RiskNewCountry       = "newCountry"
+	RiskImpossibleTravel = "impossibleTravel"
+	RiskUnusualHour      = "unusualHour"
+	RiskBadIP            = "badIp"
+	RiskRecentFailures   = "recentFailures"
+)
+
+//Risk decisions, from least to most strict
+const (
+	RiskAllow        = "allow"
+	RiskNotify       = "notify"
+	RiskSecondFactor = "secondFactor"
+	RiskBlock        = "block"
+)
+
+//RiskSignals - the signals a weight can be set for
+var RiskSignals = []string{RiskNewDevice, RiskUnverifiedDevice, RiskNewCountry, RiskImpossibleTravel, RiskUnusualHour, RiskBadIP, RiskRecentFailures}
+
+//RiskDecisions - the decisions a threshold can be set for, strictest first
+var RiskDecisions = []string{RiskBlock, RiskSecondFactor, RiskNotify}
+
+//Thresholds for signals that compare a login with the ones before it
+const (
+	//riskMinTravelKm - jumps shorter than this are left alone, GeoIP is rarely closer
+	riskMinTravelKm = 500
+	//riskHourSessions - sessions needed before the hour of a login can be unusual
+	riskHourSessions = 5
+	//riskHourWindow - how many hours either side of a previous login count as usual
+	riskHourWindow = 2
+)
+
+//RiskPolicy - what each signal adds to the score of a login and the score each decision starts at
+type RiskPolicy struct {
+	Weights map[string]int `json:"weights"`
+	//Thresholds - keyed by decision. 0 turns the decision off
+	Thresholds map[string]int `json:"thresholds"`
+	//MaxTravelKmh - faster than this between the last session and the login is impossible travel
+	MaxTravelKmh int `json:"maxTravelKmh"`
+	//FailureCount - failed logins within FailureWindowMinutes that raise recentFailures
+	FailureCount         int `json:"failureCount"`
+	FailureWindowMinutes int `json:"failureWindowMinutes"`
+}
+
+//Risk - scores logins and decides what happens to them
+type Risk struct {
+	Policy RiskPolicy
+	//BadNetworks - ips and ranges from RISK_IP_LIST
+	BadNetworks []*net.IPNet
+}
+
+//RiskAssessment - the score of a login, the signals behind it and what was decided
+type RiskAssessment struct {
+	Score    int
+	Signals  []string
+	Decision string
+}
+
+//String - summary recorded in the audit trail, eg: "risk 50 secondFactor: newDevice, newCountry"
+func (a *RiskAssessment) String() string {
+	summary := fmt.Sprintf("risk %d %s", a.Score, a.Decision)
+	if len(a.Signals) > 0 {
+		summary += ": " + strings.Join(a.Signals, ", ")
+	}
+	return summary
+}
+
+//defaultRiskPolicy - used unless RISK_POLICY_FILE replaces parts of it
+func defaultRiskPolicy() RiskPolicy {
+	return RiskPolicy{
+		Weights: map[string]int{
+			RiskNewDevice:        20,
+			RiskUnverifiedDevice: 10,
+			RiskNewCountry:       30,
+			RiskImpossibleTravel: 50,
+			RiskUnusualHour:      10,
+			RiskBadIP:            40,
+			RiskRecentFailures:   25,
+		},
+		Thresholds: map[string]int{
+			RiskNotify:       20,
+			RiskSecondFactor: 40,
+			RiskBlock:        80,
+		},
+		MaxTravelKmh:         1000,
+		FailureCount:         3,
+		FailureWindowMinutes: 60,
+	}
+}
+
+//Init - starts from the default policy. RISK_POLICY_FILE is JSON that replaces the weights, thresholds and limits it lists.
+//RISK_IP_LIST is a file of ips and CIDR ranges with a bad reputation, one per line
+func (r Risk) Init() (*Risk, error) {
+	r.Policy = defaultRiskPolicy()
+
+	if file := os.Getenv("RISK_POLICY_FILE"); file != "" {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		//Maps are merged into the defaults, so only the entries given change
+		if err := json.Unmarshal(contents, &r.Policy); err != nil {
+			return nil, errors.New("Invalid RISK_POLICY_FILE: " + err.Error())
+		}
+		for signal, weight := range r.Policy.Weights {
+			if !utils.Contains(signal, RiskSignals) {
+				return nil, errors.New("Invalid RISK_POLICY_FILE: unknown signal " + signal)
+			}
+			if weight < 0 {
+				return nil, errors.New("Invalid RISK_POLICY_FILE: " + signal + " has a negative weight")
+			}
+		}
+		for decision, threshold := range r.Policy.Thresholds {
+			if !utils.Contains(decision, RiskDecisions) {
+				return nil, errors.New("Invalid RISK_POLICY_FILE: unknown decision " + decision)
+			}
+			if threshold < 0 {
+				return nil, errors.New("Invalid RISK_POLICY_FILE: " + decision + " has a negative threshold")
+			}
+		}
+		if r.Policy.MaxTravelKmh <= 0 || r.Policy.FailureCount <= 0 || r.Policy.FailureWindowMinutes <= 0 {
+			return nil, errors.New("Invalid RISK_POLICY_FILE: maxTravelKmh, failureCount and failureWindowMinutes must be positive")
+		}
+	}
+
+	if file := os.Getenv("RISK_IP_LIST"); file != "" {
+		networks, err := readNetworks(file)
+		if err != nil {
+			return nil, err
+		}
+		r.BadNetworks = networks
+	}
+
+	return &r, nil
+}
+
+//Decide - scores the signals and returns the strictest decision the score reaches
+func (r *Risk) Decide(signals []string) *RiskAssessment {
+	assessment := &RiskAssessment{Signals: signals, Decision: RiskAllow}
+	for _, signal := range signals {
+		assessment.Score += r.Policy.Weights[signal]
+	}
+
+	for _, decision := range RiskDecisions {
+		threshold := r.Policy.Thresholds[decision]
+		if threshold > 0 && assessment.Score >= threshold {
+			assessment.Decision = decision
+			break
+		}
+	}
+	return assessment
+}
+
+//IsBadIP - returns true if the ip is on the reputation list
+func (r *Risk) IsBadIP(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, network := range r.BadNetworks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+//readNetworks - reads a list of ips and CIDR ranges. Blank lines and lines starting with # are skipped
+func readNetworks(path string) ([]*net.IPNet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	networks := []*net.IPNet{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		//A single ip is a range of one
+		if !strings.Contains(line, "/") {
+			if ip := net.ParseIP(line); ip != nil && ip.To4() != nil {
+				line += "/32"
+			} else {
+				line += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(line)
+		if err != nil {
+			return nil, errors.New("Invalid RISK_IP_LIST entry: " + line)
+		}
+		networks = append(networks, network)
+	}
+	return networks, scanner.Err()
+}
+
+//assessRisk - compares a login with the devices and sessions of the account and its recent failed logins, then decides what happens to it.
+//Only completed logins leave devices and sessions behind, so attempts that were blocked or never activated do not become familiar
+func (auth Authenticate) assessRisk(ctx context.Context, account *types.Account, deviceID string, client *types.ClientDetails) (*RiskAssessment, error) {
+	signals := []string{}
+
+	devices, err := auth.DB.GetDevicesByAccount(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := auth.DB.GetRefreshTokensByAccount(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	//Where the account has logged in from before
+	type seen struct {
+		ip       string
+		browser  string
+		os       string
+		country  string
+		lastSeen time.Time
+	}
+	history := []seen{}
+	knownDevice := false
+	for _, device := range *devices {
+		if !device.Active {
+			continue
+		}
+		if device.ID == deviceID {
+			knownDevice = true
+		}
+		history = append(history, seen{ip: device.LastIP, browser: device.Browser, os: device.OS, country: device.Country, lastSeen: lastSeen(device.Created, device.LastSeen)})
+	}
+	sessionCount := len(*sessions)
+	for _, session := range *sessions {
+		history = append(history, seen{ip: session.LastIP, browser: session.Browser, os: session.OS, country: session.Country, lastSeen: lastSeen(session.Created, session.LastSeen)})
+	}
+
+	//A first login has nothing to be compared with
+	if len(history) > 0 {
+		//The user agent is sent by the client, so a matching one only lowers the signal. Only the device cookie makes a device known
+		browser := browserFamily(client.Browser)
+		sameBrowser := false
+		countries := []string{}
+		for _, h := range history {
+			if (browser != "" || client.OS != "") && browserFamily(h.browser) == browser && h.os == client.OS {
+				sameBrowser = true
+			}
+			if h.country != "" {
+				countries = append(countries, h.country)
+			}
+		}
+
+		if !knownDevice && sameBrowser {
+			signals = append(signals, RiskUnverifiedDevice)
+		} else if !knownDevice {
+			signals = append(signals, RiskNewDevice)
+		}
+		if client.Country != "" && len(countries) > 0 && !utils.Contains(client.Country, countries) {
+			signals = append(signals, RiskNewCountry)
+		}
+
+		//Compare with wherever the account was most recently
+		sort.Slice(history, func(i, j int) bool {
+			return history[i].lastSeen.Before(history[j].lastSeen)
+		})
+		last := history[len(history)-1]
+		if auth.impossibleTravel(client, last.ip, last.lastSeen) {
+			signals = append(signals, RiskImpossibleTravel)
+		}
+
+		//Each login starts a session, so their hours show when the account usually logs in
+		if sessionCount >= riskHourSessions {
+			hour := time.Now().UTC().Hour()
+			usual := false
+			for _, session := range *sessions {
+				diff := hour - session.Created.UTC().Hour()
+				if diff < 0 {
+					diff = -diff
+				}
+				if diff > 12 {
+					diff = 24 - diff
+				}
+				if diff <= riskHourWindow {
+					usual = true
+					break
+				}
+			}
+			if !usual {
+				signals = append(signals, RiskUnusualHour)
+			}
+		}
+	}
+
+	if auth.Risk.IsBadIP(client.IP) {
+		signals = append(signals, RiskBadIP)
+	}
+
+	failures, _, err := dao.AuditDAO{}.SearchEvents(ctx, &types.AuditSearchRequest{
+		Type:    types.AuditLogin,
+		ActorID: account.ID,
+		Outcome: types.AuditOutcomeFailure,
+		Since:   time.Now().Add(-time.Duration(auth.Risk.Policy.FailureWindowMinutes) * time.Minute),
+		Limit:   auth.Risk.Policy.FailureCount,
+	}, auth.DB)
+	if err != nil {
+		return nil, err
+	}
+	if len(*failures) >= auth.Risk.Policy.FailureCount {
+		signals = append(signals, RiskRecentFailures)
+	}
+
+	return auth.Risk.Decide(signals), nil
+}
+
+//impossibleTravel - returns true if getting from where the account was last seen to the client would have been faster than the policy allows.
+//A broken GeoIP file is logged and skips the signal, the same as describeClient, rather than failing every login
+func (auth Authenticate) impossibleTravel(client *types.ClientDetails, lastIP string, last time.Time) bool {
+	if lastIP == "" || lastIP == client.IP {
+		return false
+	}
+
+	from, err := auth.GeoIP.Lookup(lastIP)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GeoIP Error: "+err.Error())
+		return false
+	}
+	to, err := auth.GeoIP.Lookup(client.IP)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GeoIP Error: "+err.Error())
+		return false
+	}
+
+	distance := geoip.Distance(from, to)
+	if distance < riskMinTravelKm {
+		return false
+	}
+
+	hours := time.Since(last).Hours()
+	return hours <= 0 || distance/hours > float64(auth.Risk.Policy.MaxTravelKmh)
+}
+
+//lastSeen - when a device or session was last used. Ones from before it was recorded fall back to when they were created
+func lastSeen(created time.Time, seen *time.Time) time.Time {
+	if seen == nil {
+		return created
+	}
+	return *seen
+}
+
+//browserFamily - drops the version from a parsed browser, so an update does not make a device look new
+func browserFamily(browser string) string {
+	return strings.TrimRight(browser, "0123456789 ")
+}
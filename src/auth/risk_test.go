@@ -0,0 +1,34 @@
+package auth
+
+import "testing"
+
+func TestRiskDecide(t *testing.T) {
+	risk := &Risk{Policy: defaultRiskPolicy()}
+	//Block turned off
+	lenient := &Risk{Policy: defaultRiskPolicy()}
+	lenient.Policy.Thresholds[RiskBlock] = 0
+
+	tests := []struct {
+		name     string
+		risk     *Risk
+		signals  []string
+		score    int
+		decision string
+	}{
+		{name: "no signals", risk: risk, signals: []string{}, score: 0, decision: RiskAllow},
+		{name: "below notify", risk: risk, signals: []string{RiskUnverifiedDevice}, score: 10, decision: RiskAllow},
+		{name: "at notify", risk: risk, signals: []string{RiskNewDevice}, score: 20, decision: RiskNotify},
+		{name: "between notify and second factor", risk: risk, signals: []string{RiskNewDevice, RiskUnusualHour}, score: 30, decision: RiskNotify},
+		{name: "at second factor", risk: risk, signals: []string{RiskBadIP}, score: 40, decision: RiskSecondFactor},
+		{name: "below block", risk: risk, signals: []string{RiskImpossibleTravel, RiskRecentFailures}, score: 75, decision: RiskSecondFactor},
+		{name: "at block", risk: risk, signals: []string{RiskImpossibleTravel, RiskNewCountry}, score: 80, decision: RiskBlock},
+		{name: "block turned off", risk: lenient, signals: []string{RiskImpossibleTravel, RiskNewCountry, RiskBadIP}, score: 120, decision: RiskSecondFactor},
+	}
+
+	for _, test := range tests {
+		assessment := test.risk.Decide(test.signals)
+		if assessment.Score != test.score || assessment.Decision != test.decision {
+			t.Errorf("%s: got %d %s, expected %d %s", test.name, assessment.Score, assessment.Decision, test.score, test.decision)
+		}
+	}
+}
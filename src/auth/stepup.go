@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"signer"
+	"time"
+	"types"
+	"utils"
+)
+
+//Requirement - how recent and strong the login behind an access token must be
+type Requirement struct {
+	//MaxAgeMinutes - how long ago the account can have authenticated. 0 drops the requirement
+	MaxAgeMinutes int  `json:"maxAgeMinutes"`
+	MultiFactor   bool `json:"multiFactor"`
+}
+
+//StepUp - operations that need a recent login, keyed by their audit event type
+type StepUp struct {
+	Policy map[string]Requirement
+}
+
+//StepUpPasswordHashes - an account export that includes password hashes. It has its own requirement as it is stricter than the export
+const StepUpPasswordHashes = "account.export.hashes"
+
+//StepUpOperations - the operations a requirement can be set for
+var StepUpOperations = []string{
+	types.AuditPasswordChange,
+	types.AuditAccountDelete,
+	types.AuditErasureRequest,
+	types.AuditPATCreate,
+	types.AuditSCIMTokenNew,
+	types.AuditWebhookCreate,
+	types.AuditImpersonate,
+	types.AuditAccountUpdate,
+	StepUpPasswordHashes,
+}
+
+//defaultStepUp - requirements used unless STEP_UP_POLICY_FILE replaces them
+var defaultStepUp = map[string]Requirement{
+	types.AuditPasswordChange: {MaxAgeMinutes: 10},
+	types.AuditAccountDelete:  {MaxAgeMinutes: 10},
+	types.AuditErasureRequest: {MaxAgeMinutes: 10},
+	types.AuditPATCreate:      {MaxAgeMinutes: 10},
+	types.AuditSCIMTokenNew:   {MaxAgeMinutes: 10, MultiFactor: true},
+	types.AuditWebhookCreate:  {MaxAgeMinutes: 10, MultiFactor: true},
+	types.AuditImpersonate:    {MaxAgeMinutes: 10, MultiFactor: true},
+	types.AuditAccountUpdate:  {MaxAgeMinutes: 10},
+	StepUpPasswordHashes:      {MaxAgeMinutes: 10, MultiFactor: true},
+}
+
+//Init - starts from the default policy. STEP_UP_POLICY_FILE is JSON keyed by operation and replaces the requirements it lists
+func (s StepUp) Init() (*StepUp, error) {
+	s.Policy = map[string]Requirement{}
+	for operation, requirement := range defaultStepUp {
+		s.Policy[operation] = requirement
+	}
+
+	if file := os.Getenv("STEP_UP_POLICY_FILE"); file != "" {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		overrides := map[string]Requirement{}
+		if err := json.Unmarshal(contents, &overrides); err != nil {
+			return nil, errors.New("Invalid STEP_UP_POLICY_FILE: " + err.Error())
+		}
+		for operation, requirement := range overrides {
+			if !utils.Contains(operation, StepUpOperations) {
+				return nil, errors.New("Invalid STEP_UP_POLICY_FILE: " + operation + " cannot require a step up")
+			}
+			if requirement.MaxAgeMinutes < 0 {
+				return nil, errors.New("Invalid STEP_UP_POLICY_FILE: " + operation + " has a negative maxAgeMinutes")
+			}
+			s.Policy[operation] = requirement
+		}
+	}
+
+	return &s, nil
+}
+
+//Check - returns utils.ErrStepUpRequired unless the claims come from a login recent and strong enough for the operation.
+//A valid token is not enough for these operations since a stolen or forgotten session lasts as long as its refresh token.
+//Personal access and impersonation tokens never come from a login, so they cannot be used for operations with a requirement
+func (s *StepUp) Check(claims *signer.AccessClaims, operation string) error {
+	requirement, ok := s.Policy[operation]
+	if !ok || requirement.MaxAgeMinutes == 0 {
+		return nil
+	}
+
+	authentication := claims.Authentication()
+	if authentication == nil || claims.Act != nil {
+		return utils.ErrStepUpRequired
+	}
+	if time.Since(authentication.Time) > time.Duration(requirement.MaxAgeMinutes)*time.Minute {
+		return utils.ErrStepUpRequired
+	}
+	if requirement.MultiFactor && !claims.MultiFactor() {
+		return utils.ErrStepUpRequired
+	}
+	return nil
+}
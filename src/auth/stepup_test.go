@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"signer"
+	"testing"
+	"time"
+	"utils"
+)
+
+func TestStepUpCheck(t *testing.T) {
+	stepUp := &StepUp{Policy: map[string]Requirement{
+		"recent": {MaxAgeMinutes: 10},
+		"strong": {MaxAgeMinutes: 10, MultiFactor: true},
+		"off":    {MaxAgeMinutes: 0, MultiFactor: true},
+	}}
+
+	login := func(age time.Duration, methods ...string) *signer.AccessClaims {
+		return &signer.AccessClaims{AuthTime: time.Now().Add(-age).Unix(), AMR: methods}
+	}
+	//Personal access tokens carry no login
+	personal := &signer.AccessClaims{}
+	impersonation := login(time.Minute, signer.MethodPassword, signer.MethodMFA)
+	impersonation.Act = &signer.Actor{Sub: "admin"}
+
+	tests := []struct {
+		name      string
+		claims    *signer.AccessClaims
+		operation string
+		allowed   bool
+	}{
+		{name: "recent password login", claims: login(time.Minute, signer.MethodPassword), operation: "recent", allowed: true},
+		{name: "old password login", claims: login(11*time.Minute, signer.MethodPassword), operation: "recent"},
+		{name: "password only", claims: login(time.Minute, signer.MethodPassword), operation: "strong"},
+		{name: "activated device", claims: login(time.Minute, signer.MethodPassword, signer.MethodMFA), operation: "strong", allowed: true},
+		{name: "two methods", claims: login(time.Minute, signer.MethodPassword, signer.MethodOTP), operation: "strong", allowed: true},
+		{name: "same method twice", claims: login(time.Minute, signer.MethodOTP, signer.MethodOTP), operation: "strong"},
+		{name: "old multi factor login", claims: login(11*time.Minute, signer.MethodPassword, signer.MethodMFA), operation: "strong"},
+		{name: "personal access token", claims: personal, operation: "recent"},
+		{name: "impersonation token", claims: impersonation, operation: "recent"},
+		{name: "requirement turned off", claims: personal, operation: "off", allowed: true},
+		{name: "operation without a requirement", claims: personal, operation: "other", allowed: true},
+	}
+
+	for _, test := range tests {
+		err := stepUp.Check(test.claims, test.operation)
+		if test.allowed && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if !test.allowed && !utils.IsStepUpRequired(err) {
+			t.Errorf("%s: expected a step up, got %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Screener - checks passwords against known breach corpora
+type Screener struct {
+	File     *HashFile
+	Range    *RangeClient
+	MinCount int
+}
+
+//Init - sets up password screening from config. Nothing is screened if no source is configured.
+func (s Screener) Init() (*Screener, error) {
+
+	//Only count a password as breached once it has been seen this many times
+	min, err := strconv.Atoi(os.Getenv("BREACH_MIN_COUNT"))
+	if err != nil || min < 1 {
+		min = 1
+	}
+	s.MinCount = min
+
+	//Local sorted hash file
+	if path := os.Getenv("BREACH_HASH_FILE"); path != "" {
+		file, err := HashFile{}.Init(path)
+		if err != nil {
+			return nil, err
+		}
+		s.File = file
+	}
+
+	//Optional k-anonymity range API
+	if url := os.Getenv("BREACH_RANGE_API"); url != "" {
+		s.Range = RangeClient{}.Init(url)
+	}
+
+	return &s, nil
+}
+
+//IsBreached - returns true if the password appears in any configured breach corpus
+func (s *Screener) IsBreached(password string) (bool, error) {
+	hash := Hash(password)
+
+	//Check the local file first, it never leaves the machine. A broken file falls back to the range API rather than turning screening off
+	if s.File != nil {
+		count, err := s.File.Lookup(hash)
+		if err != nil && s.Range == nil {
+			return false, err
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Breach File Error: "+err.Error())
+		} else if count >= s.MinCount {
+			return true, nil
+		}
+	}
+
+	if s.Range != nil {
+		count, err := s.Range.Lookup(hash)
+		if err != nil {
+			return false, err
+		}
+		if count >= s.MinCount {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+//Hash - returns the upper case SHA-1 hex of a password, as used by Pwned Passwords
+func Hash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+//parseLine - splits a "HASH:COUNT" line. Lines without a count are treated as seen once.
+func parseLine(line string) (string, int) {
+	line = strings.TrimSpace(line)
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) < 2 {
+		return strings.ToUpper(parts[0]), 1
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		count = 1
+	}
+	return strings.ToUpper(parts[0]), count
+}
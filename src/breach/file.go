@@ -0,0 +1,104 @@
+package breach
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+//HashFile - a local Pwned Passwords file of "SHA1:COUNT" lines sorted by hash
+type HashFile struct {
+	Path string
+	file *os.File
+	size int64
+}
+
+//Init - opens the hash file for lookups
+func (f HashFile) Init(path string) (*HashFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		file.Close()
+		return nil, errors.New("Breach hash file is empty: " + path)
+	}
+
+	f.Path = path
+	f.file = file
+	f.size = info.Size()
+	return &f, nil
+}
+
+//Lookup - binary searches the file for a hash and returns how many times it was seen. 0 if not found.
+func (f *HashFile) Lookup(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+
+	//The line we want always starts somewhere in [lo, hi)
+	lo, hi := int64(0), f.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		line, next, err := f.lineFrom(mid)
+		if err == io.EOF {
+			//No line starts at or after mid
+			hi = mid
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		lineHash, count := parseLine(line)
+		switch {
+		case lineHash == hash:
+			return count, nil
+		case lineHash < hash:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+
+	return 0, nil
+}
+
+//lineFrom - returns the first full line starting at or after offset, and the offset just past it
+func (f *HashFile) lineFrom(offset int64) (string, int64, error) {
+	start := offset
+	if offset > 0 {
+		//Read from the byte before so we know if offset is already the start of a line
+		start = offset - 1
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(f.file, start, f.size-start))
+
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err != nil {
+			return "", 0, io.EOF
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", 0, err
+	}
+
+	return line, start + int64(len(line)), nil
+}
+
+//Close - closes the underlying file
+func (f *HashFile) Close() error {
+	return f.file.Close()
+}
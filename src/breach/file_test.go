@@ -0,0 +1,87 @@
+package breach
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHashFileLookup(t *testing.T) {
+	//Hashes of password0 to password299, seen i+1 times. Every tenth line has no count, which counts as once
+	counts := map[string]int{}
+	hashes := []string{}
+	for i := 0; i < 300; i++ {
+		hash := Hash("password" + strconv.Itoa(i))
+		counts[hash] = i + 1
+		if i%10 == 0 {
+			counts[hash] = 1
+		}
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	lines := func(hashes []string) []string {
+		lines := []string{}
+		for _, hash := range hashes {
+			if counts[hash] == 1 {
+				lines = append(lines, hash)
+			} else {
+				lines = append(lines, hash+":"+strconv.Itoa(counts[hash]))
+			}
+		}
+		return lines
+	}
+
+	files := []struct {
+		name     string
+		contents string
+		hashes   []string
+	}{
+		{name: "trailing newline", contents: strings.Join(lines(hashes), "\n") + "\n", hashes: hashes},
+		{name: "no trailing newline", contents: strings.Join(lines(hashes), "\n"), hashes: hashes},
+		{name: "windows line endings", contents: strings.Join(lines(hashes), "\r\n") + "\r\n", hashes: hashes},
+		{name: "one line", contents: lines(hashes[:1])[0] + "\n", hashes: hashes[:1]},
+	}
+
+	for _, test := range files {
+		path := filepath.Join(t.TempDir(), "hashes.txt")
+		if err := os.WriteFile(path, []byte(test.contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		file, err := HashFile{}.Init(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, hash := range test.hashes {
+			count, err := file.Lookup(strings.ToLower(hash))
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			if count != counts[hash] {
+				t.Errorf("%s: %s seen %d times, expected %d", test.name, hash, count, counts[hash])
+			}
+		}
+
+		missing := []string{
+			strings.Repeat("0", 40),
+			strings.Repeat("F", 40),
+			Hash("not in the file"),
+			//Sorts between the first two lines
+			test.hashes[0] + "0",
+		}
+		for _, hash := range missing {
+			count, err := file.Lookup(hash)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			if count != 0 {
+				t.Errorf("%s: %s seen %d times, expected 0", test.name, hash, count)
+			}
+		}
+		file.Close()
+	}
+}
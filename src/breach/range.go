@@ -0,0 +1,66 @@
+package breach
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//RangeClient - k-anonymity client for a Pwned Passwords range API. Only the first 5 characters of a hash are sent.
+type RangeClient struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+//Init - creates a range client against the given base url, eg: https://api.pwnedpasswords.com or an internal mirror
+func (c RangeClient) Init(baseURL string) *RangeClient {
+	c.BaseURL = strings.TrimRight(baseURL, "/")
+	c.Client = &http.Client{Timeout: 5 * time.Second}
+	return &c
+}
+
+//Lookup - returns how many times the hash was seen. 0 if not found.
+func (c *RangeClient) Lookup(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+	if len(hash) != 40 {
+		return 0, errors.New("Invalid SHA-1 hash")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/range/"+hash[:5], nil)
+	if err != nil {
+		return 0, err
+	}
+
+	//Padding hides the real response size from anyone watching the wire
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "JWT_Auth")
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, errors.New("Range API returned status " + strconv.Itoa(res.StatusCode))
+	}
+
+	suffix := hash[5:]
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		lineSuffix, count := parseLine(scanner.Text())
+		if lineSuffix == suffix {
+			//Padding entries have a count of 0
+			return count, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
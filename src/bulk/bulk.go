@@ -0,0 +1,154 @@
+package bulk
+
+import (
+	"context"
+	"dao"
+	"db"
+	"email"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"types"
+)
+
+//Importer - creates accounts, or sends invitations, in bulk and exports accounts in the same formats
+type Importer struct {
+	DB                 db.Store
+	Emailer            *email.Emailer
+	InvitationDuration time.Duration
+}
+
+//Init - start bulk import service
+func (im Importer) Init(db db.Store, emailer *email.Emailer, invitationDuration time.Duration) *Importer {
+	im.DB = db
+	im.Emailer = emailer
+	im.InvitationDuration = invitationDuration
+	return &im
+}
+
+//Import - validates and imports every row, carrying on past rows that fail. adminID is recorded on invitations
+func (im *Importer) Import(ctx context.Context, rows []types.BulkAccount, dryRun bool, invite bool, adminID string) (*types.ImportReport, error) {
+	if len(rows) > types.BulkMaxImportRows {
+		return nil, errors.New("Import has more than " + strconv.Itoa(types.BulkMaxImportRows) + " rows")
+	}
+
+	report := &types.ImportReport{DryRun: dryRun, Rows: []types.ImportResult{}}
+	seen := map[string]bool{}
+
+	for i := range rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		row := &rows[i]
+		result := types.ImportResult{Row: i + 1, Email: row.Email}
+
+		key := strings.ToLower(row.Email)
+		if seen[key] {
+			result.Status = types.ImportFailed
+			result.Reason = "Email appears more than once in the import"
+			report.Add(result)
+			continue
+		}
+		seen[key] = true
+
+		var err error
+		if invite {
+			err = im.invite(ctx, row, dryRun, adminID, &result)
+		} else {
+			err = im.create(ctx, row, dryRun, &result)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Import Error: "+err.Error())
+			result.Status = types.ImportFailed
+			result.Reason = "Could not be saved"
+		}
+		report.Add(result)
+	}
+
+	return report, nil
+}
+
+//create - creates the account for a row
+func (im *Importer) create(ctx context.Context, row *types.BulkAccount, dryRun bool, result *types.ImportResult) error {
+	account, res, err := dao.AccountDAO{}.ImportAccount(ctx, row, dryRun, im.DB)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case res != "":
+		result.Status = types.ImportFailed
+		result.Reason = res
+	case dryRun:
+		result.Status = types.ImportValid
+	default:
+		result.Status = types.ImportCreated
+		result.ID = account.ID
+	}
+	return nil
+}
+
+//invite - creates and emails an invitation with the roles and organization of a row
+func (im *Importer) invite(ctx context.Context, row *types.BulkAccount, dryRun bool, adminID string, result *types.ImportResult) error {
+	request := &types.InvitationRequest{Email: row.Email, Roles: row.Roles, Organization: row.Organization}
+
+	if dryRun {
+		res, err := dao.InvitationDAO{}.CheckInvitation(ctx, request, im.DB)
+		if err != nil {
+			return err
+		}
+		result.Status = types.ImportValid
+		if res != "" {
+			result.Status = types.ImportFailed
+			result.Reason = res
+		}
+		return nil
+	}
+
+	//Queue the invitation email with the invitation so nobody is invited without being told
+	var invitation *types.Invitation
+	var res string
+	err := im.DB.InTx(ctx, func(tx db.Store) error {
+		var err error
+		invitation, res, err = dao.InvitationDAO{}.CreateInvitation(ctx, request, adminID, im.InvitationDuration, tx)
+		if err != nil || res != "" {
+			return err
+		}
+		return im.Emailer.InviteAccount(ctx, invitation, tx)
+	})
+	if err != nil {
+		return err
+	}
+	if res != "" {
+		result.Status = types.ImportFailed
+		result.Reason = res
+		return nil
+	}
+
+	result.Status = types.ImportInvited
+	result.ID = invitation.ID
+	return nil
+}
+
+//Export - writes every account matching the search
+func (im *Importer) Export(ctx context.Context, w io.Writer, request *types.ExportAccountsRequest) error {
+	writer, err := Writer{}.Init(w, request.Format)
+	if err != nil {
+		return err
+	}
+
+	search := request.Search
+	err = dao.AccountDAO{}.EachAccount(ctx, &search, func(account *types.Account) error {
+		return writer.Write(types.NewBulkAccount(account, request.PasswordHashes))
+	}, im.DB)
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
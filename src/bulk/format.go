@@ -0,0 +1,160 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"types"
+)
+
+//ReadAccounts - parses import rows from CSV with a header row, or a JSON array
+func ReadAccounts(r io.Reader, format string) ([]types.BulkAccount, error) {
+	switch format {
+	case types.BulkFormatCSV:
+		return readCSV(r)
+	case types.BulkFormatJSON:
+		rows := []types.BulkAccount{}
+		if err := json.NewDecoder(r).Decode(&rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	default:
+		return nil, errors.New("Unknown format: " + format)
+	}
+}
+
+//readCSV - parses CSV rows by their header names. Roles are separated by semicolons
+func readCSV(r io.Reader) ([]types.BulkAccount, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []types.BulkAccount{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("CSV is missing the email column")
+	}
+
+	rows := []types.BulkAccount{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			i, ok := columns[strings.ToLower(name)]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := types.BulkAccount{
+			Email:        field("email"),
+			FirstName:    field("firstName"),
+			LastName:     field("lastName"),
+			Phone:        field("phone"),
+			Organization: field("organization"),
+			Roles:        []string{},
+			Password:     field("password"),
+			PasswordHash: field("passwordHash"),
+		}
+		for _, role := range strings.Split(field("roles"), ";") {
+			if role = strings.TrimSpace(role); role != "" {
+				row.Roles = append(row.Roles, strings.ToUpper(role))
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+//Writer - writes export rows as CSV or as a JSON array
+type Writer struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	rows   int
+}
+
+//Init - starts an export in the format given
+func (bw Writer) Init(w io.Writer, format string) (*Writer, error) {
+	bw.format = format
+	bw.w = w
+
+	switch format {
+	case types.BulkFormatCSV:
+		bw.csv = csv.NewWriter(w)
+		if err := bw.csv.Write(types.BulkColumns); err != nil {
+			return nil, err
+		}
+	case types.BulkFormatJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("Unknown format: " + format)
+	}
+
+	return &bw, nil
+}
+
+//Write - writes one row
+func (bw *Writer) Write(row *types.BulkAccount) error {
+	bw.rows++
+
+	if bw.csv != nil {
+		return bw.csv.Write([]string{
+			row.ID,
+			row.Email,
+			row.FirstName,
+			row.LastName,
+			row.Phone,
+			row.Organization,
+			strings.Join(row.Roles, ";"),
+			strconv.FormatBool(row.TwoFA),
+			strconv.FormatBool(row.Disabled),
+			row.Created.Format(time.RFC3339),
+			row.PasswordHash,
+		})
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if bw.rows > 1 {
+		data = append([]byte(",\n"), data...)
+	} else {
+		data = append([]byte("\n"), data...)
+	}
+	_, err = bw.w.Write(data)
+	return err
+}
+
+//Close - finishes the export
+func (bw *Writer) Close() error {
+	if bw.csv != nil {
+		bw.csv.Flush()
+		return bw.csv.Error()
+	}
+
+	_, err := io.WriteString(bw.w, "\n]\n")
+	return err
+}
@@ -1,15 +1,17 @@
 package dao
 
 import (
+	"context"
 	"db"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
-	"strconv"
+	"strings"
 	"time"
 	"types"
 	"utils"
 
 	"github.com/google/uuid"
-	"github.com/kisielk/sqlstruct"
 )
 
 //AccountDAO - data access for accounts
@@ -19,26 +21,20 @@ type AccountDAO struct {
 //CheckDuplicates - checks if account info already exists.
 //Returns empty string and no error if no duplicates are found
 //Returns string with an error message if duplicates are found
-func (dao AccountDAO) CheckDuplicates(ID string, email string, db *db.MySQL) (string, error) {
-	stmt, err := db.PreparedQuery("SELECT * FROM users WHERE email = ? AND id <> ?")
+func (dao AccountDAO) CheckDuplicates(ctx context.Context, ID string, email string, db db.Store) (string, error) {
+	taken, err := db.EmailTaken(ctx, email, ID)
 	if err != nil {
 		return "", err
 	}
-	rows, err := stmt.Query(email, ID)
-	if err != nil {
-		return "", err
-	}
-	stmt.Close()
-	defer rows.Close()
-	for rows.Next() {
+	if taken {
 		return "Email is taken: " + email, nil
 	}
 
 	return "", nil
 }
 
-//CreateAccount - verifies and creates a new account
-func (dao AccountDAO) CreateAccount(account *types.Account, db *db.MySQL) (string, error) {
+//CreateAccount - verifies and creates a new account. If an invitation is given the account gets its role and organization and uses it up
+func (dao AccountDAO) CreateAccount(ctx context.Context, account *types.Account, invitation *types.Invitation, db db.Store) (string, error) {
 
 	if err := account.CheckName(); err != nil {
 		return err.Error(), nil
@@ -52,145 +48,399 @@ func (dao AccountDAO) CreateAccount(account *types.Account, db *db.MySQL) (strin
 	if err := account.CheckPhone(); err != nil {
 		return err.Error(), nil
 	}
-	//Check if account details already exist with another account
-	isDuplicate, err := dao.CheckDuplicates(account.ID, account.Email, db)
+	if err := account.CheckLanguage(); err != nil {
+		return err.Error(), nil
+	}
+	//Hash password
+	hash, err := utils.HashPassword(account.Password)
 	if err != nil {
 		return "", err
 	}
-	if isDuplicate != "" {
-		return isDuplicate, nil
+	account.Password = hash
+	account.Role = 100 //default
+
+	return dao.insertAccount(ctx, account, invitation, db)
+}
+
+//ImportAccount - verifies and creates an account from an import row. In a dry run nothing is created
+func (dao AccountDAO) ImportAccount(ctx context.Context, row *types.BulkAccount, dryRun bool, db db.Store) (*types.Account, string, error) {
+	account := &types.Account{
+		FirstName:    row.FirstName,
+		LastName:     row.LastName,
+		Phone:        row.Phone,
+		Email:        row.Email,
+		Organization: row.Organization,
+		Role:         types.GetRole(row.Roles),
 	}
 
-	//Setup account details
-	account.ID = uuid.New().String()
-	account.Created = time.Now()
-	account.Role = 100 //default
+	if err := account.CheckName(); err != nil {
+		return nil, err.Error(), nil
+	}
+	if err := account.CheckEmail(); err != nil {
+		return nil, err.Error(), nil
+	}
+	if err := account.CheckPhone(); err != nil {
+		return nil, err.Error(), nil
+	}
+	if len(account.Organization) > 255 {
+		return nil, "Organization must be 255 characters or less", nil
+	}
+	for _, role := range row.Roles {
+		if role != "ADMIN" && role != "DEFAULT" {
+			return nil, "Unknown role: " + role, nil
+		}
+	}
 
-	//Hash password
-	account.Password, err = utils.HashPassword(account.Password)
-	if err != nil {
-		return "", err
+	//Hashes from another system are kept as they are so the account can log in with its old password
+	switch {
+	case row.PasswordHash != "":
+		if !utils.IsPasswordHash(row.PasswordHash) {
+			return nil, "Password hash is not a bcrypt hash", nil
+		}
+		account.Password = row.PasswordHash
+	case row.Password != "":
+		account.Password = row.Password
+		if err := account.CheckPassword(); err != nil {
+			return nil, err.Error(), nil
+		}
+	default:
+		return nil, "A password or password hash is required", nil
 	}
 
-	//Insert into database
-	stmt, err := db.PreparedQuery("INSERT INTO users (id, password, role, firstName, lastName, phone, email, created) VALUES(?,?,?,?,?,?,?,?)")
-	if err != nil {
-		return "", err
+	if dryRun {
+		isDuplicate, err := dao.CheckDuplicates(ctx, "", account.Email, db)
+		return nil, isDuplicate, err
 	}
-	_, err = stmt.Query(account.ID, account.Password, account.Role, account.FirstName, account.LastName, account.Phone, account.Email, account.Created)
+
+	if row.PasswordHash == "" {
+		hash, err := utils.HashPassword(account.Password)
+		if err != nil {
+			return nil, "", err
+		}
+		account.Password = hash
+	}
+
+	res, err := dao.insertAccount(ctx, account, nil, db)
+	if err != nil || res != "" {
+		return nil, res, err
+	}
+	return account, "", nil
+}
+
+//insertAccount - saves a verified account with a hashed password. If an invitation is given the account gets its role and organization and uses it up
+func (dao AccountDAO) insertAccount(ctx context.Context, account *types.Account, invitation *types.Invitation, db db.Store) (string, error) {
+	//Duplicate check, insert and webhook all happen together or not at all
+	res := ""
+	err := db.InTx(ctx, func(tx store) error {
+		//Check if account details already exist with another account
+		isDuplicate, err := dao.CheckDuplicates(ctx, account.ID, account.Email, tx)
+		if err != nil || isDuplicate != "" {
+			res = isDuplicate
+			return err
+		}
+
+		//Setup account details
+		account.ID = uuid.New().String()
+		account.Created = time.Now()
+
+		if invitation != nil {
+			accepted, err := tx.AcceptInvitation(ctx, invitation.ID, account.ID, account.Created)
+			if err != nil || !accepted {
+				res = "Invitation has already been used"
+				return err
+			}
+			account.Role = invitation.Role
+			account.Organization = invitation.Organization
+		}
+
+		//Insert into database
+		if err := tx.InsertAccount(ctx, account); err != nil {
+			return err
+		}
+
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountCreated, account.ID, types.NewWebhookAccount(account), tx)
+	})
 	if err != nil {
 		return "", err
 	}
-	stmt.Close()
 
-	return "", nil
+	return res, nil
 }
 
-//DeleteAccount - deletes account from DB
-func (dao AccountDAO) DeleteAccount(account *types.Account, db *db.MySQL) error {
+//EachAccount - calls fn with every account matching the search in its sort order, password hash included. Paging in the search is ignored
+func (dao AccountDAO) EachAccount(ctx context.Context, search *types.AccountSearchRequest, fn func(account *types.Account) error, db db.Store) error {
+	if search.Sort == "" {
+		search.Sort = types.AccountSortFirstName
+	}
+	if !utils.Contains(search.Sort, types.AccountSortFields) {
+		return errors.New("Invalid sort field: " + search.Sort)
+	}
 
-	stmt, err := db.PreparedQuery("DELETE FROM users WHERE id = ?")
-	if err != nil {
-		return err
+	//A 0 role means every account
+	for _, role := range search.Roles {
+		if role == 0 {
+			search.Roles = nil
+			break
+		}
+	}
+
+	var after *types.Account
+	for {
+		accounts, err := db.SearchAccounts(ctx, search, after, types.AccountMaxPageLimit)
+		if err != nil {
+			return err
+		}
+
+		for i := range *accounts {
+			(*accounts)[i].GetAccountPermissions()
+			if err := fn(&(*accounts)[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(*accounts) < types.AccountMaxPageLimit {
+			return nil
+		}
+		after = &(*accounts)[len(*accounts)-1]
 	}
-	_, err = stmt.Query(account.ID)
+}
+
+//DeleteAccount - soft deletes an account and ends its sessions and impersonations. It can be restored until it is purged
+func (dao AccountDAO) DeleteAccount(ctx context.Context, account *types.Account, db db.Store) error {
+
+	deleted := time.Now()
+
+	return db.InTx(ctx, func(tx store) error {
+		if err := tx.SetDeleted(ctx, account.ID, &deleted); err != nil {
+			return err
+		}
+		account.Deleted = &deleted
+
+		if err := dao.endSessions(ctx, account, tx); err != nil {
+			return err
+		}
+
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountDeleted, account.ID, types.NewWebhookAccount(account), tx)
+	})
+}
+
+//RestoreAccount - brings back a soft deleted account
+func (dao AccountDAO) RestoreAccount(ctx context.Context, account *types.Account, db db.Store) error {
+	return db.InTx(ctx, func(tx store) error {
+		if err := tx.SetDeleted(ctx, account.ID, nil); err != nil {
+			return err
+		}
+		account.Deleted = nil
+
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountRestored, account.ID, types.NewWebhookAccount(account), tx)
+	})
+}
+
+//GetDeletedBefore - returns up to limit accounts soft deleted before the time given
+func (dao AccountDAO) GetDeletedBefore(ctx context.Context, before time.Time, limit int, db db.Store) (*[]types.Account, error) {
+	return db.GetDeletedBefore(ctx, before, limit)
+}
+
+//PurgeAccount - permanently removes an account along with its devices, refresh tokens and recoveries
+func (dao AccountDAO) PurgeAccount(ctx context.Context, account *types.Account, db db.Store) error {
+	return db.InTx(ctx, func(tx store) error {
+		if err := tx.DeleteAccount(ctx, account.ID); err != nil {
+			return err
+		}
+
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountPurged, account.ID, types.NewWebhookAccount(account), tx)
+	})
+}
+
+//DisableAccount - stops an account from logging in and ends its sessions and impersonations
+func (dao AccountDAO) DisableAccount(ctx context.Context, account *types.Account, reason string, db db.Store) (string, error) {
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "A reason is required to disable an account", nil
+	}
+	if len(reason) > 255 {
+		return "Reason must be 255 characters or less", nil
+	}
+
+	err := db.InTx(ctx, func(tx store) error {
+		if err := tx.SetDisabled(ctx, account.ID, true, reason); err != nil {
+			return err
+		}
+		account.Disabled = true
+		account.DisabledReason = reason
+
+		if err := dao.endSessions(ctx, account, tx); err != nil {
+			return err
+		}
+
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountDisabled, account.ID, types.NewWebhookAccount(account), tx)
+	})
 	if err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+//endSessions - deletes the refresh tokens of an account and stops impersonations by or of it
+func (dao AccountDAO) endSessions(ctx context.Context, account *types.Account, tx store) error {
+	if err := tx.DeleteRefreshTokensByAccount(ctx, account.ID); err != nil {
 		return err
 	}
+	return tx.StopImpersonationsByAccount(ctx, account.ID, time.Now())
+}
+
+//EnableAccount - lets a disabled account log in again
+func (dao AccountDAO) EnableAccount(ctx context.Context, account *types.Account, db db.Store) error {
+	return db.InTx(ctx, func(tx store) error {
+		if err := tx.SetDisabled(ctx, account.ID, false, ""); err != nil {
+			return err
+		}
+		account.Disabled = false
+		account.DisabledReason = ""
 
-	stmt.Close()
-	return nil
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountEnabled, account.ID, types.NewWebhookAccount(account), tx)
+	})
 
 }
 
 //GetAccountByEmail - returns an account by email
-func (dao AccountDAO) GetAccountByEmail(email string, db *db.MySQL) (*types.Account, error) {
-	stmt, err := db.PreparedQuery("SELECT * FROM users WHERE email = ?")
-	if err != nil {
-		return nil, err
+func (dao AccountDAO) GetAccountByEmail(ctx context.Context, email string, db db.Store) (*types.Account, error) {
+	return db.GetAccountByEmail(ctx, email)
+}
+
+//GetAccountByID - returns an account by ID
+func (dao AccountDAO) GetAccountByID(ctx context.Context, id string, db db.Store) (*types.Account, error) {
+	return db.GetAccountByID(ctx, id)
+}
+
+//SearchAccounts - returns a page of accounts matching the search and how many match in total
+func (dao AccountDAO) SearchAccounts(ctx context.Context, search *types.AccountSearchRequest, db db.Store) (*types.AccountsResponse, error) {
+
+	if search.Sort == "" {
+		search.Sort = types.AccountSortFirstName
 	}
-	rows, err := stmt.Query(email)
-	if err != nil {
-		return nil, err
+	if !utils.Contains(search.Sort, types.AccountSortFields) {
+		return nil, errors.New("Invalid sort field: " + search.Sort)
 	}
-	stmt.Close()
-	defer rows.Close()
-	for rows.Next() {
-		account := types.Account{}
-		err = sqlstruct.Scan(&account, rows)
+
+	limit := search.Limit
+	if limit <= 0 {
+		limit = types.AccountDefaultPageLimit
+	}
+	if limit > types.AccountMaxPageLimit {
+		limit = types.AccountMaxPageLimit
+	}
+
+	//A 0 role means every account
+	for _, role := range search.Roles {
+		if role == 0 {
+			search.Roles = nil
+			break
+		}
+	}
+
+	//Continue after the last account of the previous page
+	var after *types.Account
+	if search.Cursor != "" {
+		var err error
+		after, err = decodeAccountCursor(search.Cursor, search)
 		if err != nil {
 			return nil, err
 		}
-		return &account, nil
 	}
-	return nil, nil
-}
 
-//GetAccountByID - returns an account by ID
-func (dao AccountDAO) GetAccountByID(id string, db *db.MySQL) (*types.Account, error) {
-	stmt, err := db.PreparedQuery("SELECT * FROM users WHERE id = ?")
+	//Fetch one extra row to know if there is another page
+	accounts, err := db.SearchAccounts(ctx, search, after, limit+1)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(id)
+
+	total, err := db.CountAccounts(ctx, search)
 	if err != nil {
 		return nil, err
 	}
-	stmt.Close()
-	defer rows.Close()
-	for rows.Next() {
-		account := types.Account{}
-		err = sqlstruct.Scan(&account, rows)
+
+	next := ""
+	if len(*accounts) > limit {
+		*accounts = (*accounts)[:limit]
+		next, err = encodeAccountCursor(&(*accounts)[limit-1], search)
 		if err != nil {
 			return nil, err
 		}
-		return &account, nil
 	}
-	return nil, nil
-}
-
-//GetAccounts - returns all accounts with the role given
-func (dao AccountDAO) GetAccounts(roles []int, db *db.MySQL) (*[]types.Account, error) {
 
-	if len(roles) <= 0 {
-		return nil, errors.New("Roles array is empty")
+	for i := range *accounts {
+		(*accounts)[i].HideImportant()
+		(*accounts)[i].GetAccountPermissions()
 	}
 
-	query := "SELECT * FROM users WHERE role = '" + strconv.Itoa(roles[0]) + "'"
+	return &types.AccountsResponse{Accounts: accounts, NextCursor: next, Total: total}, nil
+}
 
-	for i, r := range roles {
-		if i == 0 {
-			continue
-		}
-		query += " OR role = '" + strconv.Itoa(r) + "'"
-	}
+//accountCursor - where a page of accounts ended. The sort is kept so a cursor cannot be reused with a different order
+type accountCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
 
-	//If the first role is 0, then we get all accounts
-	if roles[0] == 0 {
-		query = "SELECT * FROM users"
+//encodeAccountCursor - creates an opaque cursor pointing at an account
+func encodeAccountCursor(account *types.Account, search *types.AccountSearchRequest) (string, error) {
+	cursor := accountCursor{Sort: search.Sort, Desc: search.Desc, ID: account.ID}
+	switch search.Sort {
+	case types.AccountSortLastName:
+		cursor.Value = account.LastName
+	case types.AccountSortEmail:
+		cursor.Value = account.Email
+	case types.AccountSortCreated:
+		cursor.Value = account.Created.UTC().Format(time.RFC3339Nano)
+	default:
+		cursor.Value = account.FirstName
+	}
+
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
 
-	rows, err := db.SimpleQuery(query + " ORDER BY firstName ASC")
+//decodeAccountCursor - reads a cursor created by encodeAccountCursor for the same search order
+func decodeAccountCursor(encoded string, search *types.AccountSearchRequest) (*types.Account, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
 	if err != nil {
-		return nil, err
+		return nil, errors.New("Invalid cursor")
+	}
+	cursor := accountCursor{}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, errors.New("Invalid cursor")
+	}
+	if cursor.Sort != search.Sort || cursor.Desc != search.Desc || cursor.ID == "" {
+		return nil, errors.New("Cursor does not match the sort order")
 	}
-	accounts := []types.Account{}
-	defer rows.Close()
-	for rows.Next() {
-		account := types.Account{}
-		err := sqlstruct.Scan(&account, rows)
+
+	account := &types.Account{ID: cursor.ID}
+	switch cursor.Sort {
+	case types.AccountSortLastName:
+		account.LastName = cursor.Value
+	case types.AccountSortEmail:
+		account.Email = cursor.Value
+	case types.AccountSortCreated:
+		account.Created, err = time.Parse(time.RFC3339Nano, cursor.Value)
 		if err != nil {
-			//fmt.Println(err) -> fails to convert NULL to datatype
+			return nil, errors.New("Invalid cursor")
 		}
-		account.HideImportant()
-		account.GetAccountPermissions()
-		accounts = append(accounts, account)
+	default:
+		account.FirstName = cursor.Value
 	}
-	return &accounts, nil
+	return account, nil
 }
 
 //UpdateSettings - updates the requesting accounts settings
-func (dao AccountDAO) UpdateSettings(updatedAccount *types.Account, id string, db *db.MySQL) (string, error) {
+func (dao AccountDAO) UpdateSettings(ctx context.Context, updatedAccount *types.Account, id string, db db.Store) (string, error) {
 
 	if err := updatedAccount.CheckName(); err != nil {
 		return err.Error(), nil
@@ -198,54 +448,61 @@ func (dao AccountDAO) UpdateSettings(updatedAccount *types.Account, id string, d
 	if err := updatedAccount.CheckPhone(); err != nil {
 		return err.Error(), nil
 	}
-
-	stmt, err := db.PreparedQuery("UPDATE users SET firstName = ?, lastName = ?, phone = ? WHERE id = ?")
-	if err != nil {
-		return "", err
+	if err := updatedAccount.CheckLanguage(); err != nil {
+		return err.Error(), nil
 	}
-	_, err = stmt.Query(updatedAccount.FirstName, updatedAccount.LastName, updatedAccount.Phone, id)
+
+	updatedAccount.ID = id
+	err := db.UpdateSettings(ctx, updatedAccount)
 	if err != nil {
 		return "", err
 	}
-	stmt.Close()
 
 	return "", nil
 }
 
 //UpdateAccount - updates the another users account settings
-func (dao AccountDAO) UpdateAccount(updatedAccount *types.Account, db *db.MySQL) (string, error) {
+func (dao AccountDAO) UpdateAccount(ctx context.Context, updatedAccount *types.Account, db db.Store) (string, error) {
 
 	if err := updatedAccount.CheckPhone(); err != nil {
 		return err.Error(), nil
 	}
+	if err := updatedAccount.CheckLanguage(); err != nil {
+		return err.Error(), nil
+	}
 	if err := updatedAccount.CheckEmail(); err != nil {
 		return err.Error(), nil
 	}
 
-	//Check if account details already exist with another account
-	isDuplicate, err := dao.CheckDuplicates(updatedAccount.ID, updatedAccount.Email, db)
-	if err != nil {
-		return "", err
-	}
-	if isDuplicate != "" {
-		return isDuplicate, nil
-	}
+	return dao.updateAccount(ctx, updatedAccount, db)
+}
 
-	stmt, err := db.PreparedQuery("UPDATE users SET firstName = ?, lastName = ?, email = ?, phone = ?, role = ? WHERE id = ?")
-	if err != nil {
-		return "", err
-	}
-	_, err = stmt.Query(updatedAccount.FirstName, updatedAccount.LastName, updatedAccount.Email, updatedAccount.Phone, updatedAccount.Role, updatedAccount.ID)
+//updateAccount - saves a verified account if its email is not taken
+func (dao AccountDAO) updateAccount(ctx context.Context, updatedAccount *types.Account, db db.Store) (string, error) {
+	res := ""
+	err := db.InTx(ctx, func(tx store) error {
+		//Check if account details already exist with another account
+		isDuplicate, err := dao.CheckDuplicates(ctx, updatedAccount.ID, updatedAccount.Email, tx)
+		if err != nil || isDuplicate != "" {
+			res = isDuplicate
+			return err
+		}
+
+		if err := tx.UpdateAccount(ctx, updatedAccount); err != nil {
+			return err
+		}
+
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountUpdated, updatedAccount.ID, types.NewWebhookAccount(updatedAccount), tx)
+	})
 	if err != nil {
 		return "", err
 	}
-	stmt.Close()
 
-	return "", nil
+	return res, nil
 }
 
 //ChangeAccountPassword - updates the requesting accounts password
-func (dao AccountDAO) ChangeAccountPassword(account *types.Account, passwordRequest *types.UpdateAccountPassword, db *db.MySQL) (string, error) {
+func (dao AccountDAO) ChangeAccountPassword(ctx context.Context, account *types.Account, passwordRequest *types.UpdateAccountPassword, db db.Store) (string, error) {
 
 	//Set the new password
 	account.Password = passwordRequest.NewPassword
@@ -263,15 +520,10 @@ func (dao AccountDAO) ChangeAccountPassword(account *types.Account, passwordRequ
 
 	account.Password = hash
 
-	stmt, err := db.PreparedQuery("UPDATE users SET password = ? WHERE id = ?")
-	if err != nil {
-		return "", err
-	}
-	_, err = stmt.Query(account.Password, account.ID)
+	err = db.UpdatePassword(ctx, account.ID, account.Password)
 	if err != nil {
 		return "", err
 	}
-	stmt.Close()
 
 	return "", nil
 }
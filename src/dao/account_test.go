@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"db"
+	"testing"
+	"time"
+	"types"
+)
+
+func TestEndingAccountStopsImpersonations(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		end  func(account *types.Account, store db.Store) error
+		//actor - end the admin rather than the account being impersonated
+		actor bool
+	}{
+		{name: "account disabled", end: func(account *types.Account, store db.Store) error {
+			_, err := AccountDAO{}.DisableAccount(ctx, account, "test", store)
+			return err
+		}},
+		{name: "account deleted", end: func(account *types.Account, store db.Store) error {
+			return AccountDAO{}.DeleteAccount(ctx, account, store)
+		}},
+		{name: "admin disabled", actor: true, end: func(account *types.Account, store db.Store) error {
+			_, err := AccountDAO{}.DisableAccount(ctx, account, "test", store)
+			return err
+		}},
+		{name: "admin deleted", actor: true, end: func(account *types.Account, store db.Store) error {
+			return AccountDAO{}.DeleteAccount(ctx, account, store)
+		}},
+	}
+
+	for _, test := range tests {
+		store := db.Memory{}.Init()
+		admin := &types.Account{ID: "admin", Email: "admin@example.com", Role: 999}
+		target := &types.Account{ID: "target", Email: "target@example.com", Role: 100}
+		bystander := &types.Account{ID: "bystander", Email: "bystander@example.com", Role: 100}
+		for _, account := range []*types.Account{admin, target, bystander} {
+			if err := store.InsertAccount(ctx, account); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		impersonation, _, err := ImpersonationDAO{}.StartImpersonation(ctx, admin.ID, target.ID, "test", time.Now().Add(time.Hour), store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, _, err := ImpersonationDAO{}.StartImpersonation(ctx, admin.ID, bystander.ID, "test", time.Now().Add(time.Hour), store)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ended := target
+		if test.actor {
+			ended = admin
+		}
+		if err := test.end(ended, store); err != nil {
+			t.Fatal(err)
+		}
+
+		active, err := ImpersonationDAO{}.IsActive(ctx, impersonation.ID, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active {
+			t.Errorf("%s: impersonation is still active", test.name)
+		}
+
+		//Only the admin ending stops their other impersonations
+		active, err = ImpersonationDAO{}.IsActive(ctx, other.ID, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active == test.actor {
+			t.Errorf("%s: other impersonation active %v, expected %v", test.name, active, !test.actor)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"context"
+	"db"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+	"types"
+)
+
+//AuditDAO - data access for audit events
+type AuditDAO struct {
+}
+
+//SaveEvent - saves an audit event to the db
+func (dao AuditDAO) SaveEvent(ctx context.Context, event *types.AuditEvent, db db.Store) error {
+	return db.InsertEvent(ctx, event)
+}
+
+//SearchEvents - returns a page of audit events matching the search, newest first.
+//Returns the cursor for the next page, empty if this is the last page
+func (dao AuditDAO) SearchEvents(ctx context.Context, search *types.AuditSearchRequest, db db.Store) (*[]types.AuditEvent, string, error) {
+
+	limit := search.Limit
+	if limit <= 0 {
+		limit = types.AuditDefaultPageLimit
+	}
+	if limit > types.AuditMaxPageLimit {
+		limit = types.AuditMaxPageLimit
+	}
+
+	//Continue after the last event of the previous page
+	var after *types.AuditEvent
+	if search.Cursor != "" {
+		created, id, err := decodeAuditCursor(search.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &types.AuditEvent{ID: id, Created: created}
+	}
+
+	//Fetch one extra row to know if there is another page
+	events, err := db.SearchEvents(ctx, search, after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(*events) > limit {
+		*events = (*events)[:limit]
+		last := (*events)[limit-1]
+		next = encodeAuditCursor(last.Created, last.ID)
+	}
+
+	return events, next, nil
+}
+
+//encodeAuditCursor - creates an opaque cursor pointing at an event
+func encodeAuditCursor(created time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(created.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+//decodeAuditCursor - reads a cursor created by encodeAuditCursor
+func decodeAuditCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", errors.New("Invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", errors.New("Invalid cursor")
+	}
+	created, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", errors.New("Invalid cursor")
+	}
+	return created, parts[1], nil
+}
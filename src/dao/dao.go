@@ -0,0 +1,16 @@
+package dao
+
+import (
+	"crypto/sha256"
+	"db"
+	"encoding/hex"
+)
+
+//store - db.Store under a name the db parameters of DAO methods do not hide, for transaction callbacks
+type store = db.Store
+
+//hashSecret - returns the sha256 of a token secret. Secrets are random so they do not need a slow hash like passwords
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,13 +1,13 @@
 package dao
 
 import (
+	"context"
 	"db"
 	"time"
 	"types"
 	"utils"
 
 	"github.com/google/uuid"
-	"github.com/kisielk/sqlstruct"
 )
 
 //DeviceDAO - device data access object
@@ -15,61 +15,37 @@ type DeviceDAO struct {
 }
 
 //GetDevice - returns a device
-func (dao DeviceDAO) GetDevice(deviceID string, db *db.MySQL) (*types.Device, error) {
-
-	stmt, err := db.PreparedQuery("SELECT * FROM devices WHERE id = ?")
-	if err != nil {
-		return nil, err
-	}
-	rows, err := stmt.Query(deviceID)
-	if err != nil {
-		return nil, err
-	}
-	stmt.Close()
-	defer rows.Close()
-	for rows.Next() {
-		device := types.Device{}
-		err = sqlstruct.Scan(&device, rows)
-		if err != nil {
-			return nil, err
-		}
-		return &device, nil
-	}
-	return nil, nil
+func (dao DeviceDAO) GetDevice(ctx context.Context, deviceID string, db db.Store) (*types.Device, error) {
+	return db.GetDevice(ctx, deviceID)
 }
 
-//CreateDevice - creates a new device
-func (dao DeviceDAO) CreateDevice(account *types.Account, db *db.MySQL) (*types.Device, error) {
+//CreateDevice - creates a new device first seen from the client given
+func (dao DeviceDAO) CreateDevice(ctx context.Context, account *types.Account, client *types.ClientDetails, db db.Store) (*types.Device, error) {
 	device := types.Device{ID: uuid.New().String(), AccountID: account.ID, Created: time.Now(), Active: false, Code: utils.RandomCode()}
+	device.Seen(client)
 
-	stmt, err := db.PreparedQuery("INSERT INTO devices (id, accountId, created, active, code) VALUES(?,?,?,?,?)")
+	err := db.InsertDevice(ctx, &device)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(device.ID, device.AccountID, device.Created, device.Active, device.Code)
-	if err != nil {
-		return nil, err
-	}
-
-	stmt.Close()
-	defer rows.Close()
 
 	return &device, nil
 }
 
-//ActivateDevice - activate the given device
-func (dao DeviceDAO) ActivateDevice(deviceID string, db *db.MySQL) error {
-	stmt, err := db.PreparedQuery("UPDATE devices SET active = 1 WHERE id = ?")
-	if err != nil {
-		return err
-	}
-	rows, err := stmt.Query(deviceID)
-	if err != nil {
-		return err
-	}
+//TouchDevice - records that the device was just used from the client given
+func (dao DeviceDAO) TouchDevice(ctx context.Context, device *types.Device, client *types.ClientDetails, db db.Store) error {
+	device.Seen(client)
+	return db.TouchDevice(ctx, device)
+}
 
-	stmt.Close()
-	defer rows.Close()
+//ActivateDevice - activate the given device
+func (dao DeviceDAO) ActivateDevice(ctx context.Context, device *types.Device, db db.Store) error {
+	return db.InTx(ctx, func(tx store) error {
+		if err := tx.ActivateDevice(ctx, device.ID); err != nil {
+			return err
+		}
+		device.Active = true
 
-	return nil
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookDeviceActivated, device.AccountID, types.NewWebhookDevice(device), tx)
+	})
 }
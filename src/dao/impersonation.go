@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"context"
+	"db"
+	"strings"
+	"time"
+	"types"
+
+	"github.com/google/uuid"
+)
+
+//ImpersonationDAO - data access for admin impersonations
+type ImpersonationDAO struct {
+}
+
+//StartImpersonation - records an admin starting to act as an account until expires
+func (dao ImpersonationDAO) StartImpersonation(ctx context.Context, actorID string, targetID string, reason string, expires time.Time, db db.Store) (*types.Impersonation, string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, "A reason is required", nil
+	}
+	if len(reason) > 255 {
+		return nil, "Reason must be 255 characters or less", nil
+	}
+
+	impersonation := types.Impersonation{
+		ID:       uuid.New().String(),
+		ActorID:  actorID,
+		TargetID: targetID,
+		Reason:   reason,
+		Started:  time.Now(),
+		Expires:  expires,
+	}
+
+	err := db.InsertImpersonation(ctx, &impersonation)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &impersonation, "", nil
+}
+
+//IsActive - checks an impersonation exists, has not expired and was not stopped
+func (dao ImpersonationDAO) IsActive(ctx context.Context, id string, db db.Store) (bool, error) {
+	impersonation, err := db.GetImpersonation(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return impersonation != nil && impersonation.Stopped == nil && impersonation.Expires.After(time.Now()), nil
+}
+
+//StopImpersonation - stops an impersonation, which revokes its token
+func (dao ImpersonationDAO) StopImpersonation(ctx context.Context, id string, db db.Store) error {
+	return db.StopImpersonation(ctx, id, time.Now())
+}
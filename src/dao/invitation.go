@@ -0,0 +1,100 @@
+package dao
+
+import (
+	"context"
+	"db"
+	"time"
+	"types"
+	"utils"
+
+	"github.com/google/uuid"
+)
+
+//InvitationDAO - data access for invitations
+type InvitationDAO struct {
+}
+
+//CheckInvitation - verifies an invitation could be created.
+//Returns empty string and no error if it can
+//Returns string with an error message if it cannot
+func (dao InvitationDAO) CheckInvitation(ctx context.Context, request *types.InvitationRequest, db db.Store) (string, error) {
+	invitee := types.Account{Email: request.Email}
+	if err := invitee.CheckEmail(); err != nil {
+		return err.Error(), nil
+	}
+	if len(request.Organization) > 255 {
+		return "Organization must be 255 characters or less", nil
+	}
+	for _, role := range request.Roles {
+		if role != "ADMIN" && role != "DEFAULT" {
+			return "Unknown role: " + role, nil
+		}
+	}
+
+	return AccountDAO{}.CheckDuplicates(ctx, "", request.Email, db)
+}
+
+//CreateInvitation - verifies and creates an invitation for the email that expires after the duration given
+func (dao InvitationDAO) CreateInvitation(ctx context.Context, request *types.InvitationRequest, adminID string, duration time.Duration, db db.Store) (*types.Invitation, string, error) {
+	res, err := dao.CheckInvitation(ctx, request, db)
+	if err != nil || res != "" {
+		return nil, res, err
+	}
+
+	token, err := utils.RandomSecret(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	invitation := types.Invitation{
+		ID:           uuid.New().String(),
+		Email:        request.Email,
+		Token:        token,
+		Role:         types.GetRole(request.Roles),
+		Organization: request.Organization,
+		InvitedBy:    adminID,
+		Created:      now,
+		Expires:      now.Add(duration),
+	}
+
+	err = db.InsertInvitation(ctx, &invitation)
+	if err != nil {
+		return nil, "", err
+	}
+	invitation.GetInvitationPermissions()
+
+	return &invitation, "", nil
+}
+
+//GetInvitation - returns an invitation
+func (dao InvitationDAO) GetInvitation(ctx context.Context, id string, db db.Store) (*types.Invitation, error) {
+	invitation, err := db.GetInvitation(ctx, id)
+	if err != nil || invitation == nil {
+		return nil, err
+	}
+	invitation.GetInvitationPermissions()
+	return invitation, nil
+}
+
+//GetInvitations - returns invitations, newest first
+func (dao InvitationDAO) GetInvitations(ctx context.Context, request *types.InvitationsRequest, db db.Store) (*[]types.Invitation, error) {
+	limit := request.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	invitations, err := db.GetInvitations(ctx, request.Pending, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range *invitations {
+		(*invitations)[i].GetInvitationPermissions()
+	}
+	return invitations, nil
+}
+
+//RevokeInvitation - removes an invitation so it can no longer be used
+func (dao InvitationDAO) RevokeInvitation(ctx context.Context, invitation *types.Invitation, db db.Store) error {
+	return db.DeleteInvitation(ctx, invitation.ID)
+}
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"crypto/subtle"
+	"db"
+	"time"
+	"types"
+	"utils"
+
+	"github.com/google/uuid"
+)
+
+//LoginCodeDAO - data access for emailed login codes
+type LoginCodeDAO struct {
+}
+
+//CreateLoginCode - replaces the login code of an account with a new one of the digits given. The code is only ever returned here
+func (dao LoginCodeDAO) CreateLoginCode(ctx context.Context, accountID string, digits int, duration time.Duration, db db.Store) (*types.LoginCode, string, error) {
+	secret, err := utils.RandomDigits(digits)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	code := types.LoginCode{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Hash:      hashSecret(secret),
+		Created:   now,
+		Expires:   now.Add(duration),
+	}
+
+	//Only the newest code works
+	err = db.InTx(ctx, func(tx store) error {
+		if err := tx.DeleteLoginCodes(ctx, accountID); err != nil {
+			return err
+		}
+		return tx.InsertLoginCode(ctx, &code)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &code, secret, nil
+}
+
+//UseLoginCode - checks the code given against the login code of an account and uses it up if it matches.
+//Every try counts against maxAttempts, and once they are used up the code no longer works. Returns a reason if it cannot be used
+func (dao LoginCodeDAO) UseLoginCode(ctx context.Context, accountID string, secret string, maxAttempts int, db db.Store) (string, error) {
+	code, err := db.GetLoginCode(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	if code == nil || code.Expires.Before(time.Now()) {
+		return "Code is invalid or has expired", nil
+	}
+
+	//Take the attempt before comparing so guesses sent at the same time cannot go over the limit
+	allowed, err := db.AddLoginCodeAttempt(ctx, code.ID, maxAttempts)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "Too many attempts, request a new code", nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code.Hash), []byte(hashSecret(secret))) != 1 {
+		return "Code is invalid or has expired", nil
+	}
+
+	used, err := db.UseLoginCode(ctx, code.ID)
+	if err != nil {
+		return "", err
+	}
+	if !used {
+		return "Code is invalid or has expired", nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,63 @@
+package dao
+
+import (
+	"context"
+	"db"
+	"testing"
+	"time"
+)
+
+func TestUseLoginCode(t *testing.T) {
+	const (
+		invalid  = "Code is invalid or has expired"
+		tooMany  = "Too many attempts, request a new code"
+		right    = "right"
+		wrong    = "wrong"
+		previous = "previous"
+	)
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		guesses  []string
+		results  []string
+	}{
+		{name: "right code", duration: time.Minute, guesses: []string{right}, results: []string{""}},
+		{name: "wrong then right", duration: time.Minute, guesses: []string{wrong, wrong, right}, results: []string{invalid, invalid, ""}},
+		{name: "attempts used up", duration: time.Minute, guesses: []string{wrong, wrong, wrong, right}, results: []string{invalid, invalid, invalid, tooMany}},
+		{name: "used once", duration: time.Minute, guesses: []string{right, right}, results: []string{"", invalid}},
+		{name: "expired", duration: -time.Minute, guesses: []string{right}, results: []string{invalid}},
+		{name: "replaced code", duration: time.Minute, guesses: []string{previous, right}, results: []string{invalid, ""}},
+	}
+
+	ctx := context.Background()
+	for _, test := range tests {
+		store := db.Memory{}.Init()
+
+		_, old, err := LoginCodeDAO{}.CreateLoginCode(ctx, "account", 6, test.duration, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, secret, err := LoginCodeDAO{}.CreateLoginCode(ctx, "account", 6, test.duration, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, guess := range test.guesses {
+			switch guess {
+			case right:
+				guess = secret
+			case previous:
+				guess = old
+			}
+
+			res, err := LoginCodeDAO{}.UseLoginCode(ctx, "account", guess, 3, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != test.results[i] {
+				t.Errorf("%s: guess %d got %q, expected %q", test.name, i+1, res, test.results[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"context"
+	"crypto/subtle"
+	"db"
+	"time"
+	"types"
+	"utils"
+
+	"github.com/google/uuid"
+)
+
+//MagicLinkDAO - data access for magic login links
+type MagicLinkDAO struct {
+}
+
+//CreateMagicLink - replaces the magic links of an account with one that only works with the browser nonce given. The token is only ever returned here
+func (dao MagicLinkDAO) CreateMagicLink(ctx context.Context, accountID string, nonce string, duration time.Duration, db db.Store) (*types.MagicLink, string, error) {
+	token, err := utils.RandomSecret(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	link := types.MagicLink{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Hash:      hashSecret(token),
+		NonceHash: hashSecret(nonce),
+		Created:   now,
+		Expires:   now.Add(duration),
+	}
+
+	//Only the newest link works so an old email can not be used
+	err = db.InTx(ctx, func(tx store) error {
+		if err := tx.DeleteMagicLinks(ctx, accountID); err != nil {
+			return err
+		}
+		return tx.InsertMagicLink(ctx, &link)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &link, token, nil
+}
+
+//UseMagicLink - verifies a magic link against its token and the nonce of the browser using it, then uses it up.
+//Returns a reason and no link if it cannot be used
+func (dao MagicLinkDAO) UseMagicLink(ctx context.Context, id string, token string, nonce string, db db.Store) (*types.MagicLink, string, error) {
+	link, err := db.GetMagicLink(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if link == nil || subtle.ConstantTimeCompare([]byte(link.Hash), []byte(hashSecret(token))) != 1 {
+		return nil, "Login link is invalid or has already been used", nil
+	}
+	if link.Expires.Before(time.Now()) {
+		return nil, "Login link has expired", nil
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(link.NonceHash), []byte(hashSecret(nonce))) != 1 {
+		return nil, "Login link must be opened in the browser it was requested from", nil
+	}
+
+	//Deleting it is what uses it, so only one request can log in with it
+	used, err := db.UseMagicLink(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if !used {
+		return nil, "Login link is invalid or has already been used", nil
+	}
+
+	return link, "", nil
+}
@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"context"
+	"db"
+	"time"
+	"types"
+
+	"github.com/google/uuid"
+)
+
+//OutboxDAO - data access for the email outbox
+type OutboxDAO struct {
+}
+
+//Enqueue - queues an email to be sent by the outbox worker.
+//Call it in the same transaction as the change so the email is queued only if the change is saved
+func (dao OutboxDAO) Enqueue(ctx context.Context, email *types.OutboxEmail, db db.Store) error {
+	now := time.Now()
+	email.ID = uuid.New().String()
+	email.Status = types.EmailPending
+	email.Attempts = 0
+	email.NextAttempt = now
+	email.Created = now
+	email.Updated = now
+
+	return db.InsertEmail(ctx, email)
+}
+
+//GetDueEmails - returns pending emails whose next attempt is due
+func (dao OutboxDAO) GetDueEmails(ctx context.Context, limit int, db db.Store) (*[]types.OutboxEmail, error) {
+	return db.GetDueEmails(ctx, time.Now(), limit)
+}
+
+//ClaimEmail - pushes a due email's next attempt out to lockUntil so no other worker picks it up.
+//Returns false if another worker claimed it first.
+func (dao OutboxDAO) ClaimEmail(ctx context.Context, email *types.OutboxEmail, lockUntil time.Time, db db.Store) (bool, error) {
+	return db.ClaimEmail(ctx, email, lockUntil)
+}
+
+//UpdateEmail - saves the result of a send attempt
+func (dao OutboxDAO) UpdateEmail(ctx context.Context, email *types.OutboxEmail, db db.Store) error {
+	email.Updated = time.Now()
+	return db.UpdateEmail(ctx, email)
+}
+
+//GetEmail - returns an outbox email
+func (dao OutboxDAO) GetEmail(ctx context.Context, id string, db db.Store) (*types.OutboxEmail, error) {
+	return db.GetEmail(ctx, id)
+}
+
+//GetEmails - returns the outbox, newest first
+func (dao OutboxDAO) GetEmails(ctx context.Context, request *types.OutboxRequest, db db.Store) (*[]types.OutboxEmail, error) {
+	limit := request.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	return db.GetEmails(ctx, request.Status, limit)
+}
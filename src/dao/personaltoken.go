@@ -0,0 +1,139 @@
+package dao
+
+import (
+	"context"
+	"crypto/subtle"
+	"db"
+	"strconv"
+	"strings"
+	"time"
+	"types"
+	"utils"
+
+	"github.com/google/uuid"
+)
+
+//PersonalTokenDAO - data access for personal access tokens
+type PersonalTokenDAO struct {
+}
+
+//CreateToken - creates a personal access token for an account. The full token is only ever returned here
+func (dao PersonalTokenDAO) CreateToken(ctx context.Context, accountID string, request *types.PersonalTokenRequest, db db.Store) (*types.PersonalToken, string, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, "A name is required", nil
+	}
+	if len(name) > 255 {
+		return nil, "Name must be 255 characters or less", nil
+	}
+
+	if len(request.Scopes) == 0 {
+		return nil, "At least one scope is required", nil
+	}
+	scopes := []string{}
+	for _, scope := range request.Scopes {
+		if !utils.Contains(scope, types.Scopes) {
+			return nil, "Invalid scope: " + scope, nil
+		}
+		if !utils.Contains(scope, scopes) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	days := request.ExpiresDays
+	if days == 0 {
+		days = types.PersonalTokenDefaultDays
+	}
+	if days < 1 || days > types.PersonalTokenMaxDays {
+		return nil, "Tokens must expire within 1 to " + strconv.Itoa(types.PersonalTokenMaxDays) + " days", nil
+	}
+
+	secret, err := utils.RandomSecret(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	token := types.PersonalToken{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Name:      name,
+		Hash:      hashSecret(secret),
+		Scope:     strings.Join(scopes, " "),
+		Created:   now,
+		Expires:   now.AddDate(0, 0, days),
+	}
+
+	err = db.InsertPersonalToken(ctx, &token)
+	if err != nil {
+		return nil, "", err
+	}
+	token.GetScopes()
+	token.Token = types.PersonalTokenPrefix + token.ID + "." + secret
+
+	return &token, "", nil
+}
+
+//IsPersonalToken - checks if a bearer token is a personal access token rather than a JWT
+func (dao PersonalTokenDAO) IsPersonalToken(bearer string) bool {
+	return strings.HasPrefix(bearer, types.PersonalTokenPrefix)
+}
+
+//VerifyToken - returns the personal access token a bearer token belongs to, or nil if it is not valid or has expired
+func (dao PersonalTokenDAO) VerifyToken(ctx context.Context, bearer string, db db.Store) (*types.PersonalToken, error) {
+	parts := strings.SplitN(strings.TrimPrefix(bearer, types.PersonalTokenPrefix), ".", 2)
+	if len(parts) != 2 {
+		return nil, nil
+	}
+
+	token, err := db.GetPersonalToken(ctx, parts[0])
+	if err != nil || token == nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hashSecret(parts[1]))) != 1 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if token.Expires.Before(now) {
+		return nil, nil
+	}
+
+	//Only record use once a minute so busy scripts dont write on every request
+	if token.LastUsed == nil || token.LastUsed.Before(now.Add(-1*time.Minute)) {
+		if err := db.TouchPersonalToken(ctx, token.ID, now); err != nil {
+			return nil, err
+		}
+		token.LastUsed = &now
+	}
+
+	token.GetScopes()
+	return token, nil
+}
+
+//GetToken - returns a personal access token
+func (dao PersonalTokenDAO) GetToken(ctx context.Context, id string, db db.Store) (*types.PersonalToken, error) {
+	token, err := db.GetPersonalToken(ctx, id)
+	if err != nil || token == nil {
+		return nil, err
+	}
+	token.GetScopes()
+	return token, nil
+}
+
+//GetTokens - returns the personal access tokens of an account, newest first
+func (dao PersonalTokenDAO) GetTokens(ctx context.Context, accountID string, db db.Store) (*[]types.PersonalToken, error) {
+	tokens, err := db.GetPersonalTokens(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range *tokens {
+		(*tokens)[i].GetScopes()
+	}
+	return tokens, nil
+}
+
+//RevokeToken - removes a personal access token so it can no longer be used
+func (dao PersonalTokenDAO) RevokeToken(ctx context.Context, token *types.PersonalToken, db db.Store) error {
+	return db.DeletePersonalToken(ctx, token.ID)
+}
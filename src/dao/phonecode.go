@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"context"
+	"crypto/subtle"
+	"db"
+	"time"
+	"types"
+	"utils"
+
+	"github.com/google/uuid"
+)
+
+//PhoneCodeDAO - data access for codes sent by text message or phone call
+type PhoneCodeDAO struct {
+}
+
+//CountPhoneCodes - returns how many codes have been sent to a number since the time given
+func (dao PhoneCodeDAO) CountPhoneCodes(ctx context.Context, phone string, since time.Time, db db.Store) (int, error) {
+	return db.CountPhoneCodes(ctx, phone, since)
+}
+
+//CreatePhoneCode - saves a new code for a number and purpose, replacing any earlier one for the purpose. The code is only ever returned here
+func (dao PhoneCodeDAO) CreatePhoneCode(ctx context.Context, accountID string, phone string, purpose string, channel string, digits int, duration time.Duration, db db.Store) (*types.PhoneCode, string, error) {
+	secret, err := utils.RandomDigits(digits)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	code := types.PhoneCode{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Phone:     phone,
+		Purpose:   purpose,
+		Channel:   channel,
+		Hash:      hashSecret(secret),
+		Created:   now,
+		Expires:   now.Add(duration),
+	}
+
+	if err := db.InsertPhoneCode(ctx, &code); err != nil {
+		return nil, "", err
+	}
+	return &code, secret, nil
+}
+
+//UsePhoneCode - checks the code given against the newest code of an account for a purpose and uses it up if it matches.
+//Every try counts against maxAttempts. Returns the code that matched, or a reason if none did
+func (dao PhoneCodeDAO) UsePhoneCode(ctx context.Context, accountID string, purpose string, secret string, maxAttempts int, db db.Store) (*types.PhoneCode, string, error) {
+	code, err := db.GetPhoneCode(ctx, accountID, purpose)
+	if err != nil {
+		return nil, "", err
+	}
+	if code == nil || code.Expires.Before(time.Now()) {
+		return nil, "Code is invalid or has expired", nil
+	}
+
+	//Take the attempt before comparing so guesses sent at the same time cannot go over the limit
+	allowed, err := db.AddPhoneCodeAttempt(ctx, code.ID, maxAttempts)
+	if err != nil {
+		return nil, "", err
+	}
+	if !allowed {
+		return nil, "Too many attempts, request a new code", nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, "Code is invalid or has expired", nil
+	}
+
+	used, err := db.UsePhoneCode(ctx, code.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !used {
+		return nil, "Code is invalid or has expired", nil
+	}
+
+	return code, "", nil
+}
+
+//SetVerifiedPhone - records the number an account proved it has
+func (dao PhoneCodeDAO) SetVerifiedPhone(ctx context.Context, account *types.Account, phone string, db db.Store) error {
+	if err := db.SetVerifiedPhone(ctx, account.ID, phone); err != nil {
+		return err
+	}
+	account.VerifiedPhone = phone
+	return nil
+}
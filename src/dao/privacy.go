@@ -0,0 +1,183 @@
+package dao
+
+import (
+	"context"
+	"db"
+	"sort"
+	"time"
+	"types"
+	"utils"
+
+	"github.com/google/uuid"
+)
+
+//PrivacyDAO - data access for data exports and erasure requests
+type PrivacyDAO struct {
+}
+
+//ExportAccount - gathers everything stored about an account
+func (dao PrivacyDAO) ExportAccount(ctx context.Context, account *types.Account, db db.Store) (*types.DataExport, error) {
+	devices, err := db.GetDevicesByAccount(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := db.GetRefreshTokensByAccount(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := types.NewDataExport(account, devices, tokens)
+
+	//Events the account did and events done to it, each only once
+	seen := map[string]bool{}
+	for _, search := range []types.AuditSearchRequest{{ActorID: account.ID}, {TargetID: account.ID}} {
+		events, err := dao.allEvents(ctx, search, db)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if !seen[event.ID] {
+				seen[event.ID] = true
+				export.AuditEvents = append(export.AuditEvents, event)
+			}
+		}
+	}
+	sort.Slice(export.AuditEvents, func(i, j int) bool {
+		return export.AuditEvents[i].Created.After(export.AuditEvents[j].Created)
+	})
+
+	requests, err := db.GetErasureRequests(ctx, account.ID, "", types.AuditMaxPageLimit)
+	if err != nil {
+		return nil, err
+	}
+	export.ErasureRequests = *requests
+
+	personalTokens, err := PersonalTokenDAO{}.GetTokens(ctx, account.ID, db)
+	if err != nil {
+		return nil, err
+	}
+	export.PersonalTokens = *personalTokens
+
+	return export, nil
+}
+
+//allEvents - returns every audit event matching the search
+func (dao PrivacyDAO) allEvents(ctx context.Context, search types.AuditSearchRequest, db db.Store) ([]types.AuditEvent, error) {
+	search.Limit = types.AuditMaxPageLimit
+
+	all := []types.AuditEvent{}
+	for {
+		events, next, err := AuditDAO{}.SearchEvents(ctx, &search, db)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, *events...)
+
+		if next == "" {
+			return all, nil
+		}
+		search.Cursor = next
+	}
+}
+
+//CreateErasureRequest - starts an erasure request that the account owner has to confirm by email
+func (dao PrivacyDAO) CreateErasureRequest(ctx context.Context, account *types.Account, db db.Store) (*types.ErasureRequest, error) {
+	token, err := utils.RandomSecret(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	request := types.ErasureRequest{
+		ID:        uuid.New().String(),
+		AccountID: account.ID,
+		Token:     token,
+		Status:    types.ErasureUnconfirmed,
+		Created:   now,
+		Updated:   now,
+	}
+
+	err = db.InsertErasureRequest(ctx, &request)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+//GetErasureRequest - returns an erasure request
+func (dao PrivacyDAO) GetErasureRequest(ctx context.Context, id string, db db.Store) (*types.ErasureRequest, error) {
+	return db.GetErasureRequest(ctx, id)
+}
+
+//GetErasureRequests - returns the erasure request queue, newest first
+func (dao PrivacyDAO) GetErasureRequests(ctx context.Context, request *types.ErasureRequestsRequest, db db.Store) (*[]types.ErasureRequest, error) {
+	limit := request.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	return db.GetErasureRequests(ctx, "", request.Status, limit)
+}
+
+//HasPendingErasure - checks if the account has a confirmed request waiting in the queue
+func (dao PrivacyDAO) HasPendingErasure(ctx context.Context, accountID string, db db.Store) (bool, error) {
+	requests, err := db.GetErasureRequests(ctx, accountID, types.ErasurePending, 1)
+	if err != nil {
+		return false, err
+	}
+	return len(*requests) > 0, nil
+}
+
+//ConfirmErasureRequest - moves a confirmed request into the admin queue
+func (dao PrivacyDAO) ConfirmErasureRequest(ctx context.Context, request *types.ErasureRequest, db db.Store) error {
+	request.Status = types.ErasurePending
+	request.Updated = time.Now()
+	return db.UpdateErasureRequest(ctx, request)
+}
+
+//RejectErasureRequest - closes a request without erasing anything
+func (dao PrivacyDAO) RejectErasureRequest(ctx context.Context, request *types.ErasureRequest, adminID string, note string, db db.Store) error {
+	request.Status = types.ErasureRejected
+	request.Note = note
+	request.HandledBy = adminID
+	request.Updated = time.Now()
+	return db.UpdateErasureRequest(ctx, request)
+}
+
+//EraseAccount - anonymizes an account and completes its erasure request.
+//The account row and its ID stay so audit events and other records still point at something, but nothing left identifies the person
+func (dao PrivacyDAO) EraseAccount(ctx context.Context, account *types.Account, request *types.ErasureRequest, adminID string, note string, db db.Store) error {
+	email := account.Email
+
+	account.Password = ""
+	account.FirstName = "Erased"
+	account.LastName = "Account"
+	account.Phone = ""
+	account.VerifiedPhone = ""
+	account.Email = "erased-" + account.ID + "@invalid"
+	account.Organization = ""
+	account.ExternalID = ""
+	account.TwoFA = false
+	account.Disabled = true
+	account.DisabledReason = "Erased on request"
+
+	request.Status = types.ErasureCompleted
+	request.Note = note
+	request.HandledBy = adminID
+	request.Updated = time.Now()
+
+	return db.InTx(ctx, func(tx store) error {
+		if err := tx.AnonymizeAccount(ctx, account); err != nil {
+			return err
+		}
+		if err := tx.ScrubEvents(ctx, account.ID, email); err != nil {
+			return err
+		}
+		if err := tx.UpdateErasureRequest(ctx, request); err != nil {
+			return err
+		}
+
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountErased, account.ID, types.NewWebhookAccount(account), tx)
+	})
+}
@@ -1,76 +1,59 @@
 package dao
 
 import (
+	"context"
 	"db"
 	"time"
 	"types"
 
 	"github.com/google/uuid"
-	"github.com/kisielk/sqlstruct"
 )
 
 //RecoverDAO - data access for recovery requests
 type RecoverDAO struct {
 }
 
+//recoveryExpiry - how long a recovery can be finished for, expired ones are removed by DeleteExpired
+const recoveryExpiry = time.Hour
+
 //CreateRecovery - creates a new recovery
-func (dao RecoverDAO) CreateRecovery(account *types.Account, db *db.MySQL) (*types.Recovery, error) {
+func (dao RecoverDAO) CreateRecovery(ctx context.Context, account *types.Account, db db.Store) (*types.Recovery, error) {
 
 	recovery := types.Recovery{ID: uuid.New().String(), AccountID: account.ID, Created: time.Now(), Email: account.Email}
 
-	stmt, err := db.PreparedQuery("INSERT INTO recover (id, accountId, created, email) VALUES(?,?,?,?)")
+	err := db.InsertRecovery(ctx, &recovery)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(recovery.ID, recovery.AccountID, recovery.Created, recovery.Email)
-	if err != nil {
-		return nil, err
-	}
-	stmt.Close()
-	defer rows.Close()
 	return &recovery, nil
 
 }
 
 //GetRecovery - returns a recovery from db
-func (dao RecoverDAO) GetRecovery(recovery *types.Recovery, db *db.MySQL) (*types.Recovery, error) {
-	stmt, err := db.PreparedQuery("SELECT * FROM recover WHERE id = ?")
-	if err != nil {
-		return nil, err
-	}
-	rows, err := stmt.Query(recovery.ID)
-	if err != nil {
-		return nil, err
-	}
-	stmt.Close()
-	defer rows.Close()
-	for rows.Next() {
-		rec := types.Recovery{}
-		err = sqlstruct.Scan(&rec, rows)
-		if err != nil {
-			return nil, err
-		}
-		return &rec, nil
-	}
-	return nil, nil
+func (dao RecoverDAO) GetRecovery(ctx context.Context, recovery *types.Recovery, db db.Store) (*types.Recovery, error) {
+	return db.GetRecovery(ctx, recovery.ID)
 }
 
 //FinishRecovery - completes a account recovery process
-func (dao RecoverDAO) FinishRecovery(account *types.Account, recoveryRequest *types.FinalRecoveryRequest, recovery *types.Recovery, db *db.MySQL) (string, error) {
+func (dao RecoverDAO) FinishRecovery(ctx context.Context, account *types.Account, recoveryRequest *types.FinalRecoveryRequest, recovery *types.Recovery, db db.Store) (string, error) {
 
-	stmt, err := db.PreparedQuery("UPDATE users SET password = ? WHERE id = ?")
-	if err != nil {
-		return "", err
+	if time.Since(recovery.Created) > recoveryExpiry {
+		return "Recovery has expired", nil
 	}
-	rows, err := stmt.Query(account.Password, account.ID)
+
+	//Deleting the recovery is what uses it, so only one request can change the password with it
+	res := ""
+	err := db.InTx(ctx, func(tx store) error {
+		used, err := tx.DeleteRecovery(ctx, recovery.ID)
+		if err != nil || !used {
+			res = "Recovery has already been used"
+			return err
+		}
+		return tx.UpdatePassword(ctx, account.ID, account.Password)
+	})
 	if err != nil {
 		return "", err
 	}
-	stmt.Close()
-	defer rows.Close()
-
-	//If this fails it will expire within the HOUR. The request is already completed.
-	_, _ = db.SimpleQuery("DELETE FROM recover WHERE id = '" + recovery.ID + "'")
 
-	return "", nil
+	return res, nil
 }
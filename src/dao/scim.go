@@ -0,0 +1,159 @@
+package dao
+
+import (
+	"context"
+	"crypto/subtle"
+	"db"
+	"strings"
+	"time"
+	"types"
+	"utils"
+
+	"github.com/google/uuid"
+)
+
+//SCIMDAO - data access for SCIM tokens and provisioned accounts
+type SCIMDAO struct {
+}
+
+//CreateToken - creates a SCIM token for an organization. The full token is only ever returned here
+func (dao SCIMDAO) CreateToken(ctx context.Context, request *types.SCIMTokenRequest, db db.Store) (*types.SCIMToken, string, error) {
+	organization := strings.TrimSpace(request.Organization)
+	if organization == "" {
+		return nil, "An organization is required", nil
+	}
+	if len(organization) > 255 {
+		return nil, "Organization must be 255 characters or less", nil
+	}
+	if len(request.Description) > 255 {
+		return nil, "Description must be 255 characters or less", nil
+	}
+
+	secret, err := utils.RandomSecret(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := types.SCIMToken{
+		ID:           uuid.New().String(),
+		Organization: organization,
+		Hash:         hashSecret(secret),
+		Description:  request.Description,
+		ManageAdmins: request.ManageAdmins,
+		Created:      time.Now(),
+	}
+
+	err = db.InsertSCIMToken(ctx, &token)
+	if err != nil {
+		return nil, "", err
+	}
+	token.Token = token.ID + "." + secret
+
+	return &token, "", nil
+}
+
+//VerifyToken - returns the SCIM token a bearer token belongs to, or nil if it is not valid
+func (dao SCIMDAO) VerifyToken(ctx context.Context, bearer string, db db.Store) (*types.SCIMToken, error) {
+	parts := strings.SplitN(bearer, ".", 2)
+	if len(parts) != 2 {
+		return nil, nil
+	}
+
+	token, err := db.GetSCIMToken(ctx, parts[0])
+	if err != nil || token == nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hashSecret(parts[1]))) != 1 {
+		return nil, nil
+	}
+
+	//Only record use once a minute so busy clients dont write on every request
+	now := time.Now()
+	if token.LastUsed == nil || token.LastUsed.Before(now.Add(-1*time.Minute)) {
+		if err := db.TouchSCIMToken(ctx, token.ID, now); err != nil {
+			return nil, err
+		}
+		token.LastUsed = &now
+	}
+
+	return token, nil
+}
+
+//GetToken - returns a SCIM token
+func (dao SCIMDAO) GetToken(ctx context.Context, id string, db db.Store) (*types.SCIMToken, error) {
+	return db.GetSCIMToken(ctx, id)
+}
+
+//GetTokens - returns every SCIM token
+func (dao SCIMDAO) GetTokens(ctx context.Context, db db.Store) (*[]types.SCIMToken, error) {
+	return db.GetSCIMTokens(ctx)
+}
+
+//RevokeToken - removes a SCIM token so it can no longer be used
+func (dao SCIMDAO) RevokeToken(ctx context.Context, token *types.SCIMToken, db db.Store) error {
+	return db.DeleteSCIMToken(ctx, token.ID)
+}
+
+//CreateAccount - verifies and creates a provisioned account. Phone and password are optional,
+//an account without a password has to set one through recovery or log in another way
+func (dao SCIMDAO) CreateAccount(ctx context.Context, account *types.Account, db db.Store) (string, error) {
+	if res := dao.checkAccount(account); res != "" {
+		return res, nil
+	}
+
+	if account.Password != "" {
+		if err := account.CheckPassword(); err != nil {
+			return err.Error(), nil
+		}
+		hash, err := utils.HashPassword(account.Password)
+		if err != nil {
+			return "", err
+		}
+		account.Password = hash
+	}
+
+	return AccountDAO{}.insertAccount(ctx, account, nil, db)
+}
+
+//UpdateAccount - verifies and saves the details of a provisioned account
+func (dao SCIMDAO) UpdateAccount(ctx context.Context, account *types.Account, db db.Store) (string, error) {
+	if res := dao.checkAccount(account); res != "" {
+		return res, nil
+	}
+
+	return AccountDAO{}.updateAccount(ctx, account, db)
+}
+
+//SetRole - changes the role of a provisioned account when its group membership changes
+func (dao SCIMDAO) SetRole(ctx context.Context, account *types.Account, role int, db db.Store) error {
+	return db.InTx(ctx, func(tx store) error {
+		account.Role = role
+		if err := tx.UpdateAccount(ctx, account); err != nil {
+			return err
+		}
+
+		return WebhookDAO{}.Enqueue(ctx, types.WebhookAccountUpdated, account.ID, types.NewWebhookAccount(account), tx)
+	})
+}
+
+//checkAccount - returns why a provisioned account is not valid, or an empty string if it is
+func (dao SCIMDAO) checkAccount(account *types.Account) string {
+	if err := account.CheckName(); err != nil {
+		return err.Error()
+	}
+	if err := account.CheckEmail(); err != nil {
+		return err.Error()
+	}
+	if account.Phone != "" {
+		if err := account.CheckPhone(); err != nil {
+			return err.Error()
+		}
+	}
+	if err := account.CheckLanguage(); err != nil {
+		return err.Error()
+	}
+	if len(account.ExternalID) > 255 {
+		return "External id must be 255 characters or less"
+	}
+	return ""
+}
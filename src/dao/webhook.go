@@ -0,0 +1,125 @@
+package dao
+
+import (
+	"context"
+	"db"
+	"encoding/json"
+	"time"
+	"types"
+
+	"github.com/google/uuid"
+)
+
+//WebhookDAO - data access for webhook subscriptions and deliveries
+type WebhookDAO struct {
+}
+
+//CreateSubscription - saves a new webhook subscription
+func (dao WebhookDAO) CreateSubscription(ctx context.Context, sub *types.WebhookSubscription, db db.Store) error {
+	sub.ID = uuid.New().String()
+	sub.Created = time.Now()
+	sub.Active = true
+	sub.SetEventTypes()
+
+	return db.InsertSubscription(ctx, sub)
+}
+
+//GetSubscription - returns a webhook subscription
+func (dao WebhookDAO) GetSubscription(ctx context.Context, id string, db db.Store) (*types.WebhookSubscription, error) {
+	sub, err := db.GetSubscription(ctx, id)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	sub.GetEventTypes()
+	return sub, nil
+}
+
+//GetSubscriptions - returns every webhook subscription
+func (dao WebhookDAO) GetSubscriptions(ctx context.Context, db db.Store) (*[]types.WebhookSubscription, error) {
+	subs, err := db.GetSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range *subs {
+		(*subs)[i].GetEventTypes()
+	}
+	return subs, nil
+}
+
+//DeleteSubscription - removes a subscription and its delivery log
+func (dao WebhookDAO) DeleteSubscription(ctx context.Context, id string, db db.Store) error {
+	return db.DeleteSubscription(ctx, id)
+}
+
+//Enqueue - queues a delivery of the event to every active subscription that wants it.
+//Call it in the same transaction as the change so the event is queued only if the change is saved.
+//accountID is the account the event is about, so its deliveries can be removed when it is erased
+func (dao WebhookDAO) Enqueue(ctx context.Context, event string, accountID string, data interface{}, db db.Store) error {
+	subs, err := db.GetSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(types.WebhookPayload{ID: uuid.New().String(), Event: event, Created: now, Data: data})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range *subs {
+		if !sub.Active || !sub.Wants(event) {
+			continue
+		}
+
+		delivery := types.WebhookDelivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: sub.ID,
+			AccountID:      accountID,
+			Event:          event,
+			Payload:        string(payload),
+			Status:         types.DeliveryPending,
+			NextAttempt:    now,
+			Created:        now,
+			Updated:        now,
+		}
+
+		err = db.InsertDelivery(ctx, &delivery)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//GetDueDeliveries - returns pending deliveries whose next attempt is due
+func (dao WebhookDAO) GetDueDeliveries(ctx context.Context, limit int, db db.Store) (*[]types.WebhookDelivery, error) {
+	return db.GetDueDeliveries(ctx, time.Now(), limit)
+}
+
+//ClaimDelivery - pushes a due delivery's next attempt out to lockUntil so no other worker picks it up.
+//Returns false if another worker claimed it first.
+func (dao WebhookDAO) ClaimDelivery(ctx context.Context, delivery *types.WebhookDelivery, lockUntil time.Time, db db.Store) (bool, error) {
+	return db.ClaimDelivery(ctx, delivery, lockUntil)
+}
+
+//UpdateDelivery - saves the result of a delivery attempt
+func (dao WebhookDAO) UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery, db db.Store) error {
+	delivery.Updated = time.Now()
+	return db.UpdateDelivery(ctx, delivery)
+}
+
+//GetDelivery - returns a webhook delivery
+func (dao WebhookDAO) GetDelivery(ctx context.Context, id string, db db.Store) (*types.WebhookDelivery, error) {
+	return db.GetDelivery(ctx, id)
+}
+
+//GetDeliveries - returns the delivery log, newest first
+func (dao WebhookDAO) GetDeliveries(ctx context.Context, request *types.WebhookDeliveriesRequest, db db.Store) (*[]types.WebhookDelivery, error) {
+	limit := request.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	return db.GetDeliveries(ctx, request.SubscriptionID, request.Status, limit)
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Dialect - the differences between the SQL databases we support.
+//Queries are written with ? placeholders and portable SQL, the dialect adapts them.
+type Dialect struct {
+	Name string
+	//NumberedParams - uses $1, $2 instead of ?
+	NumberedParams bool
+	//UTCTimes - stores times as text, so they must all be in one zone to compare correctly
+	UTCTimes bool
+	//TimeType - column type used for timestamps
+	TimeType string
+	//ExistsError - set for databases that commit each DDL statement as it runs. Returns true if a statement failed because what it adds is already there
+	ExistsError func(error) bool
+}
+
+//Supported dialects
+var (
+	MySQLDialect    = Dialect{Name: "mysql", TimeType: "DATETIME(6)", ExistsError: mysqlExistsError}
+	PostgresDialect = Dialect{Name: "postgres", NumberedParams: true, TimeType: "TIMESTAMPTZ"}
+	SQLiteDialect   = Dialect{Name: "sqlite", UTCTimes: true, TimeType: "DATETIME"}
+)
+
+//Rebind - rewrites ? placeholders for the dialect
+func (d Dialect) Rebind(query string) string {
+	if !d.NumberedParams {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+//Args - converts query arguments for the dialect
+func (d Dialect) Args(args []interface{}) []interface{} {
+	if !d.UTCTimes {
+		return args
+	}
+
+	for i, arg := range args {
+		if t, ok := arg.(time.Time); ok {
+			args[i] = t.UTC()
+		}
+	}
+	return args
+}
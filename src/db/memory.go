@@ -0,0 +1,1659 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"types"
+)
+
+//Memory - Store kept in memory. Nothing survives a restart, use it for tests, local dev and demos
+type Memory struct {
+	mu                   rwLocker
+	accounts             map[string]types.Account
+	devices              map[string]types.Device
+	tokens               map[string]types.RefreshToken
+	recoveries           map[string]types.Recovery
+	events               []types.AuditEvent
+	webhooks             map[string]types.WebhookSubscription
+	deliveries           map[string]types.WebhookDelivery
+	erasures             map[string]types.ErasureRequest
+	invitations          map[string]types.Invitation
+	scimTokens           map[string]types.SCIMToken
+	personalTokens       map[string]types.PersonalToken
+	impersonations       map[string]types.Impersonation
+	outbox               map[string]types.OutboxEmail
+	magicLinks           map[string]types.MagicLink
+	loginCodes           map[string]types.LoginCode
+	phoneCodes           map[string]types.PhoneCode
+	RefreshTokenDuration int
+
+	//inTx - set on the working copy used by a transaction
+	inTx bool
+}
+
+//rwLocker - a sync.RWMutex, or noLock inside a transaction which already holds the stores lock
+type rwLocker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+//noLock - rwLocker that does nothing
+type noLock struct{}
+
+func (noLock) Lock()    {}
+func (noLock) Unlock()  {}
+func (noLock) RLock()   {}
+func (noLock) RUnlock() {}
+
+//Init - creates an empty in memory store
+func (db Memory) Init() *Memory {
+	db.mu = &sync.RWMutex{}
+	db.accounts = map[string]types.Account{}
+	db.devices = map[string]types.Device{}
+	db.tokens = map[string]types.RefreshToken{}
+	db.recoveries = map[string]types.Recovery{}
+	db.events = []types.AuditEvent{}
+	db.webhooks = map[string]types.WebhookSubscription{}
+	db.deliveries = map[string]types.WebhookDelivery{}
+	db.erasures = map[string]types.ErasureRequest{}
+	db.invitations = map[string]types.Invitation{}
+	db.scimTokens = map[string]types.SCIMToken{}
+	db.personalTokens = map[string]types.PersonalToken{}
+	db.impersonations = map[string]types.Impersonation{}
+	db.outbox = map[string]types.OutboxEmail{}
+	db.magicLinks = map[string]types.MagicLink{}
+	db.loginCodes = map[string]types.LoginCode{}
+	db.phoneCodes = map[string]types.PhoneCode{}
+	db.RefreshTokenDuration = refreshTokenDays()
+
+	return &db
+}
+
+//InTx - runs fn against a copy of the store while holding the lock. The copy replaces the store only if fn returns nil.
+//Calls made while already in a transaction join it
+func (db *Memory) InTx(ctx context.Context, fn func(tx Store) error) error {
+	if db.inTx {
+		return fn(db)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx := db.copy()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.accounts = tx.accounts
+	db.devices = tx.devices
+	db.tokens = tx.tokens
+	db.recoveries = tx.recoveries
+	db.events = tx.events
+	db.webhooks = tx.webhooks
+	db.deliveries = tx.deliveries
+	db.erasures = tx.erasures
+	db.invitations = tx.invitations
+	db.scimTokens = tx.scimTokens
+	db.personalTokens = tx.personalTokens
+	db.impersonations = tx.impersonations
+	db.outbox = tx.outbox
+	db.magicLinks = tx.magicLinks
+	db.loginCodes = tx.loginCodes
+	db.phoneCodes = tx.phoneCodes
+	return nil
+}
+
+//copy - returns a working copy of the data for a transaction. Caller must hold the lock
+func (db *Memory) copy() *Memory {
+	tx := Memory{mu: noLock{}, inTx: true, RefreshTokenDuration: db.RefreshTokenDuration}
+
+	tx.accounts = make(map[string]types.Account, len(db.accounts))
+	for k, v := range db.accounts {
+		tx.accounts[k] = v
+	}
+	tx.devices = make(map[string]types.Device, len(db.devices))
+	for k, v := range db.devices {
+		tx.devices[k] = v
+	}
+	tx.tokens = make(map[string]types.RefreshToken, len(db.tokens))
+	for k, v := range db.tokens {
+		tx.tokens[k] = v
+	}
+	tx.recoveries = make(map[string]types.Recovery, len(db.recoveries))
+	for k, v := range db.recoveries {
+		tx.recoveries[k] = v
+	}
+	tx.events = append([]types.AuditEvent{}, db.events...)
+	tx.webhooks = make(map[string]types.WebhookSubscription, len(db.webhooks))
+	for k, v := range db.webhooks {
+		tx.webhooks[k] = v
+	}
+	tx.deliveries = make(map[string]types.WebhookDelivery, len(db.deliveries))
+	for k, v := range db.deliveries {
+		tx.deliveries[k] = v
+	}
+	tx.erasures = make(map[string]types.ErasureRequest, len(db.erasures))
+	for k, v := range db.erasures {
+		tx.erasures[k] = v
+	}
+	tx.invitations = make(map[string]types.Invitation, len(db.invitations))
+	for k, v := range db.invitations {
+		tx.invitations[k] = v
+	}
+	tx.scimTokens = make(map[string]types.SCIMToken, len(db.scimTokens))
+	for k, v := range db.scimTokens {
+		tx.scimTokens[k] = v
+	}
+	tx.personalTokens = make(map[string]types.PersonalToken, len(db.personalTokens))
+	for k, v := range db.personalTokens {
+		tx.personalTokens[k] = v
+	}
+	tx.impersonations = make(map[string]types.Impersonation, len(db.impersonations))
+	for k, v := range db.impersonations {
+		tx.impersonations[k] = v
+	}
+	tx.outbox = make(map[string]types.OutboxEmail, len(db.outbox))
+	for k, v := range db.outbox {
+		tx.outbox[k] = v
+	}
+	tx.magicLinks = make(map[string]types.MagicLink, len(db.magicLinks))
+	for k, v := range db.magicLinks {
+		tx.magicLinks[k] = v
+	}
+	tx.loginCodes = make(map[string]types.LoginCode, len(db.loginCodes))
+	for k, v := range db.loginCodes {
+		tx.loginCodes[k] = v
+	}
+	tx.phoneCodes = make(map[string]types.PhoneCode, len(db.phoneCodes))
+	for k, v := range db.phoneCodes {
+		tx.phoneCodes[k] = v
+	}
+
+	return &tx
+}
+
+//-----------------ACCOUNTS-----------------\\
+
+//EmailTaken - checks if an account other than excludeID already uses the email
+func (db *Memory) EmailTaken(ctx context.Context, email string, excludeID string) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, account := range db.accounts {
+		if account.Email == email && account.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//InsertAccount - saves a new account
+func (db *Memory) InsertAccount(ctx context.Context, account *types.Account) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.accounts[account.ID] = *account
+	return nil
+}
+
+//GetAccountByID - returns an account by ID
+func (db *Memory) GetAccountByID(ctx context.Context, id string) (*types.Account, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	account, ok := db.accounts[id]
+	if !ok {
+		return nil, nil
+	}
+	return &account, nil
+}
+
+//GetAccountByEmail - returns an account by email
+func (db *Memory) GetAccountByEmail(ctx context.Context, email string) (*types.Account, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, account := range db.accounts {
+		if account.Email == email {
+			return &account, nil
+		}
+	}
+	return nil, nil
+}
+
+//SearchAccounts - returns up to limit accounts matching the search in its sort order
+func (db *Memory) SearchAccounts(ctx context.Context, search *types.AccountSearchRequest, after *types.Account, limit int) (*[]types.Account, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	//Positive when a comes after b in the requested order
+	order := func(a *types.Account, b *types.Account) int {
+		c := compareAccounts(a, b, search.Sort)
+		if search.Desc {
+			return -c
+		}
+		return c
+	}
+
+	accounts := []types.Account{}
+	for _, account := range db.accounts {
+		if !matchesAccount(&account, search) {
+			continue
+		}
+		//Continue after the last account of the previous page
+		if after != nil && order(&account, after) <= 0 {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return order(&accounts[i], &accounts[j]) < 0
+	})
+	if len(accounts) > limit {
+		accounts = accounts[:limit]
+	}
+	return &accounts, nil
+}
+
+//CountAccounts - returns how many accounts match the search
+func (db *Memory) CountAccounts(ctx context.Context, search *types.AccountSearchRequest) (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	total := 0
+	for _, account := range db.accounts {
+		if matchesAccount(&account, search) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+//matchesAccount - checks an account against the filters of a search
+func matchesAccount(account *types.Account, search *types.AccountSearchRequest) bool {
+	if len(search.Roles) > 0 && !containsInt(account.Role, search.Roles) {
+		return false
+	}
+	if search.Disabled != nil && account.Disabled != *search.Disabled {
+		return false
+	}
+	if search.TwoFA != nil && account.TwoFA != *search.TwoFA {
+		return false
+	}
+	if search.Deleted != (account.Deleted != nil) {
+		return false
+	}
+	if search.Organization != "" && account.Organization != search.Organization {
+		return false
+	}
+	if !search.CreatedSince.IsZero() && account.Created.Before(search.CreatedSince) {
+		return false
+	}
+	if !search.CreatedUntil.IsZero() && !account.Created.Before(search.CreatedUntil) {
+		return false
+	}
+	if search.Query != "" {
+		query := strings.ToLower(search.Query)
+		if !strings.Contains(strings.ToLower(account.FirstName), query) &&
+			!strings.Contains(strings.ToLower(account.LastName), query) &&
+			!strings.Contains(strings.ToLower(account.Email), query) &&
+			!strings.Contains(account.Phone, query) {
+			return false
+		}
+	}
+	return true
+}
+
+//compareAccounts - orders two accounts by the sort field then ID, ascending
+func compareAccounts(a *types.Account, b *types.Account, field string) int {
+	c := 0
+	switch field {
+	case types.AccountSortLastName:
+		c = strings.Compare(a.LastName, b.LastName)
+	case types.AccountSortEmail:
+		c = strings.Compare(a.Email, b.Email)
+	case types.AccountSortCreated:
+		if a.Created.Before(b.Created) {
+			c = -1
+		} else if a.Created.After(b.Created) {
+			c = 1
+		}
+	default:
+		c = strings.Compare(a.FirstName, b.FirstName)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+//UpdateSettings - updates the settings an account can change itself
+func (db *Memory) UpdateSettings(ctx context.Context, account *types.Account) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.accounts[account.ID]
+	if !ok {
+		return nil
+	}
+	existing.FirstName = account.FirstName
+	existing.LastName = account.LastName
+	existing.Phone = account.Phone
+	existing.Language = account.Language
+	db.accounts[account.ID] = existing
+	return nil
+}
+
+//UpdateAccount - updates the settings an admin can change
+func (db *Memory) UpdateAccount(ctx context.Context, account *types.Account) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.accounts[account.ID]
+	if !ok {
+		return nil
+	}
+	existing.FirstName = account.FirstName
+	existing.LastName = account.LastName
+	existing.Email = account.Email
+	existing.Phone = account.Phone
+	existing.Role = account.Role
+	existing.Organization = account.Organization
+	existing.ExternalID = account.ExternalID
+	existing.Language = account.Language
+	db.accounts[account.ID] = existing
+	return nil
+}
+
+//UpdatePassword - sets an accounts password hash
+func (db *Memory) UpdatePassword(ctx context.Context, id string, password string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.accounts[id]
+	if !ok {
+		return nil
+	}
+	existing.Password = password
+	db.accounts[id] = existing
+	return nil
+}
+
+//AnonymizeAccount - overwrites the personal details of an account and removes everything that belongs to it
+func (db *Memory) AnonymizeAccount(ctx context.Context, account *types.Account) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.accounts[account.ID]
+	if !ok {
+		return nil
+	}
+	existing.Password = account.Password
+	existing.FirstName = account.FirstName
+	existing.LastName = account.LastName
+	existing.Phone = account.Phone
+	existing.Email = account.Email
+	existing.Organization = account.Organization
+	existing.ExternalID = account.ExternalID
+	existing.TwoFA = account.TwoFA
+	existing.Disabled = account.Disabled
+	existing.DisabledReason = account.DisabledReason
+	existing.VerifiedPhone = account.VerifiedPhone
+	db.accounts[account.ID] = existing
+
+	db.deleteAccountData(account.ID)
+	return nil
+}
+
+//deleteAccountData - removes the devices, refresh tokens, recoveries, invitations, personal access tokens, impersonations, magic links, login codes, phone codes, emails and webhook deliveries of an account. Caller must hold the lock
+func (db *Memory) deleteAccountData(id string) {
+	for key, token := range db.tokens {
+		if token.AccountID == id {
+			delete(db.tokens, key)
+		}
+	}
+	for key, device := range db.devices {
+		if device.AccountID == id {
+			delete(db.devices, key)
+		}
+	}
+	for key, recovery := range db.recoveries {
+		if recovery.AccountID == id {
+			delete(db.recoveries, key)
+		}
+	}
+	for key, invitation := range db.invitations {
+		if invitation.AccountID == id {
+			delete(db.invitations, key)
+		}
+	}
+	for key, token := range db.personalTokens {
+		if token.AccountID == id {
+			delete(db.personalTokens, key)
+		}
+	}
+	for key, impersonation := range db.impersonations {
+		if impersonation.TargetID == id || impersonation.ActorID == id {
+			delete(db.impersonations, key)
+		}
+	}
+	for key, email := range db.outbox {
+		if email.AccountID == id {
+			delete(db.outbox, key)
+		}
+	}
+	for key, link := range db.magicLinks {
+		if link.AccountID == id {
+			delete(db.magicLinks, key)
+		}
+	}
+	for key, code := range db.loginCodes {
+		if code.AccountID == id {
+			delete(db.loginCodes, key)
+		}
+	}
+	for key, code := range db.phoneCodes {
+		if code.AccountID == id {
+			delete(db.phoneCodes, key)
+		}
+	}
+	for key, delivery := range db.deliveries {
+		if delivery.AccountID == id || (delivery.AccountID == "" && strings.Contains(delivery.Payload, id)) {
+			delete(db.deliveries, key)
+		}
+	}
+}
+
+//SetDisabled - disables or enables an account
+func (db *Memory) SetDisabled(ctx context.Context, id string, disabled bool, reason string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.accounts[id]
+	if !ok {
+		return nil
+	}
+	existing.Disabled = disabled
+	existing.DisabledReason = reason
+	db.accounts[id] = existing
+	return nil
+}
+
+//SetVerifiedPhone - records the number an account proved it has
+func (db *Memory) SetVerifiedPhone(ctx context.Context, id string, phone string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.accounts[id]
+	if !ok {
+		return nil
+	}
+	existing.VerifiedPhone = phone
+	db.accounts[id] = existing
+	return nil
+}
+
+//SetDeleted - soft deletes or restores an account
+func (db *Memory) SetDeleted(ctx context.Context, id string, deleted *time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.accounts[id]
+	if !ok {
+		return nil
+	}
+	existing.Deleted = deleted
+	db.accounts[id] = existing
+	return nil
+}
+
+//GetDeletedBefore - returns up to limit accounts soft deleted before the time given, oldest first
+func (db *Memory) GetDeletedBefore(ctx context.Context, before time.Time, limit int) (*[]types.Account, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	accounts := []types.Account{}
+	for _, account := range db.accounts {
+		if account.Deleted != nil && account.Deleted.Before(before) {
+			accounts = append(accounts, account)
+		}
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Deleted.Before(*accounts[j].Deleted)
+	})
+	if len(accounts) > limit {
+		accounts = accounts[:limit]
+	}
+	return &accounts, nil
+}
+
+//DeleteAccount - deletes an account and everything that belongs to it
+func (db *Memory) DeleteAccount(ctx context.Context, id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.deleteAccountData(id)
+	delete(db.accounts, id)
+	return nil
+}
+
+//-----------------DEVICES-----------------\\
+
+//GetDevice - returns a device
+func (db *Memory) GetDevice(ctx context.Context, id string) (*types.Device, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	device, ok := db.devices[id]
+	if !ok {
+		return nil, nil
+	}
+	return &device, nil
+}
+
+//InsertDevice - saves a new device
+func (db *Memory) InsertDevice(ctx context.Context, device *types.Device) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.devices[device.ID] = *device
+	return nil
+}
+
+//ActivateDevice - marks a device as active
+func (db *Memory) ActivateDevice(ctx context.Context, id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	device, ok := db.devices[id]
+	if !ok {
+		return nil
+	}
+	device.Active = true
+	db.devices[id] = device
+	return nil
+}
+
+//TouchDevice - saves the client a device was last used from
+func (db *Memory) TouchDevice(ctx context.Context, device *types.Device) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	saved, ok := db.devices[device.ID]
+	if !ok {
+		return nil
+	}
+	saved.FirstIP, saved.LastIP, saved.UserAgent = device.FirstIP, device.LastIP, device.UserAgent
+	saved.Browser, saved.OS, saved.Country, saved.Location = device.Browser, device.OS, device.Country, device.Location
+	saved.LastSeen = device.LastSeen
+	db.devices[device.ID] = saved
+	return nil
+}
+
+//GetDevicesByAccount - returns every device of an account, oldest first
+func (db *Memory) GetDevicesByAccount(ctx context.Context, accountID string) (*[]types.Device, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	devices := []types.Device{}
+	for _, device := range db.devices {
+		if device.AccountID == accountID {
+			devices = append(devices, device)
+		}
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Created.Before(devices[j].Created)
+	})
+	return &devices, nil
+}
+
+//-----------------TOKENS-----------------\\
+
+//InsertRefreshToken - saves a refresh token
+func (db *Memory) InsertRefreshToken(ctx context.Context, token *types.RefreshToken) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.tokens[token.ID] = *token
+	return nil
+}
+
+//GetRefreshToken - returns a refresh token
+func (db *Memory) GetRefreshToken(ctx context.Context, id string) (*types.RefreshToken, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	token, ok := db.tokens[id]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+//DeleteRefreshToken - deletes a refresh token
+func (db *Memory) DeleteRefreshToken(ctx context.Context, id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.tokens, id)
+	return nil
+}
+
+//TouchRefreshToken - saves the client a session was last used from
+func (db *Memory) TouchRefreshToken(ctx context.Context, token *types.RefreshToken) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	saved, ok := db.tokens[token.ID]
+	if !ok {
+		return nil
+	}
+	saved.FirstIP, saved.LastIP, saved.UserAgent = token.FirstIP, token.LastIP, token.UserAgent
+	saved.Browser, saved.OS, saved.Country, saved.Location = token.Browser, token.OS, token.Country, token.Location
+	saved.LastSeen = token.LastSeen
+	db.tokens[token.ID] = saved
+	return nil
+}
+
+//GetRefreshTokensByAccount - returns every refresh token of an account, oldest first
+func (db *Memory) GetRefreshTokensByAccount(ctx context.Context, accountID string) (*[]types.RefreshToken, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	tokens := []types.RefreshToken{}
+	for _, token := range db.tokens {
+		if token.AccountID == accountID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Created.Before(tokens[j].Created)
+	})
+	return &tokens, nil
+}
+
+//DeleteRefreshTokensByAccount - deletes every refresh token of an account
+func (db *Memory) DeleteRefreshTokensByAccount(ctx context.Context, accountID string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, token := range db.tokens {
+		if token.AccountID == accountID {
+			delete(db.tokens, id)
+		}
+	}
+	return nil
+}
+
+//-----------------RECOVERIES-----------------\\
+
+//InsertRecovery - saves a new recovery
+func (db *Memory) InsertRecovery(ctx context.Context, recovery *types.Recovery) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.recoveries[recovery.ID] = *recovery
+	return nil
+}
+
+//GetRecovery - returns a recovery
+func (db *Memory) GetRecovery(ctx context.Context, id string) (*types.Recovery, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	recovery, ok := db.recoveries[id]
+	if !ok {
+		return nil, nil
+	}
+	return &recovery, nil
+}
+
+//DeleteRecovery - deletes a recovery. Returns false if it was already gone
+func (db *Memory) DeleteRecovery(ctx context.Context, id string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.recoveries[id]; !ok {
+		return false, nil
+	}
+	delete(db.recoveries, id)
+	return true, nil
+}
+
+//-----------------AUDIT-----------------\\
+
+//InsertEvent - saves an audit event
+func (db *Memory) InsertEvent(ctx context.Context, event *types.AuditEvent) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.events = append(db.events, *event)
+	return nil
+}
+
+//SearchEvents - returns up to limit events matching the search, newest first
+func (db *Memory) SearchEvents(ctx context.Context, search *types.AuditSearchRequest, after *types.AuditEvent, limit int) (*[]types.AuditEvent, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	events := []types.AuditEvent{}
+	for _, event := range db.events {
+		if search.Type != "" && event.Type != search.Type {
+			continue
+		}
+		if search.ActorID != "" && event.ActorID != search.ActorID {
+			continue
+		}
+		if search.ImpersonatorID != "" && event.ImpersonatorID != search.ImpersonatorID {
+			continue
+		}
+		if search.TargetID != "" && event.TargetID != search.TargetID {
+			continue
+		}
+		if search.Outcome != "" && event.Outcome != search.Outcome {
+			continue
+		}
+		if search.IP != "" && event.IP != search.IP {
+			continue
+		}
+		if !search.Since.IsZero() && event.Created.Before(search.Since) {
+			continue
+		}
+		if !search.Until.IsZero() && !event.Created.Before(search.Until) {
+			continue
+		}
+		if after != nil && !olderEvent(&event, after) {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return olderEvent(&events[j], &events[i])
+	})
+
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return &events, nil
+}
+
+//olderEvent - returns true if a sorts after b in newest first order
+func olderEvent(a *types.AuditEvent, b *types.AuditEvent) bool {
+	if a.Created.Equal(b.Created) {
+		return a.ID < b.ID
+	}
+	return a.Created.Before(b.Created)
+}
+
+//ScrubEvents - blanks the email, client and detail of every event about the account or email
+func (db *Memory) ScrubEvents(ctx context.Context, accountID string, email string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, event := range db.events {
+		if event.ActorID == accountID || event.TargetID == accountID || event.ActorEmail == email {
+			event.ActorEmail = ""
+			event.IP = ""
+			event.UserAgent = ""
+			event.Detail = ""
+			db.events[i] = event
+		}
+	}
+	return nil
+}
+
+//-----------------WEBHOOKS-----------------\\
+
+//InsertSubscription - saves a new webhook subscription
+func (db *Memory) InsertSubscription(ctx context.Context, sub *types.WebhookSubscription) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.webhooks[sub.ID] = *sub
+	return nil
+}
+
+//GetSubscription - returns a webhook subscription
+func (db *Memory) GetSubscription(ctx context.Context, id string) (*types.WebhookSubscription, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	sub, ok := db.webhooks[id]
+	if !ok {
+		return nil, nil
+	}
+	return &sub, nil
+}
+
+//GetSubscriptions - returns every webhook subscription
+func (db *Memory) GetSubscriptions(ctx context.Context) (*[]types.WebhookSubscription, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	subs := []types.WebhookSubscription{}
+	for _, sub := range db.webhooks {
+		subs = append(subs, sub)
+	}
+
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].Created.Before(subs[j].Created)
+	})
+	return &subs, nil
+}
+
+//DeleteSubscription - removes a subscription and its delivery log
+func (db *Memory) DeleteSubscription(ctx context.Context, id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for deliveryID, delivery := range db.deliveries {
+		if delivery.SubscriptionID == id {
+			delete(db.deliveries, deliveryID)
+		}
+	}
+	delete(db.webhooks, id)
+	return nil
+}
+
+//InsertDelivery - queues a webhook delivery
+func (db *Memory) InsertDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+//GetDelivery - returns a webhook delivery
+func (db *Memory) GetDelivery(ctx context.Context, id string) (*types.WebhookDelivery, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	delivery, ok := db.deliveries[id]
+	if !ok {
+		return nil, nil
+	}
+	return &delivery, nil
+}
+
+//GetDeliveries - returns the delivery log newest first, optionally filtered by subscription and status
+func (db *Memory) GetDeliveries(ctx context.Context, subscriptionID string, status string, limit int) (*[]types.WebhookDelivery, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	deliveries := []types.WebhookDelivery{}
+	for _, delivery := range db.deliveries {
+		if subscriptionID != "" && delivery.SubscriptionID != subscriptionID {
+			continue
+		}
+		if status != "" && delivery.Status != status {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].Created.After(deliveries[j].Created)
+	})
+
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return &deliveries, nil
+}
+
+//GetDueDeliveries - returns pending deliveries whose next attempt is due
+func (db *Memory) GetDueDeliveries(ctx context.Context, now time.Time, limit int) (*[]types.WebhookDelivery, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	deliveries := []types.WebhookDelivery{}
+	for _, delivery := range db.deliveries {
+		if delivery.Status == types.DeliveryPending && !delivery.NextAttempt.After(now) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttempt.Before(deliveries[j].NextAttempt)
+	})
+
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return &deliveries, nil
+}
+
+//ClaimDelivery - pushes a due delivery's next attempt out to lockUntil so no other worker picks it up.
+//Returns false if another worker claimed it first.
+func (db *Memory) ClaimDelivery(ctx context.Context, delivery *types.WebhookDelivery, lockUntil time.Time) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.deliveries[delivery.ID]
+	if !ok || existing.Status != types.DeliveryPending || !existing.NextAttempt.Equal(delivery.NextAttempt) {
+		return false, nil
+	}
+	existing.NextAttempt = lockUntil
+	db.deliveries[delivery.ID] = existing
+	return true, nil
+}
+
+//UpdateDelivery - saves the result of a delivery attempt
+func (db *Memory) UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.deliveries[delivery.ID]
+	if !ok {
+		return nil
+	}
+	existing.Status = delivery.Status
+	existing.Attempts = delivery.Attempts
+	existing.NextAttempt = delivery.NextAttempt
+	existing.ResponseCode = delivery.ResponseCode
+	existing.LastError = delivery.LastError
+	existing.Updated = delivery.Updated
+	db.deliveries[delivery.ID] = existing
+	return nil
+}
+
+//-----------------OUTBOX-----------------\\
+
+//InsertEmail - queues an email
+func (db *Memory) InsertEmail(ctx context.Context, email *types.OutboxEmail) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.outbox[email.ID] = *email
+	return nil
+}
+
+//GetEmail - returns a queued email
+func (db *Memory) GetEmail(ctx context.Context, id string) (*types.OutboxEmail, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	email, ok := db.outbox[id]
+	if !ok {
+		return nil, nil
+	}
+	return &email, nil
+}
+
+//GetEmails - returns the outbox newest first, optionally filtered by status
+func (db *Memory) GetEmails(ctx context.Context, status string, limit int) (*[]types.OutboxEmail, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	emails := []types.OutboxEmail{}
+	for _, email := range db.outbox {
+		if status != "" && email.Status != status {
+			continue
+		}
+		emails = append(emails, email)
+	}
+
+	sort.Slice(emails, func(i, j int) bool {
+		return emails[i].Created.After(emails[j].Created)
+	})
+
+	if len(emails) > limit {
+		emails = emails[:limit]
+	}
+	return &emails, nil
+}
+
+//GetDueEmails - returns pending emails whose next attempt is due
+func (db *Memory) GetDueEmails(ctx context.Context, now time.Time, limit int) (*[]types.OutboxEmail, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	emails := []types.OutboxEmail{}
+	for _, email := range db.outbox {
+		if email.Status == types.EmailPending && !email.NextAttempt.After(now) {
+			emails = append(emails, email)
+		}
+	}
+
+	sort.Slice(emails, func(i, j int) bool {
+		return emails[i].NextAttempt.Before(emails[j].NextAttempt)
+	})
+
+	if len(emails) > limit {
+		emails = emails[:limit]
+	}
+	return &emails, nil
+}
+
+//ClaimEmail - pushes a due email's next attempt out to lockUntil so no other worker picks it up.
+//Returns false if another worker claimed it first.
+func (db *Memory) ClaimEmail(ctx context.Context, email *types.OutboxEmail, lockUntil time.Time) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.outbox[email.ID]
+	if !ok || existing.Status != types.EmailPending || !existing.NextAttempt.Equal(email.NextAttempt) {
+		return false, nil
+	}
+	existing.NextAttempt = lockUntil
+	db.outbox[email.ID] = existing
+	return true, nil
+}
+
+//UpdateEmail - saves the result of a send attempt
+func (db *Memory) UpdateEmail(ctx context.Context, email *types.OutboxEmail) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.outbox[email.ID]
+	if !ok {
+		return nil
+	}
+	existing.Body = email.Body
+	existing.Text = email.Text
+	existing.Status = email.Status
+	existing.Attempts = email.Attempts
+	existing.NextAttempt = email.NextAttempt
+	existing.LastError = email.LastError
+	existing.Updated = email.Updated
+	db.outbox[email.ID] = existing
+	return nil
+}
+
+//-----------------PRIVACY-----------------\\
+
+//InsertErasureRequest - saves a new erasure request
+func (db *Memory) InsertErasureRequest(ctx context.Context, request *types.ErasureRequest) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.erasures[request.ID] = *request
+	return nil
+}
+
+//GetErasureRequest - returns an erasure request
+func (db *Memory) GetErasureRequest(ctx context.Context, id string) (*types.ErasureRequest, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	request, ok := db.erasures[id]
+	if !ok {
+		return nil, nil
+	}
+	return &request, nil
+}
+
+//GetErasureRequests - returns up to limit erasure requests, newest first
+func (db *Memory) GetErasureRequests(ctx context.Context, accountID string, status string, limit int) (*[]types.ErasureRequest, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	requests := []types.ErasureRequest{}
+	for _, request := range db.erasures {
+		if accountID != "" && request.AccountID != accountID {
+			continue
+		}
+		if status != "" && request.Status != status {
+			continue
+		}
+		requests = append(requests, request)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Created.After(requests[j].Created)
+	})
+	if len(requests) > limit {
+		requests = requests[:limit]
+	}
+	return &requests, nil
+}
+
+//UpdateErasureRequest - saves the status of an erasure request
+func (db *Memory) UpdateErasureRequest(ctx context.Context, request *types.ErasureRequest) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.erasures[request.ID]
+	if !ok {
+		return nil
+	}
+	existing.Status = request.Status
+	existing.Note = request.Note
+	existing.HandledBy = request.HandledBy
+	existing.Updated = request.Updated
+	db.erasures[request.ID] = existing
+	return nil
+}
+
+//-----------------INVITATIONS-----------------\\
+
+//InsertInvitation - saves a new invitation
+func (db *Memory) InsertInvitation(ctx context.Context, invitation *types.Invitation) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.invitations[invitation.ID] = *invitation
+	return nil
+}
+
+//GetInvitation - returns an invitation
+func (db *Memory) GetInvitation(ctx context.Context, id string) (*types.Invitation, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	invitation, ok := db.invitations[id]
+	if !ok {
+		return nil, nil
+	}
+	return &invitation, nil
+}
+
+//GetInvitations - returns up to limit invitations, newest first
+func (db *Memory) GetInvitations(ctx context.Context, pending bool, limit int) (*[]types.Invitation, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	invitations := []types.Invitation{}
+	for _, invitation := range db.invitations {
+		if pending && invitation.Accepted != nil {
+			continue
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].Created.After(invitations[j].Created)
+	})
+	if len(invitations) > limit {
+		invitations = invitations[:limit]
+	}
+	return &invitations, nil
+}
+
+//AcceptInvitation - marks an invitation as used by the account. Returns false if it was already used
+func (db *Memory) AcceptInvitation(ctx context.Context, id string, accountID string, accepted time.Time) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.invitations[id]
+	if !ok || existing.Accepted != nil {
+		return false, nil
+	}
+	existing.Accepted = &accepted
+	existing.AccountID = accountID
+	db.invitations[id] = existing
+	return true, nil
+}
+
+//DeleteInvitation - removes an invitation
+func (db *Memory) DeleteInvitation(ctx context.Context, id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.invitations, id)
+	return nil
+}
+
+//-----------------SCIM-----------------\\
+
+//InsertSCIMToken - saves a new SCIM token
+func (db *Memory) InsertSCIMToken(ctx context.Context, token *types.SCIMToken) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	saved := *token
+	saved.Token = ""
+	db.scimTokens[token.ID] = saved
+	return nil
+}
+
+//GetSCIMToken - returns a SCIM token
+func (db *Memory) GetSCIMToken(ctx context.Context, id string) (*types.SCIMToken, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	token, ok := db.scimTokens[id]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+//GetSCIMTokens - returns every SCIM token, newest first
+func (db *Memory) GetSCIMTokens(ctx context.Context) (*[]types.SCIMToken, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	tokens := []types.SCIMToken{}
+	for _, token := range db.scimTokens {
+		tokens = append(tokens, token)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Created.After(tokens[j].Created)
+	})
+	return &tokens, nil
+}
+
+//TouchSCIMToken - records when a SCIM token was last used
+func (db *Memory) TouchSCIMToken(ctx context.Context, id string, used time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.scimTokens[id]
+	if !ok {
+		return nil
+	}
+	existing.LastUsed = &used
+	db.scimTokens[id] = existing
+	return nil
+}
+
+//DeleteSCIMToken - removes a SCIM token
+func (db *Memory) DeleteSCIMToken(ctx context.Context, id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.scimTokens, id)
+	return nil
+}
+
+//-----------------PERSONAL ACCESS TOKENS-----------------\\
+
+//InsertPersonalToken - saves a new personal access token
+func (db *Memory) InsertPersonalToken(ctx context.Context, token *types.PersonalToken) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	saved := *token
+	saved.Token = ""
+	saved.Scopes = nil
+	db.personalTokens[token.ID] = saved
+	return nil
+}
+
+//GetPersonalToken - returns a personal access token
+func (db *Memory) GetPersonalToken(ctx context.Context, id string) (*types.PersonalToken, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	token, ok := db.personalTokens[id]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+//GetPersonalTokens - returns the personal access tokens of an account, newest first
+func (db *Memory) GetPersonalTokens(ctx context.Context, accountID string) (*[]types.PersonalToken, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	tokens := []types.PersonalToken{}
+	for _, token := range db.personalTokens {
+		if token.AccountID == accountID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Created.After(tokens[j].Created)
+	})
+	return &tokens, nil
+}
+
+//TouchPersonalToken - records when a personal access token was last used
+func (db *Memory) TouchPersonalToken(ctx context.Context, id string, used time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.personalTokens[id]
+	if !ok {
+		return nil
+	}
+	existing.LastUsed = &used
+	db.personalTokens[id] = existing
+	return nil
+}
+
+//DeletePersonalToken - removes a personal access token
+func (db *Memory) DeletePersonalToken(ctx context.Context, id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.personalTokens, id)
+	return nil
+}
+
+//-----------------IMPERSONATIONS-----------------\\
+
+//InsertImpersonation - saves a new impersonation
+func (db *Memory) InsertImpersonation(ctx context.Context, impersonation *types.Impersonation) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.impersonations[impersonation.ID] = *impersonation
+	return nil
+}
+
+//GetImpersonation - returns an impersonation
+func (db *Memory) GetImpersonation(ctx context.Context, id string) (*types.Impersonation, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	impersonation, ok := db.impersonations[id]
+	if !ok {
+		return nil, nil
+	}
+	return &impersonation, nil
+}
+
+//StopImpersonation - records when an impersonation was stopped
+func (db *Memory) StopImpersonation(ctx context.Context, id string, stopped time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.impersonations[id]
+	if !ok {
+		return nil
+	}
+	existing.Stopped = &stopped
+	db.impersonations[id] = existing
+	return nil
+}
+
+//StopImpersonationsByAccount - stops every running impersonation by or of an account
+func (db *Memory) StopImpersonationsByAccount(ctx context.Context, accountID string, stopped time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, impersonation := range db.impersonations {
+		if impersonation.ActorID != accountID && impersonation.TargetID != accountID {
+			continue
+		}
+		if impersonation.Stopped != nil || !impersonation.Expires.After(stopped) {
+			continue
+		}
+		impersonation.Stopped = &stopped
+		db.impersonations[id] = impersonation
+	}
+	return nil
+}
+
+//-----------------MAGIC LINKS-----------------\\
+
+//InsertMagicLink - saves a new magic link
+func (db *Memory) InsertMagicLink(ctx context.Context, link *types.MagicLink) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.magicLinks[link.ID] = *link
+	return nil
+}
+
+//GetMagicLink - returns a magic link
+func (db *Memory) GetMagicLink(ctx context.Context, id string) (*types.MagicLink, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	link, ok := db.magicLinks[id]
+	if !ok {
+		return nil, nil
+	}
+	return &link, nil
+}
+
+//UseMagicLink - deletes a magic link as it is used. Returns false if it was already used
+func (db *Memory) UseMagicLink(ctx context.Context, id string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.magicLinks[id]; !ok {
+		return false, nil
+	}
+	delete(db.magicLinks, id)
+	return true, nil
+}
+
+//DeleteMagicLinks - removes every magic link of an account
+func (db *Memory) DeleteMagicLinks(ctx context.Context, accountID string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, link := range db.magicLinks {
+		if link.AccountID == accountID {
+			delete(db.magicLinks, id)
+		}
+	}
+	return nil
+}
+
+//-----------------LOGIN CODES-----------------\\
+
+//InsertLoginCode - saves a new login code
+func (db *Memory) InsertLoginCode(ctx context.Context, code *types.LoginCode) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.loginCodes[code.ID] = *code
+	return nil
+}
+
+//GetLoginCode - returns the login code of an account
+func (db *Memory) GetLoginCode(ctx context.Context, accountID string) (*types.LoginCode, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var found *types.LoginCode
+	for _, code := range db.loginCodes {
+		if code.AccountID == accountID && (found == nil || code.Created.After(found.Created)) {
+			code := code
+			found = &code
+		}
+	}
+	return found, nil
+}
+
+//AddLoginCodeAttempt - counts a try of a login code. Returns false if it is gone or has no attempts left
+func (db *Memory) AddLoginCodeAttempt(ctx context.Context, id string, max int) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	code, ok := db.loginCodes[id]
+	if !ok || code.Attempts >= max {
+		return false, nil
+	}
+	code.Attempts++
+	db.loginCodes[id] = code
+	return true, nil
+}
+
+//UseLoginCode - deletes a login code as it is used. Returns false if it was already used
+func (db *Memory) UseLoginCode(ctx context.Context, id string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.loginCodes[id]; !ok {
+		return false, nil
+	}
+	delete(db.loginCodes, id)
+	return true, nil
+}
+
+//DeleteLoginCodes - removes every login code of an account
+func (db *Memory) DeleteLoginCodes(ctx context.Context, accountID string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, code := range db.loginCodes {
+		if code.AccountID == accountID {
+			delete(db.loginCodes, id)
+		}
+	}
+	return nil
+}
+
+//-----------------PHONE CODES-----------------\\
+
+//InsertPhoneCode - saves a new phone code
+func (db *Memory) InsertPhoneCode(ctx context.Context, code *types.PhoneCode) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.phoneCodes[code.ID] = *code
+	return nil
+}
+
+//GetPhoneCode - returns the newest unused code of an account for a purpose
+func (db *Memory) GetPhoneCode(ctx context.Context, accountID string, purpose string) (*types.PhoneCode, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var found *types.PhoneCode
+	for _, code := range db.phoneCodes {
+		if code.AccountID == accountID && code.Purpose == purpose && !code.Used && (found == nil || code.Created.After(found.Created)) {
+			code := code
+			found = &code
+		}
+	}
+	return found, nil
+}
+
+//CountPhoneCodes - returns how many codes have been sent to a number since the time given
+func (db *Memory) CountPhoneCodes(ctx context.Context, phone string, since time.Time) (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	count := 0
+	for _, code := range db.phoneCodes {
+		if code.Phone == phone && !code.Created.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+//AddPhoneCodeAttempt - counts a try of a phone code. Returns false if it is used or has no attempts left
+func (db *Memory) AddPhoneCodeAttempt(ctx context.Context, id string, max int) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	code, ok := db.phoneCodes[id]
+	if !ok || code.Used || code.Attempts >= max {
+		return false, nil
+	}
+	code.Attempts++
+	db.phoneCodes[id] = code
+	return true, nil
+}
+
+//UsePhoneCode - marks a phone code used. Returns false if it already was
+func (db *Memory) UsePhoneCode(ctx context.Context, id string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	code, ok := db.phoneCodes[id]
+	if !ok || code.Used {
+		return false, nil
+	}
+	code.Used = true
+	db.phoneCodes[id] = code
+	return true, nil
+}
+
+//-----------------EXPIRY-----------------\\
+
+//DeleteExpired - removes all expired recoveries, devices, refresh tokens, unconfirmed erasure requests, unused invitations, personal access tokens, impersonations, magic links, login codes, phone codes, old outbox emails and old webhook deliveries. Same windows as the SQL stores
+func (db *Memory) DeleteExpired() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+
+	for id, recovery := range db.recoveries {
+		if recovery.Created.Before(now.Add(-1 * time.Hour)) {
+			delete(db.recoveries, id)
+		}
+	}
+	for id, device := range db.devices {
+		if device.Created.Before(now.AddDate(0, 0, -60)) {
+			delete(db.devices, id)
+		}
+	}
+	for id, token := range db.tokens {
+		if token.Created.Before(now.AddDate(0, 0, -db.RefreshTokenDuration)) {
+			delete(db.tokens, id)
+		}
+	}
+	for id, request := range db.erasures {
+		if request.Status == types.ErasureUnconfirmed && request.Created.Before(now.Add(-24*time.Hour)) {
+			delete(db.erasures, id)
+		}
+	}
+	for id, invitation := range db.invitations {
+		if invitation.Accepted == nil && invitation.Expires.Before(now) {
+			delete(db.invitations, id)
+		}
+	}
+	for id, token := range db.personalTokens {
+		if token.Expires.Before(now) {
+			delete(db.personalTokens, id)
+		}
+	}
+	for id, impersonation := range db.impersonations {
+		if impersonation.Expires.Before(now) {
+			delete(db.impersonations, id)
+		}
+	}
+	for id, link := range db.magicLinks {
+		if link.Expires.Before(now) {
+			delete(db.magicLinks, id)
+		}
+	}
+	for id, code := range db.loginCodes {
+		if code.Expires.Before(now) {
+			delete(db.loginCodes, id)
+		}
+	}
+	for id, code := range db.phoneCodes {
+		if code.Created.Before(now.Add(-24 * time.Hour)) {
+			delete(db.phoneCodes, id)
+		}
+	}
+	for id, email := range db.outbox {
+		if (email.Status == types.EmailSent && email.Updated.Before(now.AddDate(0, 0, -7))) ||
+			(email.Status == types.EmailDead && email.Updated.Before(now.AddDate(0, 0, -30))) {
+			delete(db.outbox, id)
+		}
+	}
+	for id, delivery := range db.deliveries {
+		if (delivery.Status == types.DeliveryDelivered && delivery.Updated.Before(now.AddDate(0, 0, -7))) ||
+			(delivery.Status == types.DeliveryDead && delivery.Updated.Before(now.AddDate(0, 0, -30))) {
+			delete(db.deliveries, id)
+		}
+	}
+}
+
+//containsInt - check if int is in array
+func containsInt(a int, list []int) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"types"
+)
+
+func TestMemoryInTx(t *testing.T) {
+	failed := errors.New("failed")
+
+	tests := []struct {
+		name string
+		//cancel - cancels the context before the transaction ends
+		cancel bool
+		fn     func(ctx context.Context, tx Store) error
+		kept   bool
+	}{
+		{name: "commit", kept: true, fn: func(ctx context.Context, tx Store) error {
+			return nil
+		}},
+		{name: "error", fn: func(ctx context.Context, tx Store) error {
+			return failed
+		}},
+		{name: "cancelled", cancel: true, fn: func(ctx context.Context, tx Store) error {
+			return nil
+		}},
+		{name: "nested commit", kept: true, fn: func(ctx context.Context, tx Store) error {
+			return tx.InTx(ctx, func(tx Store) error { return nil })
+		}},
+		{name: "nested error", fn: func(ctx context.Context, tx Store) error {
+			return tx.InTx(ctx, func(tx Store) error { return failed })
+		}},
+	}
+
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		store := Memory{}.Init()
+
+		err := store.InTx(ctx, func(tx Store) error {
+			if err := tx.InsertAccount(ctx, &types.Account{ID: "account", Email: "account@example.com"}); err != nil {
+				return err
+			}
+			if err := tx.InsertRefreshToken(ctx, &types.RefreshToken{ID: "token", AccountID: "account", Created: time.Now()}); err != nil {
+				return err
+			}
+			if err := tx.InsertEvent(ctx, &types.AuditEvent{ID: "event", ActorID: "account", Created: time.Now()}); err != nil {
+				return err
+			}
+
+			//A transaction sees its own changes
+			account, err := tx.GetAccountByID(ctx, "account")
+			if err != nil {
+				return err
+			}
+			if account == nil {
+				t.Errorf("%s: account not found inside the transaction", test.name)
+			}
+
+			if test.cancel {
+				cancel()
+			}
+			return test.fn(ctx, tx)
+		})
+		cancel()
+		if (err == nil) != test.kept {
+			t.Errorf("%s: got error %v, expected kept %v", test.name, err, test.kept)
+		}
+
+		account, _ := store.GetAccountByID(context.Background(), "account")
+		token, _ := store.GetRefreshToken(context.Background(), "token")
+		if (account != nil) != test.kept || (token != nil) != test.kept || (len(store.events) == 1) != test.kept {
+			t.Errorf("%s: got account %v, token %v, %d events, expected kept %v", test.name, account != nil, token != nil, len(store.events), test.kept)
+		}
+	}
+}
+
+func TestMemoryDeleteExpired(t *testing.T) {
+	now := time.Now()
+	refreshDays := 60
+	accepted := now
+
+	tests := []struct {
+		name string
+		//cutoff - rows from before it are removed, the same window as SQLStore.DeleteExpired
+		cutoff time.Time
+		//kept - rows are never removed, however old
+		kept   bool
+		add    func(db *Memory, id string, at time.Time)
+		exists func(db *Memory, id string) bool
+	}{
+		{name: "recoveries", cutoff: now.Add(-1 * time.Hour),
+			add: func(db *Memory, id string, at time.Time) {
+				db.recoveries[id] = types.Recovery{ID: id, Created: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.recoveries[id]; return ok }},
+		{name: "devices", cutoff: now.AddDate(0, 0, -60),
+			add: func(db *Memory, id string, at time.Time) {
+				db.devices[id] = types.Device{ID: id, Created: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.devices[id]; return ok }},
+		{name: "refresh tokens", cutoff: now.AddDate(0, 0, -refreshDays),
+			add: func(db *Memory, id string, at time.Time) {
+				db.tokens[id] = types.RefreshToken{ID: id, Created: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.tokens[id]; return ok }},
+		{name: "unconfirmed erasure requests", cutoff: now.Add(-24 * time.Hour),
+			add: func(db *Memory, id string, at time.Time) {
+				db.erasures[id] = types.ErasureRequest{ID: id, Status: types.ErasureUnconfirmed, Created: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.erasures[id]; return ok }},
+		{name: "pending erasure requests", cutoff: now.Add(-24 * time.Hour), kept: true,
+			add: func(db *Memory, id string, at time.Time) {
+				db.erasures[id] = types.ErasureRequest{ID: id, Status: types.ErasurePending, Created: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.erasures[id]; return ok }},
+		{name: "unused invitations", cutoff: now,
+			add: func(db *Memory, id string, at time.Time) {
+				db.invitations[id] = types.Invitation{ID: id, Expires: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.invitations[id]; return ok }},
+		{name: "accepted invitations", cutoff: now, kept: true,
+			add: func(db *Memory, id string, at time.Time) {
+				db.invitations[id] = types.Invitation{ID: id, Expires: at, Accepted: &accepted}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.invitations[id]; return ok }},
+		{name: "personal access tokens", cutoff: now,
+			add: func(db *Memory, id string, at time.Time) {
+				db.personalTokens[id] = types.PersonalToken{ID: id, Expires: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.personalTokens[id]; return ok }},
+		{name: "impersonations", cutoff: now,
+			add: func(db *Memory, id string, at time.Time) {
+				db.impersonations[id] = types.Impersonation{ID: id, Expires: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.impersonations[id]; return ok }},
+		{name: "sent emails", cutoff: now.AddDate(0, 0, -7),
+			add: func(db *Memory, id string, at time.Time) {
+				db.outbox[id] = types.OutboxEmail{ID: id, Status: types.EmailSent, Updated: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.outbox[id]; return ok }},
+		{name: "dead emails", cutoff: now.AddDate(0, 0, -30),
+			add: func(db *Memory, id string, at time.Time) {
+				db.outbox[id] = types.OutboxEmail{ID: id, Status: types.EmailDead, Updated: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.outbox[id]; return ok }},
+		{name: "pending emails", cutoff: now.AddDate(0, 0, -30), kept: true,
+			add: func(db *Memory, id string, at time.Time) {
+				db.outbox[id] = types.OutboxEmail{ID: id, Status: types.EmailPending, Updated: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.outbox[id]; return ok }},
+		{name: "magic links", cutoff: now,
+			add: func(db *Memory, id string, at time.Time) {
+				db.magicLinks[id] = types.MagicLink{ID: id, Expires: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.magicLinks[id]; return ok }},
+		{name: "login codes", cutoff: now,
+			add: func(db *Memory, id string, at time.Time) {
+				db.loginCodes[id] = types.LoginCode{ID: id, Expires: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.loginCodes[id]; return ok }},
+		{name: "phone codes", cutoff: now.Add(-24 * time.Hour),
+			add: func(db *Memory, id string, at time.Time) {
+				db.phoneCodes[id] = types.PhoneCode{ID: id, Created: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.phoneCodes[id]; return ok }},
+		{name: "delivered webhooks", cutoff: now.AddDate(0, 0, -7),
+			add: func(db *Memory, id string, at time.Time) {
+				db.deliveries[id] = types.WebhookDelivery{ID: id, Status: types.DeliveryDelivered, Updated: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.deliveries[id]; return ok }},
+		{name: "dead webhooks", cutoff: now.AddDate(0, 0, -30),
+			add: func(db *Memory, id string, at time.Time) {
+				db.deliveries[id] = types.WebhookDelivery{ID: id, Status: types.DeliveryDead, Updated: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.deliveries[id]; return ok }},
+		{name: "pending webhooks", cutoff: now.AddDate(0, 0, -30), kept: true,
+			add: func(db *Memory, id string, at time.Time) {
+				db.deliveries[id] = types.WebhookDelivery{ID: id, Status: types.DeliveryPending, Updated: at}
+			},
+			exists: func(db *Memory, id string) bool { _, ok := db.deliveries[id]; return ok }},
+	}
+
+	for _, test := range tests {
+		store := Memory{}.Init()
+		store.RefreshTokenDuration = refreshDays
+
+		test.add(store, "before", test.cutoff.Add(-time.Minute))
+		test.add(store, "after", test.cutoff.Add(time.Minute))
+		store.DeleteExpired()
+
+		if test.exists(store, "before") != test.kept {
+			t.Errorf("%s: row from before the cutoff kept %v, expected %v", test.name, !test.kept, test.kept)
+		}
+		if !test.exists(store, "after") {
+			t.Errorf("%s: row from after the cutoff was removed", test.name)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+//Migration - a versioned schema change shipped with the service
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+//MigrationStatus - where the database schema is compared to this binary
+type MigrationStatus struct {
+	Current int
+	Latest  int
+	Pending []Migration
+}
+
+//Migrations - returns the migrations for the dialect ordered by version
+func (d Dialect) Migrations() ([]Migration, error) {
+	dir := "migrations/" + d.Name
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []Migration{}
+	for _, entry := range entries {
+		//Files are named 0001_name.sql
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		parts := strings.SplitN(name, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 || name == entry.Name() {
+			return nil, errors.New("Invalid migration file name: " + entry.Name())
+		}
+
+		body, err := migrationFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, Migration{Version: version, Name: parts[1], SQL: string(body)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+//Statements - splits the migration into statements, drivers only run one at a time
+func (m Migration) Statements() []string {
+	statements := []string{}
+	current := ""
+	for _, line := range strings.Split(m.SQL, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current += line + "\n"
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current))
+			current = ""
+		}
+	}
+	if strings.TrimSpace(current) != "" {
+		statements = append(statements, strings.TrimSpace(current))
+	}
+	return statements
+}
+
+//MigrationStatus - returns the current schema version and the migrations that have not been applied
+func (db *SQLStore) MigrationStatus() (*MigrationStatus, error) {
+	migrations, err := db.Dialect.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.createMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied := map[int]bool{}
+	rows, err := db.Query(context.Background(), "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	status := &MigrationStatus{Pending: []Migration{}}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+		if version > status.Current {
+			status.Current = version
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range migrations {
+		if m.Version > status.Latest {
+			status.Latest = m.Version
+		}
+		if !applied[m.Version] {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+
+	return status, nil
+}
+
+//CheckSchema - refuses to run against a schema that is newer than this binary, or one that still needs migrating
+func (db *SQLStore) CheckSchema() error {
+	status, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	if status.Current > status.Latest {
+		return fmt.Errorf("Database schema version %d is newer than this binary supports (%d), upgrade the service", status.Current, status.Latest)
+	}
+	if len(status.Pending) > 0 {
+		return fmt.Errorf("Database schema has %d pending migrations, run the migrate command", len(status.Pending))
+	}
+
+	return nil
+}
+
+//Migrate - applies pending migrations in order, each in its own transaction where the database allows. A dry run only writes the SQL that would run to out
+func (db *SQLStore) Migrate(dryRun bool, out io.Writer) error {
+	status, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	if status.Current > status.Latest {
+		return fmt.Errorf("Database schema version %d is newer than this binary supports (%d), upgrade the service", status.Current, status.Latest)
+	}
+
+	for _, m := range status.Pending {
+		if dryRun {
+			fmt.Fprintf(out, "-- %04d_%s (dry run)\n%s\n", m.Version, m.Name, strings.Join(m.Statements(), "\n\n"))
+			continue
+		}
+
+		fmt.Fprintf(out, "Applying migration %04d_%s\n", m.Version, m.Name)
+		if err := db.applyMigration(m); err != nil {
+			return fmt.Errorf("Migration %04d_%s failed: %s", m.Version, m.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+//applyMigration - runs a migration and records it as applied, in one transaction where the database allows.
+//MySQL commits each DDL statement as it runs, so a migration that fails part way leaves the statements before it applied.
+//Running it again skips the tables, columns and indexes that already exist, which is why MySQL migrations keep to one change per statement
+func (db *SQLStore) applyMigration(m Migration) error {
+	tx, err := db.SQL.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range m.Statements() {
+		if _, err := tx.Exec(statement); err != nil {
+			if db.Dialect.ExistsError != nil && db.Dialect.ExistsError(err) {
+				continue
+			}
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec(db.Dialect.Rebind("INSERT INTO schema_migrations (version, name, applied) VALUES(?,?,?)"), db.Dialect.Args([]interface{}{m.Version, m.Name, time.Now()})...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//createMigrationsTable - creates the table that records applied migrations
+func (db *SQLStore) createMigrationsTable() error {
+	_, err := db.Exec(context.Background(), "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied "+db.Dialect.TimeType+" NOT NULL)")
+	return err
+}
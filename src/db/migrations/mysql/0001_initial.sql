@@ -0,0 +1,38 @@
+-- Tables that existed before migrations were tracked. IF NOT EXISTS lets existing databases adopt this version.
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    password VARCHAR(255) NOT NULL,
+    role INT NOT NULL DEFAULT 100,
+    firstName VARCHAR(255) NOT NULL DEFAULT '',
+    lastName VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(32) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL,
+    twoFA BOOLEAN NOT NULL DEFAULT FALSE,
+    created DATETIME NOT NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE KEY users_email (email)
+);
+
+CREATE TABLE IF NOT EXISTS devices (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    created DATETIME NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    code VARCHAR(16) NOT NULL,
+    KEY devices_account (accountId)
+);
+
+CREATE TABLE IF NOT EXISTS refreshtokens (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    deviceId VARCHAR(36) NOT NULL DEFAULT '',
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY refreshtokens_account (accountId)
+);
+
+CREATE TABLE IF NOT EXISTS recover (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created DATETIME NOT NULL
+);
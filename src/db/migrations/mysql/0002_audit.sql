@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS audit (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    actorId VARCHAR(36) NOT NULL DEFAULT '',
+    actorEmail VARCHAR(255) NOT NULL DEFAULT '',
+    targetId VARCHAR(36) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    userAgent VARCHAR(512) NOT NULL DEFAULT '',
+    outcome VARCHAR(16) NOT NULL,
+    detail TEXT NOT NULL,
+    created DATETIME(6) NOT NULL,
+    KEY audit_created (created, id),
+    KEY audit_actor (actorId, created),
+    KEY audit_target (targetId, created)
+);
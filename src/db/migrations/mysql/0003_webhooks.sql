@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    events VARCHAR(1024) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created DATETIME(6) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhookdeliveries (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    subscriptionId VARCHAR(36) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload MEDIUMTEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    nextAttempt DATETIME(6) NOT NULL,
+    responseCode INT NOT NULL DEFAULT 0,
+    lastError TEXT NOT NULL,
+    created DATETIME(6) NOT NULL,
+    updated DATETIME(6) NOT NULL,
+    KEY webhookdeliveries_due (status, nextAttempt),
+    KEY webhookdeliveries_subscription (subscriptionId, created)
+);
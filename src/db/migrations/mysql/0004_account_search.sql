@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN organization VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE users ADD KEY users_organization (organization);
+ALTER TABLE users ADD KEY users_created (created, id);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN disabledReason VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN deleted DATETIME(6) NULL;
+
+ALTER TABLE users ADD KEY users_deleted (deleted);
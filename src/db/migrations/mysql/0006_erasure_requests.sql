@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS erasurerequests (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    handledBy VARCHAR(36) NOT NULL DEFAULT '',
+    created DATETIME(6) NOT NULL,
+    updated DATETIME(6) NOT NULL,
+    KEY erasurerequests_status (status, created),
+    KEY erasurerequests_account (accountId)
+);
+
+ALTER TABLE webhookdeliveries ADD COLUMN accountId VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE webhookdeliveries ADD KEY webhookdeliveries_account (accountId);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    role INT NOT NULL,
+    organization VARCHAR(255) NOT NULL DEFAULT '',
+    invitedBy VARCHAR(36) NOT NULL,
+    created DATETIME(6) NOT NULL,
+    expires DATETIME(6) NOT NULL,
+    accepted DATETIME(6) NULL,
+    accountId VARCHAR(36) NOT NULL DEFAULT '',
+    KEY invitations_created (created),
+    KEY invitations_account (accountId)
+);
@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN externalId VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS scimtokens (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    organization VARCHAR(255) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    manageAdmins BOOLEAN NOT NULL DEFAULT FALSE,
+    created DATETIME(6) NOT NULL,
+    lastUsed DATETIME(6) NULL
+);
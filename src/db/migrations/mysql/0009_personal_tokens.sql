@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS personaltokens (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    created DATETIME(6) NOT NULL,
+    expires DATETIME(6) NOT NULL,
+    lastUsed DATETIME(6) NULL,
+    KEY personaltokens_account (accountId),
+    KEY personaltokens_expires (expires)
+);
@@ -0,0 +1,12 @@
+ALTER TABLE audit ADD COLUMN impersonatorId VARCHAR(36) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS impersonations (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    actorId VARCHAR(36) NOT NULL,
+    targetId VARCHAR(36) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    started DATETIME(6) NOT NULL,
+    expires DATETIME(6) NOT NULL,
+    stopped DATETIME(6) NULL,
+    KEY impersonations_expires (expires)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    sender VARCHAR(255) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body MEDIUMTEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    nextAttempt DATETIME(6) NOT NULL,
+    lastError TEXT NOT NULL,
+    created DATETIME(6) NOT NULL,
+    updated DATETIME(6) NOT NULL,
+    KEY outbox_due (status, nextAttempt),
+    KEY outbox_account (accountId)
+);
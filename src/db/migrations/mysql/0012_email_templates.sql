@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN language VARCHAR(35) NOT NULL DEFAULT '';
+
+ALTER TABLE outbox ADD COLUMN textBody MEDIUMTEXT NOT NULL;
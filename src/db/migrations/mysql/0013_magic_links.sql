@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS magiclinks (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    nonceHash VARCHAR(64) NOT NULL,
+    created DATETIME(6) NOT NULL,
+    expires DATETIME(6) NOT NULL,
+    KEY magiclinks_account (accountId),
+    KEY magiclinks_expires (expires)
+);
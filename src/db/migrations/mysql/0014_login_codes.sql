@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS logincodes (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    created DATETIME(6) NOT NULL,
+    expires DATETIME(6) NOT NULL,
+    KEY logincodes_account (accountId),
+    KEY logincodes_expires (expires)
+);
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN verifiedPhone VARCHAR(16) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS phonecodes (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    phone VARCHAR(16) NOT NULL,
+    purpose VARCHAR(36) NOT NULL,
+    channel VARCHAR(10) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    created DATETIME(6) NOT NULL,
+    expires DATETIME(6) NOT NULL,
+    KEY phonecodes_account (accountId, purpose),
+    KEY phonecodes_phone (phone, created)
+);
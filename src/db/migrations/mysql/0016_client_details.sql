@@ -0,0 +1,17 @@
+ALTER TABLE devices ADD COLUMN firstIp VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN lastIp VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN userAgent VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN browser VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN os VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN location VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN lastSeen DATETIME(6) NULL;
+
+ALTER TABLE refreshtokens ADD COLUMN firstIp VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN lastIp VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN userAgent VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN browser VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN os VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN location VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN lastSeen DATETIME(6) NULL;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    password VARCHAR(255) NOT NULL,
+    role INTEGER NOT NULL DEFAULT 100,
+    firstName VARCHAR(255) NOT NULL DEFAULT '',
+    lastName VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(32) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL UNIQUE,
+    twoFA BOOLEAN NOT NULL DEFAULT FALSE,
+    created TIMESTAMPTZ NOT NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS devices (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    code VARCHAR(16) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS devices_account ON devices (accountId);
+
+CREATE TABLE IF NOT EXISTS refreshtokens (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    deviceId VARCHAR(36) NOT NULL DEFAULT '',
+    created TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refreshtokens_account ON refreshtokens (accountId);
+
+CREATE TABLE IF NOT EXISTS recover (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created TIMESTAMPTZ NOT NULL
+);
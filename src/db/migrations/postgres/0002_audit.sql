@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS audit (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    actorId VARCHAR(36) NOT NULL DEFAULT '',
+    actorEmail VARCHAR(255) NOT NULL DEFAULT '',
+    targetId VARCHAR(36) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    userAgent VARCHAR(512) NOT NULL DEFAULT '',
+    outcome VARCHAR(16) NOT NULL,
+    detail TEXT NOT NULL,
+    created TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_created ON audit (created, id);
+CREATE INDEX IF NOT EXISTS audit_actor ON audit (actorId, created);
+CREATE INDEX IF NOT EXISTS audit_target ON audit (targetId, created);
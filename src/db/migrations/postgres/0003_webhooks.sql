@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    events VARCHAR(1024) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhookdeliveries (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    subscriptionId VARCHAR(36) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    nextAttempt TIMESTAMPTZ NOT NULL,
+    responseCode INTEGER NOT NULL DEFAULT 0,
+    lastError TEXT NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    updated TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhookdeliveries_due ON webhookdeliveries (status, nextAttempt);
+CREATE INDEX IF NOT EXISTS webhookdeliveries_subscription ON webhookdeliveries (subscriptionId, created);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN organization VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS users_organization ON users (organization);
+CREATE INDEX IF NOT EXISTS users_created ON users (created, id);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN disabledReason VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN deleted TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS users_deleted ON users (deleted);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS erasurerequests (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    handledBy VARCHAR(36) NOT NULL DEFAULT '',
+    created TIMESTAMPTZ NOT NULL,
+    updated TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS erasurerequests_status ON erasurerequests (status, created);
+CREATE INDEX IF NOT EXISTS erasurerequests_account ON erasurerequests (accountId);
+
+ALTER TABLE webhookdeliveries ADD COLUMN accountId VARCHAR(36) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS webhookdeliveries_account ON webhookdeliveries (accountId);
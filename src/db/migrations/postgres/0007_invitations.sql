@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    role INTEGER NOT NULL,
+    organization VARCHAR(255) NOT NULL DEFAULT '',
+    invitedBy VARCHAR(36) NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL,
+    accepted TIMESTAMPTZ NULL,
+    accountId VARCHAR(36) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS invitations_created ON invitations (created);
+CREATE INDEX IF NOT EXISTS invitations_account ON invitations (accountId);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS personaltokens (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL,
+    lastUsed TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS personaltokens_account ON personaltokens (accountId);
+CREATE INDEX IF NOT EXISTS personaltokens_expires ON personaltokens (expires);
@@ -0,0 +1,13 @@
+ALTER TABLE audit ADD COLUMN impersonatorId VARCHAR(36) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS impersonations (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    actorId VARCHAR(36) NOT NULL,
+    targetId VARCHAR(36) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    started TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL,
+    stopped TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS impersonations_expires ON impersonations (expires);
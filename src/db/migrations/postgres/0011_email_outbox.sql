@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    sender VARCHAR(255) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    nextAttempt TIMESTAMPTZ NOT NULL,
+    lastError TEXT NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    updated TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS outbox_due ON outbox (status, nextAttempt);
+CREATE INDEX IF NOT EXISTS outbox_account ON outbox (accountId);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS magiclinks (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    nonceHash VARCHAR(64) NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS magiclinks_account ON magiclinks (accountId);
+CREATE INDEX IF NOT EXISTS magiclinks_expires ON magiclinks (expires);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS logincodes (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS logincodes_account ON logincodes (accountId);
+CREATE INDEX IF NOT EXISTS logincodes_expires ON logincodes (expires);
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN verifiedPhone VARCHAR(16) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS phonecodes (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    accountId VARCHAR(36) NOT NULL,
+    phone VARCHAR(16) NOT NULL,
+    purpose VARCHAR(36) NOT NULL,
+    channel VARCHAR(10) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS phonecodes_account ON phonecodes (accountId, purpose);
+CREATE INDEX IF NOT EXISTS phonecodes_phone ON phonecodes (phone, created);
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT NOT NULL PRIMARY KEY,
+    password TEXT NOT NULL,
+    role INTEGER NOT NULL DEFAULT 100,
+    firstName TEXT NOT NULL DEFAULT '',
+    lastName TEXT NOT NULL DEFAULT '',
+    phone TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL UNIQUE,
+    twoFA BOOLEAN NOT NULL DEFAULT FALSE,
+    created DATETIME NOT NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS devices (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    code TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS devices_account ON devices (accountId);
+
+CREATE TABLE IF NOT EXISTS refreshtokens (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    deviceId TEXT NOT NULL DEFAULT '',
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refreshtokens_account ON refreshtokens (accountId);
+
+CREATE TABLE IF NOT EXISTS recover (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    email TEXT NOT NULL,
+    created DATETIME NOT NULL
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS audit (
+    id TEXT NOT NULL PRIMARY KEY,
+    type TEXT NOT NULL,
+    actorId TEXT NOT NULL DEFAULT '',
+    actorEmail TEXT NOT NULL DEFAULT '',
+    targetId TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    userAgent TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL,
+    detail TEXT NOT NULL,
+    created DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_created ON audit (created, id);
+CREATE INDEX IF NOT EXISTS audit_actor ON audit (actorId, created);
+CREATE INDEX IF NOT EXISTS audit_target ON audit (targetId, created);
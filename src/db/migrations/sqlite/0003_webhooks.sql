@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id TEXT NOT NULL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhookdeliveries (
+    id TEXT NOT NULL PRIMARY KEY,
+    subscriptionId TEXT NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    nextAttempt DATETIME NOT NULL,
+    responseCode INTEGER NOT NULL DEFAULT 0,
+    lastError TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    updated DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhookdeliveries_due ON webhookdeliveries (status, nextAttempt);
+CREATE INDEX IF NOT EXISTS webhookdeliveries_subscription ON webhookdeliveries (subscriptionId, created);
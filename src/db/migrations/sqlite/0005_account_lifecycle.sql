@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN disabledReason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN deleted DATETIME NULL;
+
+CREATE INDEX IF NOT EXISTS users_deleted ON users (deleted);
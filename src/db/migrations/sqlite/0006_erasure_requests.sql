@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS erasurerequests (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    token TEXT NOT NULL,
+    status TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    handledBy TEXT NOT NULL DEFAULT '',
+    created DATETIME NOT NULL,
+    updated DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS erasurerequests_status ON erasurerequests (status, created);
+CREATE INDEX IF NOT EXISTS erasurerequests_account ON erasurerequests (accountId);
+
+ALTER TABLE webhookdeliveries ADD COLUMN accountId TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS webhookdeliveries_account ON webhookdeliveries (accountId);
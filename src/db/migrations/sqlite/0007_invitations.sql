@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    id TEXT NOT NULL PRIMARY KEY,
+    email TEXT NOT NULL,
+    token TEXT NOT NULL,
+    role INTEGER NOT NULL,
+    organization TEXT NOT NULL DEFAULT '',
+    invitedBy TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    accepted DATETIME NULL,
+    accountId TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS invitations_created ON invitations (created);
+CREATE INDEX IF NOT EXISTS invitations_account ON invitations (accountId);
@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN externalId TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS scimtokens (
+    id TEXT NOT NULL PRIMARY KEY,
+    organization TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    manageAdmins BOOLEAN NOT NULL DEFAULT FALSE,
+    created DATETIME NOT NULL,
+    lastUsed DATETIME NULL
+);
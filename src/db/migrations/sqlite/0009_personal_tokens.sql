@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS personaltokens (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    name TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    lastUsed DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS personaltokens_account ON personaltokens (accountId);
+CREATE INDEX IF NOT EXISTS personaltokens_expires ON personaltokens (expires);
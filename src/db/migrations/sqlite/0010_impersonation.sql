@@ -0,0 +1,13 @@
+ALTER TABLE audit ADD COLUMN impersonatorId TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS impersonations (
+    id TEXT NOT NULL PRIMARY KEY,
+    actorId TEXT NOT NULL,
+    targetId TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    started DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    stopped DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS impersonations_expires ON impersonations (expires);
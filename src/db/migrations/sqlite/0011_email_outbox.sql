@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    sender TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    nextAttempt DATETIME NOT NULL,
+    lastError TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    updated DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS outbox_due ON outbox (status, nextAttempt);
+CREATE INDEX IF NOT EXISTS outbox_account ON outbox (accountId);
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN language TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE outbox ADD COLUMN textBody TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS magiclinks (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    nonceHash TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS magiclinks_account ON magiclinks (accountId);
+CREATE INDEX IF NOT EXISTS magiclinks_expires ON magiclinks (expires);
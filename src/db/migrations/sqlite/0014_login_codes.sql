@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS logincodes (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS logincodes_account ON logincodes (accountId);
+CREATE INDEX IF NOT EXISTS logincodes_expires ON logincodes (expires);
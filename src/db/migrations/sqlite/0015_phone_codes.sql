@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN verifiedPhone TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS phonecodes (
+    id TEXT NOT NULL PRIMARY KEY,
+    accountId TEXT NOT NULL,
+    phone TEXT NOT NULL,
+    purpose TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS phonecodes_account ON phonecodes (accountId, purpose);
+CREATE INDEX IF NOT EXISTS phonecodes_phone ON phonecodes (phone, created);
@@ -0,0 +1,17 @@
+ALTER TABLE devices ADD COLUMN firstIp TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN lastIp TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN userAgent TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN browser TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN os TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN country TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN location TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN lastSeen DATETIME NULL;
+
+ALTER TABLE refreshtokens ADD COLUMN firstIp TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN lastIp TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN userAgent TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN browser TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN os TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN country TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN location TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN lastSeen DATETIME NULL;
@@ -8,21 +8,17 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
-	"time"
-	"utils"
 
 	"github.com/go-sql-driver/mysql"
 	_ "github.com/go-sql-driver/mysql" //Mysql driver
 )
 
-//MySQL - MYSQL database class
+//MySQL - MYSQL database connector
 type MySQL struct {
-	SQL                  *sql.DB
-	RefreshTokenDuration string
 }
 
 //Init - Start pooling with mysql
-func (db MySQL) Init() (*MySQL, error) {
+func (db MySQL) Init() (*SQLStore, error) {
 
 	//If an SSL key is provided use SSL.
 	if os.Getenv("MYSQL_SSLKEY") != "" {
@@ -47,52 +43,24 @@ func (db MySQL) Init() (*MySQL, error) {
 			return nil, errors.New("Cannot connect to database using SSL")
 		}
 
-		db.SQL = pool
+		return SQLStore{}.Init(pool, MySQLDialect), nil
 
-	} else { //No SSL key was provided. Connect without.
-		pool, _ := sql.Open("mysql", os.Getenv("MYSQL_USER")+":"+os.Getenv("MYSQL_PASSWORD")+"@tcp(["+os.Getenv("MYSQL_HOST")+"])/"+os.Getenv("MYSQL_DATABASE")+"?parseTime=true")
-		if pool.Ping() != nil {
-			return nil, errors.New("Cannot connect to database")
-		}
-
-		db.SQL = pool
 	}
 
-	db.RefreshTokenDuration = os.Getenv("TOKENS_REFRESH_TOKEN_DURATION")
-
-	//Setup interval to remove expired data
-	utils.Schedule(db.DeleteExpired, 1*time.Hour)
-
-	return &db, nil
-}
-
-//PreparedQuery - returns a prepared statement
-func (db MySQL) PreparedQuery(query string) (*sql.Stmt, error) {
-	stmt, err := db.SQL.Prepare(query)
-	if err != nil {
-		return nil, err
+	//No SSL key was provided. Connect without.
+	pool, _ := sql.Open("mysql", os.Getenv("MYSQL_USER")+":"+os.Getenv("MYSQL_PASSWORD")+"@tcp(["+os.Getenv("MYSQL_HOST")+"])/"+os.Getenv("MYSQL_DATABASE")+"?parseTime=true")
+	if pool.Ping() != nil {
+		return nil, errors.New("Cannot connect to database")
 	}
-	return stmt, nil
-}
 
-//SimpleQuery - returns the result of a query
-func (db MySQL) SimpleQuery(query string) (*sql.Rows, error) {
-	q, err := db.SQL.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	return q, nil
+	return SQLStore{}.Init(pool, MySQLDialect), nil
 }
 
-//DeleteExpired - removes all expired recoveries or devices
-func (db MySQL) DeleteExpired() {
-
-	rows1, _ := db.SimpleQuery("DELETE FROM recover WHERE created < (NOW() - INTERVAL 1 HOUR)")
-	//rows2, _ := db.SimpleQuery("DELETE FROM emailChange WHERE created < (NOW() - INTERVAL 1 HOUR)")
-	rows3, _ := db.SimpleQuery("DELETE FROM devices WHERE created < (NOW() - INTERVAL 60 DAY)")
-	rows4, _ := db.SimpleQuery("DELETE FROM refreshtokens WHERE created < (NOW() - INTERVAL " + db.RefreshTokenDuration + " DAY)")
-
-	rows1.Close()
-	rows3.Close()
-	rows4.Close()
+//mysqlExistsError - the table (1050), column (1060) or index (1061) already exists
+func mysqlExistsError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == 1050 || mysqlErr.Number == 1060 || mysqlErr.Number == 1061
 }
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+
+	_ "github.com/lib/pq" //Postgres driver
+)
+
+//Postgres - PostgreSQL database connector
+type Postgres struct {
+}
+
+//Init - Start pooling with postgres using the POSTGRES_URL connection string
+func (db Postgres) Init() (*SQLStore, error) {
+	if os.Getenv("POSTGRES_URL") == "" {
+		return nil, errors.New("POSTGRES_URL is not set")
+	}
+
+	pool, err := sql.Open("postgres", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		return nil, err
+	}
+	if pool.Ping() != nil {
+		return nil, errors.New("Cannot connect to database")
+	}
+
+	return SQLStore{}.Init(pool, PostgresDialect), nil
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+	"types"
+)
+
+//SQLStore - Store backed by a SQL database
+type SQLStore struct {
+	SQL                  *sql.DB
+	Dialect              Dialect
+	RefreshTokenDuration int
+
+	//tx - set when the store is running inside a transaction
+	tx *sql.Tx
+}
+
+//querier - what both a connection pool and a transaction can run
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+//Init - wraps an open connection pool
+func (db SQLStore) Init(pool *sql.DB, dialect Dialect) *SQLStore {
+	db.SQL = pool
+	db.Dialect = dialect
+
+	db.RefreshTokenDuration = refreshTokenDays()
+
+	return &db
+}
+
+//conn - returns the transaction if there is one, otherwise the pool
+func (db *SQLStore) conn() querier {
+	if db.tx != nil {
+		return db.tx
+	}
+	return db.SQL
+}
+
+//Query - runs a query that returns rows
+func (db *SQLStore) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := db.conn().QueryContext(ctx, db.Dialect.Rebind(query), db.Dialect.Args(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//Exec - runs a query that does not return rows
+func (db *SQLStore) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := db.conn().ExecContext(ctx, db.Dialect.Rebind(query), db.Dialect.Args(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//InTx - runs fn in a transaction that is committed if fn returns nil and rolled back otherwise.
+//Calls made while already in a transaction join it.
+func (db *SQLStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	return db.inTx(ctx, func(tx *SQLStore) error {
+		return fn(tx)
+	})
+}
+
+//inTx - InTx for use inside the store
+func (db *SQLStore) inTx(ctx context.Context, fn func(tx *SQLStore) error) (err error) {
+	if db.tx != nil {
+		return fn(db)
+	}
+
+	sqlTx, err := db.SQL.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	//Never leave a transaction open, even if fn panics
+	defer func() {
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			sqlTx.Rollback()
+		}
+	}()
+
+	tx := *db
+	tx.tx = sqlTx
+	if err = fn(&tx); err != nil {
+		return err
+	}
+
+	return sqlTx.Commit()
+}
+
+//DeleteExpired - removes all expired recoveries, devices, refresh tokens, unconfirmed erasure requests, unused invitations, personal access tokens, impersonations, magic links, login codes, phone codes, old outbox emails and old webhook deliveries
+func (db *SQLStore) DeleteExpired() {
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := db.Exec(ctx, "DELETE FROM recover WHERE created < ?", now.Add(-1*time.Hour)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM devices WHERE created < ?", now.AddDate(0, 0, -60)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM refreshtokens WHERE created < ?", now.AddDate(0, 0, -db.RefreshTokenDuration)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM erasurerequests WHERE status = ? AND created < ?", types.ErasureUnconfirmed, now.Add(-24*time.Hour)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM invitations WHERE accepted IS NULL AND expires < ?", now); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM personaltokens WHERE expires < ?", now); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM impersonations WHERE expires < ?", now); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM magiclinks WHERE expires < ?", now); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM logincodes WHERE expires < ?", now); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM phonecodes WHERE created < ?", now.Add(-24*time.Hour)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM outbox WHERE status = ? AND updated < ?", types.EmailSent, now.AddDate(0, 0, -7)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM outbox WHERE status = ? AND updated < ?", types.EmailDead, now.AddDate(0, 0, -30)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM webhookdeliveries WHERE status = ? AND updated < ?", types.DeliveryDelivered, now.AddDate(0, 0, -7)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM webhookdeliveries WHERE status = ? AND updated < ?", types.DeliveryDead, now.AddDate(0, 0, -30)); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteExpired Error: "+err.Error())
+	}
+}
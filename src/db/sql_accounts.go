@@ -0,0 +1,315 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//EmailTaken - checks if an account other than excludeID already uses the email
+func (db *SQLStore) EmailTaken(ctx context.Context, email string, excludeID string) (bool, error) {
+	rows, err := db.Query(ctx, "SELECT id FROM users WHERE email = ? AND id <> ?", email, excludeID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+//InsertAccount - saves a new account
+func (db *SQLStore) InsertAccount(ctx context.Context, account *types.Account) error {
+	_, err := db.Exec(ctx, "INSERT INTO users (id, password, role, firstName, lastName, phone, email, created, organization, externalId, language) VALUES(?,?,?,?,?,?,?,?,?,?,?)",
+		account.ID, account.Password, account.Role, account.FirstName, account.LastName, account.Phone, account.Email, account.Created, account.Organization, account.ExternalID, account.Language)
+	return err
+}
+
+//GetAccountByID - returns an account by ID
+func (db *SQLStore) GetAccountByID(ctx context.Context, id string) (*types.Account, error) {
+	return db.getAccount(ctx, "SELECT * FROM users WHERE id = ?", id)
+}
+
+//GetAccountByEmail - returns an account by email
+func (db *SQLStore) GetAccountByEmail(ctx context.Context, email string) (*types.Account, error) {
+	return db.getAccount(ctx, "SELECT * FROM users WHERE email = ?", email)
+}
+
+//getAccount - returns the first account found by the query
+func (db *SQLStore) getAccount(ctx context.Context, query string, args ...interface{}) (*types.Account, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		account := types.Account{}
+		err = sqlstruct.Scan(&account, rows)
+		if err != nil {
+			return nil, err
+		}
+		return &account, nil
+	}
+	return nil, rows.Err()
+}
+
+//SearchAccounts - returns up to limit accounts matching the search in its sort order
+func (db *SQLStore) SearchAccounts(ctx context.Context, search *types.AccountSearchRequest, after *types.Account, limit int) (*[]types.Account, error) {
+	where, args := accountFilters(search)
+
+	column := accountSortColumn(search.Sort)
+	direction, compare := "ASC", ">"
+	if search.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	//Continue after the last account of the previous page
+	if after != nil {
+		value := accountSortValue(after, search.Sort)
+		where += " AND (" + column + " " + compare + " ? OR (" + column + " = ? AND id " + compare + " ?))"
+		args = append(args, value, value, after.ID)
+	}
+
+	query := "SELECT * FROM users" + where + " ORDER BY " + column + " " + direction + ", id " + direction + " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []types.Account{}
+	for rows.Next() {
+		account := types.Account{}
+		err := sqlstruct.Scan(&account, rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return &accounts, rows.Err()
+}
+
+//CountAccounts - returns how many accounts match the search
+func (db *SQLStore) CountAccounts(ctx context.Context, search *types.AccountSearchRequest) (int, error) {
+	where, args := accountFilters(search)
+
+	rows, err := db.Query(ctx, "SELECT COUNT(*) FROM users"+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	total := 0
+	if rows.Next() {
+		if err := rows.Scan(&total); err != nil {
+			return 0, err
+		}
+	}
+	return total, rows.Err()
+}
+
+//accountFilters - builds the WHERE clause for an account search
+func accountFilters(search *types.AccountSearchRequest) (string, []interface{}) {
+	where := " WHERE 1 = 1"
+	args := []interface{}{}
+
+	if len(search.Roles) > 0 {
+		where += " AND role IN (?" + strings.Repeat(",?", len(search.Roles)-1) + ")"
+		for _, r := range search.Roles {
+			args = append(args, r)
+		}
+	}
+	if search.Disabled != nil {
+		where += " AND disabled = ?"
+		args = append(args, *search.Disabled)
+	}
+	if search.TwoFA != nil {
+		where += " AND twoFA = ?"
+		args = append(args, *search.TwoFA)
+	}
+	if search.Deleted {
+		where += " AND deleted IS NOT NULL"
+	} else {
+		where += " AND deleted IS NULL"
+	}
+	if search.Organization != "" {
+		where += " AND organization = ?"
+		args = append(args, search.Organization)
+	}
+	if !search.CreatedSince.IsZero() {
+		where += " AND created >= ?"
+		args = append(args, search.CreatedSince)
+	}
+	if !search.CreatedUntil.IsZero() {
+		where += " AND created < ?"
+		args = append(args, search.CreatedUntil)
+	}
+	if search.Query != "" {
+		like := likePattern(search.Query)
+		where += " AND (LOWER(firstName) LIKE ? ESCAPE '!' OR LOWER(lastName) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!' OR phone LIKE ? ESCAPE '!')"
+		args = append(args, like, like, like, like)
+	}
+
+	return where, args
+}
+
+//accountSortColumn - returns the column for a sort field. Only known columns are ever put in a query
+func accountSortColumn(sort string) string {
+	switch sort {
+	case types.AccountSortLastName:
+		return "lastName"
+	case types.AccountSortEmail:
+		return "email"
+	case types.AccountSortCreated:
+		return "created"
+	default:
+		return "firstName"
+	}
+}
+
+//accountSortValue - returns the value of the sort field on an account
+func accountSortValue(account *types.Account, sort string) interface{} {
+	switch sort {
+	case types.AccountSortLastName:
+		return account.LastName
+	case types.AccountSortEmail:
+		return account.Email
+	case types.AccountSortCreated:
+		return account.Created
+	default:
+		return account.FirstName
+	}
+}
+
+//likePattern - matches text anywhere, with LIKE wildcards in it escaped by !
+func likePattern(text string) string {
+	text = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(text))
+	return "%" + text + "%"
+}
+
+//UpdateSettings - updates the settings an account can change itself
+func (db *SQLStore) UpdateSettings(ctx context.Context, account *types.Account) error {
+	_, err := db.Exec(ctx, "UPDATE users SET firstName = ?, lastName = ?, phone = ?, language = ? WHERE id = ?",
+		account.FirstName, account.LastName, account.Phone, account.Language, account.ID)
+	return err
+}
+
+//UpdateAccount - updates the settings an admin can change
+func (db *SQLStore) UpdateAccount(ctx context.Context, account *types.Account) error {
+	_, err := db.Exec(ctx, "UPDATE users SET firstName = ?, lastName = ?, email = ?, phone = ?, role = ?, organization = ?, externalId = ?, language = ? WHERE id = ?",
+		account.FirstName, account.LastName, account.Email, account.Phone, account.Role, account.Organization, account.ExternalID, account.Language, account.ID)
+	return err
+}
+
+//UpdatePassword - sets an accounts password hash
+func (db *SQLStore) UpdatePassword(ctx context.Context, id string, password string) error {
+	_, err := db.Exec(ctx, "UPDATE users SET password = ? WHERE id = ?", password, id)
+	return err
+}
+
+//AnonymizeAccount - overwrites the personal details of an account and removes everything that belongs to it
+func (db *SQLStore) AnonymizeAccount(ctx context.Context, account *types.Account) error {
+	return db.inTx(ctx, func(tx *SQLStore) error {
+		_, err := tx.Exec(ctx, "UPDATE users SET password = ?, firstName = ?, lastName = ?, phone = ?, email = ?, organization = ?, externalId = ?, twoFA = ?, disabled = ?, disabledReason = ?, verifiedPhone = ? WHERE id = ?",
+			account.Password, account.FirstName, account.LastName, account.Phone, account.Email, account.Organization, account.ExternalID, account.TwoFA, account.Disabled, account.DisabledReason, account.VerifiedPhone, account.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, query := range []string{
+			"DELETE FROM refreshtokens WHERE accountId = ?",
+			"DELETE FROM devices WHERE accountId = ?",
+			"DELETE FROM recover WHERE accountId = ?",
+			"DELETE FROM invitations WHERE accountId = ?",
+			"DELETE FROM personaltokens WHERE accountId = ?",
+			"DELETE FROM impersonations WHERE targetId = ?",
+			"DELETE FROM impersonations WHERE actorId = ?",
+			"DELETE FROM outbox WHERE accountId = ?",
+			"DELETE FROM magiclinks WHERE accountId = ?",
+			"DELETE FROM logincodes WHERE accountId = ?",
+			"DELETE FROM phonecodes WHERE accountId = ?",
+		} {
+			if _, err := tx.Exec(ctx, query, account.ID); err != nil {
+				return err
+			}
+		}
+		return tx.deleteDeliveries(ctx, account.ID)
+	})
+}
+
+//SetDisabled - disables or enables an account
+func (db *SQLStore) SetDisabled(ctx context.Context, id string, disabled bool, reason string) error {
+	_, err := db.Exec(ctx, "UPDATE users SET disabled = ?, disabledReason = ? WHERE id = ?", disabled, reason, id)
+	return err
+}
+
+//SetVerifiedPhone - records the number an account proved it has
+func (db *SQLStore) SetVerifiedPhone(ctx context.Context, id string, phone string) error {
+	_, err := db.Exec(ctx, "UPDATE users SET verifiedPhone = ? WHERE id = ?", phone, id)
+	return err
+}
+
+//SetDeleted - soft deletes or restores an account
+func (db *SQLStore) SetDeleted(ctx context.Context, id string, deleted *time.Time) error {
+	var value interface{}
+	if deleted != nil {
+		value = *deleted
+	}
+	_, err := db.Exec(ctx, "UPDATE users SET deleted = ? WHERE id = ?", value, id)
+	return err
+}
+
+//GetDeletedBefore - returns up to limit accounts soft deleted before the time given, oldest first
+func (db *SQLStore) GetDeletedBefore(ctx context.Context, before time.Time, limit int) (*[]types.Account, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM users WHERE deleted IS NOT NULL AND deleted < ? ORDER BY deleted ASC LIMIT ?", before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []types.Account{}
+	for rows.Next() {
+		account := types.Account{}
+		err := sqlstruct.Scan(&account, rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return &accounts, rows.Err()
+}
+
+//DeleteAccount - deletes an account and everything that belongs to it
+func (db *SQLStore) DeleteAccount(ctx context.Context, id string) error {
+	return db.inTx(ctx, func(tx *SQLStore) error {
+		for _, query := range []string{
+			"DELETE FROM refreshtokens WHERE accountId = ?",
+			"DELETE FROM devices WHERE accountId = ?",
+			"DELETE FROM recover WHERE accountId = ?",
+			"DELETE FROM invitations WHERE accountId = ?",
+			"DELETE FROM personaltokens WHERE accountId = ?",
+			"DELETE FROM impersonations WHERE targetId = ?",
+			"DELETE FROM impersonations WHERE actorId = ?",
+			"DELETE FROM outbox WHERE accountId = ?",
+			"DELETE FROM magiclinks WHERE accountId = ?",
+			"DELETE FROM logincodes WHERE accountId = ?",
+			"DELETE FROM phonecodes WHERE accountId = ?",
+			"DELETE FROM users WHERE id = ?",
+		} {
+			if _, err := tx.Exec(ctx, query, id); err != nil {
+				return err
+			}
+		}
+		return tx.deleteDeliveries(ctx, id)
+	})
+}
+
+//deleteDeliveries - removes the webhook deliveries about an account, their payloads hold its personal details.
+//Deliveries queued before they recorded their account are found by the account ID in the payload
+func (db *SQLStore) deleteDeliveries(ctx context.Context, accountID string) error {
+	_, err := db.Exec(ctx, "DELETE FROM webhookdeliveries WHERE accountId = ? OR (accountId = '' AND payload LIKE ?)", accountID, "%"+accountID+"%")
+	return err
+}
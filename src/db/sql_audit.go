@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertEvent - saves an audit event
+func (db *SQLStore) InsertEvent(ctx context.Context, event *types.AuditEvent) error {
+	_, err := db.Exec(ctx, "INSERT INTO audit (id, type, actorId, actorEmail, impersonatorId, targetId, ip, userAgent, outcome, detail, created) VALUES(?,?,?,?,?,?,?,?,?,?,?)",
+		event.ID, event.Type, event.ActorID, event.ActorEmail, event.ImpersonatorID, event.TargetID, event.IP, event.UserAgent, event.Outcome, event.Detail, event.Created)
+	return err
+}
+
+//SearchEvents - returns up to limit events matching the search, newest first
+func (db *SQLStore) SearchEvents(ctx context.Context, search *types.AuditSearchRequest, after *types.AuditEvent, limit int) (*[]types.AuditEvent, error) {
+	query := "SELECT * FROM audit WHERE 1 = 1"
+	args := []interface{}{}
+
+	if search.Type != "" {
+		query += " AND type = ?"
+		args = append(args, search.Type)
+	}
+	if search.ActorID != "" {
+		query += " AND actorId = ?"
+		args = append(args, search.ActorID)
+	}
+	if search.ImpersonatorID != "" {
+		query += " AND impersonatorId = ?"
+		args = append(args, search.ImpersonatorID)
+	}
+	if search.TargetID != "" {
+		query += " AND targetId = ?"
+		args = append(args, search.TargetID)
+	}
+	if search.Outcome != "" {
+		query += " AND outcome = ?"
+		args = append(args, search.Outcome)
+	}
+	if search.IP != "" {
+		query += " AND ip = ?"
+		args = append(args, search.IP)
+	}
+	if !search.Since.IsZero() {
+		query += " AND created >= ?"
+		args = append(args, search.Since)
+	}
+	if !search.Until.IsZero() {
+		query += " AND created < ?"
+		args = append(args, search.Until)
+	}
+
+	//Continue after the last event of the previous page
+	if after != nil {
+		query += " AND (created < ? OR (created = ? AND id < ?))"
+		args = append(args, after.Created, after.Created, after.ID)
+	}
+
+	query += " ORDER BY created DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []types.AuditEvent{}
+	for rows.Next() {
+		event := types.AuditEvent{}
+		err = sqlstruct.Scan(&event, rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return &events, rows.Err()
+}
+
+//ScrubEvents - blanks the email, client and detail of every event about the account or email
+func (db *SQLStore) ScrubEvents(ctx context.Context, accountID string, email string) error {
+	_, err := db.Exec(ctx, "UPDATE audit SET actorEmail = '', ip = '', userAgent = '', detail = '' WHERE actorId = ? OR targetId = ? OR actorEmail = ?",
+		accountID, accountID, email)
+	return err
+}
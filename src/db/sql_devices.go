@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//GetDevice - returns a device
+func (db *SQLStore) GetDevice(ctx context.Context, id string) (*types.Device, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM devices WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		device := types.Device{}
+		err = sqlstruct.Scan(&device, rows)
+		if err != nil {
+			return nil, err
+		}
+		return &device, nil
+	}
+	return nil, rows.Err()
+}
+
+//InsertDevice - saves a new device
+func (db *SQLStore) InsertDevice(ctx context.Context, device *types.Device) error {
+	_, err := db.Exec(ctx, "INSERT INTO devices (id, accountId, created, active, code, firstIp, lastIp, userAgent, browser, os, country, location, lastSeen) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		device.ID, device.AccountID, device.Created, device.Active, device.Code, device.FirstIP, device.LastIP, device.UserAgent, device.Browser, device.OS, device.Country, device.Location, device.LastSeen)
+	return err
+}
+
+//ActivateDevice - marks a device as active
+func (db *SQLStore) ActivateDevice(ctx context.Context, id string) error {
+	_, err := db.Exec(ctx, "UPDATE devices SET active = ? WHERE id = ?", true, id)
+	return err
+}
+
+//TouchDevice - saves the client a device was last used from
+func (db *SQLStore) TouchDevice(ctx context.Context, device *types.Device) error {
+	_, err := db.Exec(ctx, "UPDATE devices SET firstIp = ?, lastIp = ?, userAgent = ?, browser = ?, os = ?, country = ?, location = ?, lastSeen = ? WHERE id = ?",
+		device.FirstIP, device.LastIP, device.UserAgent, device.Browser, device.OS, device.Country, device.Location, device.LastSeen, device.ID)
+	return err
+}
+
+//GetDevicesByAccount - returns every device of an account, oldest first
+func (db *SQLStore) GetDevicesByAccount(ctx context.Context, accountID string) (*[]types.Device, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM devices WHERE accountId = ? ORDER BY created ASC", accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []types.Device{}
+	for rows.Next() {
+		device := types.Device{}
+		err = sqlstruct.Scan(&device, rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return &devices, rows.Err()
+}
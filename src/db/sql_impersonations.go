@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"time"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertImpersonation - saves a new impersonation
+func (db *SQLStore) InsertImpersonation(ctx context.Context, impersonation *types.Impersonation) error {
+	_, err := db.Exec(ctx, "INSERT INTO impersonations (id, actorId, targetId, reason, started, expires, stopped) VALUES(?,?,?,?,?,?,NULL)",
+		impersonation.ID, impersonation.ActorID, impersonation.TargetID, impersonation.Reason, impersonation.Started, impersonation.Expires)
+	return err
+}
+
+//GetImpersonation - returns an impersonation
+func (db *SQLStore) GetImpersonation(ctx context.Context, id string) (*types.Impersonation, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM impersonations WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		impersonation := types.Impersonation{}
+		err = sqlstruct.Scan(&impersonation, rows)
+		if err != nil {
+			return nil, err
+		}
+		return &impersonation, nil
+	}
+	return nil, rows.Err()
+}
+
+//StopImpersonation - records when an impersonation was stopped
+func (db *SQLStore) StopImpersonation(ctx context.Context, id string, stopped time.Time) error {
+	_, err := db.Exec(ctx, "UPDATE impersonations SET stopped = ? WHERE id = ?", stopped, id)
+	return err
+}
+
+//StopImpersonationsByAccount - stops every running impersonation by or of an account
+func (db *SQLStore) StopImpersonationsByAccount(ctx context.Context, accountID string, stopped time.Time) error {
+	_, err := db.Exec(ctx, "UPDATE impersonations SET stopped = ? WHERE (actorId = ? OR targetId = ?) AND stopped IS NULL AND expires > ?", stopped, accountID, accountID, stopped)
+	return err
+}
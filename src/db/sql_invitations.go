@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"time"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertInvitation - saves a new invitation
+func (db *SQLStore) InsertInvitation(ctx context.Context, invitation *types.Invitation) error {
+	_, err := db.Exec(ctx, "INSERT INTO invitations (id, email, token, role, organization, invitedBy, created, expires, accepted, accountId) VALUES(?,?,?,?,?,?,?,?,NULL,'')",
+		invitation.ID, invitation.Email, invitation.Token, invitation.Role, invitation.Organization, invitation.InvitedBy, invitation.Created, invitation.Expires)
+	return err
+}
+
+//GetInvitation - returns an invitation
+func (db *SQLStore) GetInvitation(ctx context.Context, id string) (*types.Invitation, error) {
+	invitations, err := db.getInvitations(ctx, "SELECT * FROM invitations WHERE id = ?", id)
+	if err != nil || len(*invitations) == 0 {
+		return nil, err
+	}
+	return &(*invitations)[0], nil
+}
+
+//GetInvitations - returns up to limit invitations, newest first
+func (db *SQLStore) GetInvitations(ctx context.Context, pending bool, limit int) (*[]types.Invitation, error) {
+	query := "SELECT * FROM invitations"
+	if pending {
+		query += " WHERE accepted IS NULL"
+	}
+	query += " ORDER BY created DESC LIMIT ?"
+
+	return db.getInvitations(ctx, query, limit)
+}
+
+//getInvitations - returns every invitation found by the query
+func (db *SQLStore) getInvitations(ctx context.Context, query string, args ...interface{}) (*[]types.Invitation, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []types.Invitation{}
+	for rows.Next() {
+		invitation := types.Invitation{}
+		err = sqlstruct.Scan(&invitation, rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return &invitations, rows.Err()
+}
+
+//AcceptInvitation - marks an invitation as used by the account. Returns false if it was already used
+func (db *SQLStore) AcceptInvitation(ctx context.Context, id string, accountID string, accepted time.Time) (bool, error) {
+	res, err := db.Exec(ctx, "UPDATE invitations SET accepted = ?, accountId = ? WHERE id = ? AND accepted IS NULL", accepted, accountID, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+//DeleteInvitation - removes an invitation
+func (db *SQLStore) DeleteInvitation(ctx context.Context, id string) error {
+	_, err := db.Exec(ctx, "DELETE FROM invitations WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertLoginCode - saves a new login code
+func (db *SQLStore) InsertLoginCode(ctx context.Context, code *types.LoginCode) error {
+	_, err := db.Exec(ctx, "INSERT INTO logincodes (id, accountId, hash, attempts, created, expires) VALUES(?,?,?,?,?,?)",
+		code.ID, code.AccountID, code.Hash, code.Attempts, code.Created, code.Expires)
+	return err
+}
+
+//GetLoginCode - returns the login code of an account
+func (db *SQLStore) GetLoginCode(ctx context.Context, accountID string) (*types.LoginCode, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM logincodes WHERE accountId = ? ORDER BY created DESC", accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		code := types.LoginCode{}
+		err = sqlstruct.Scan(&code, rows)
+		if err != nil {
+			return nil, err
+		}
+		return &code, nil
+	}
+	return nil, rows.Err()
+}
+
+//AddLoginCodeAttempt - counts a try of a login code. Returns false if it is gone or has no attempts left
+func (db *SQLStore) AddLoginCodeAttempt(ctx context.Context, id string, max int) (bool, error) {
+	res, err := db.Exec(ctx, "UPDATE logincodes SET attempts = attempts + 1 WHERE id = ? AND attempts < ?", id, max)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+//UseLoginCode - deletes a login code as it is used. Returns false if it was already used
+func (db *SQLStore) UseLoginCode(ctx context.Context, id string) (bool, error) {
+	res, err := db.Exec(ctx, "DELETE FROM logincodes WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+//DeleteLoginCodes - removes every login code of an account
+func (db *SQLStore) DeleteLoginCodes(ctx context.Context, accountID string) error {
+	_, err := db.Exec(ctx, "DELETE FROM logincodes WHERE accountId = ?", accountID)
+	return err
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertMagicLink - saves a new magic link
+func (db *SQLStore) InsertMagicLink(ctx context.Context, link *types.MagicLink) error {
+	_, err := db.Exec(ctx, "INSERT INTO magiclinks (id, accountId, hash, nonceHash, created, expires) VALUES(?,?,?,?,?,?)",
+		link.ID, link.AccountID, link.Hash, link.NonceHash, link.Created, link.Expires)
+	return err
+}
+
+//GetMagicLink - returns a magic link
+func (db *SQLStore) GetMagicLink(ctx context.Context, id string) (*types.MagicLink, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM magiclinks WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		link := types.MagicLink{}
+		err = sqlstruct.Scan(&link, rows)
+		if err != nil {
+			return nil, err
+		}
+		return &link, nil
+	}
+	return nil, rows.Err()
+}
+
+//UseMagicLink - deletes a magic link as it is used. Returns false if it was already used
+func (db *SQLStore) UseMagicLink(ctx context.Context, id string) (bool, error) {
+	res, err := db.Exec(ctx, "DELETE FROM magiclinks WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+//DeleteMagicLinks - removes every magic link of an account
+func (db *SQLStore) DeleteMagicLinks(ctx context.Context, accountID string) error {
+	_, err := db.Exec(ctx, "DELETE FROM magiclinks WHERE accountId = ?", accountID)
+	return err
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"time"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertEmail - queues an email
+func (db *SQLStore) InsertEmail(ctx context.Context, email *types.OutboxEmail) error {
+	_, err := db.Exec(ctx, "INSERT INTO outbox (id, accountId, sender, recipient, subject, body, textBody, status, attempts, nextAttempt, lastError, created, updated) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		email.ID, email.AccountID, email.From, email.To, email.Subject, email.Body, email.Text, email.Status, email.Attempts, email.NextAttempt, email.LastError, email.Created, email.Updated)
+	return err
+}
+
+//GetEmail - returns a queued email
+func (db *SQLStore) GetEmail(ctx context.Context, id string) (*types.OutboxEmail, error) {
+	emails, err := db.getEmails(ctx, "SELECT * FROM outbox WHERE id = ?", id)
+	if err != nil || len(*emails) == 0 {
+		return nil, err
+	}
+	return &(*emails)[0], nil
+}
+
+//GetEmails - returns the outbox newest first, optionally filtered by status
+func (db *SQLStore) GetEmails(ctx context.Context, status string, limit int) (*[]types.OutboxEmail, error) {
+	query := "SELECT * FROM outbox WHERE 1 = 1"
+	args := []interface{}{}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY created DESC LIMIT ?"
+	args = append(args, limit)
+
+	return db.getEmails(ctx, query, args...)
+}
+
+//GetDueEmails - returns pending emails whose next attempt is due
+func (db *SQLStore) GetDueEmails(ctx context.Context, now time.Time, limit int) (*[]types.OutboxEmail, error) {
+	return db.getEmails(ctx, "SELECT * FROM outbox WHERE status = ? AND nextAttempt <= ? ORDER BY nextAttempt ASC LIMIT ?",
+		types.EmailPending, now, limit)
+}
+
+//getEmails - returns the emails found by the query
+func (db *SQLStore) getEmails(ctx context.Context, query string, args ...interface{}) (*[]types.OutboxEmail, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []types.OutboxEmail{}
+	for rows.Next() {
+		email := types.OutboxEmail{}
+		err = sqlstruct.Scan(&email, rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return &emails, rows.Err()
+}
+
+//ClaimEmail - pushes a due email's next attempt out to lockUntil so no other worker picks it up.
+//Returns false if another worker claimed it first.
+func (db *SQLStore) ClaimEmail(ctx context.Context, email *types.OutboxEmail, lockUntil time.Time) (bool, error) {
+	res, err := db.Exec(ctx, "UPDATE outbox SET nextAttempt = ? WHERE id = ? AND status = ? AND nextAttempt = ?",
+		lockUntil, email.ID, types.EmailPending, email.NextAttempt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+//UpdateEmail - saves the result of a send attempt
+func (db *SQLStore) UpdateEmail(ctx context.Context, email *types.OutboxEmail) error {
+	_, err := db.Exec(ctx, "UPDATE outbox SET body = ?, textBody = ?, status = ?, attempts = ?, nextAttempt = ?, lastError = ?, updated = ? WHERE id = ?",
+		email.Body, email.Text, email.Status, email.Attempts, email.NextAttempt, email.LastError, email.Updated, email.ID)
+	return err
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"time"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertPersonalToken - saves a new personal access token
+func (db *SQLStore) InsertPersonalToken(ctx context.Context, token *types.PersonalToken) error {
+	_, err := db.Exec(ctx, "INSERT INTO personaltokens (id, accountId, name, hash, scopes, created, expires, lastUsed) VALUES(?,?,?,?,?,?,?,NULL)",
+		token.ID, token.AccountID, token.Name, token.Hash, token.Scope, token.Created, token.Expires)
+	return err
+}
+
+//GetPersonalToken - returns a personal access token
+func (db *SQLStore) GetPersonalToken(ctx context.Context, id string) (*types.PersonalToken, error) {
+	tokens, err := db.getPersonalTokens(ctx, "SELECT * FROM personaltokens WHERE id = ?", id)
+	if err != nil || len(*tokens) == 0 {
+		return nil, err
+	}
+	return &(*tokens)[0], nil
+}
+
+//GetPersonalTokens - returns the personal access tokens of an account, newest first
+func (db *SQLStore) GetPersonalTokens(ctx context.Context, accountID string) (*[]types.PersonalToken, error) {
+	return db.getPersonalTokens(ctx, "SELECT * FROM personaltokens WHERE accountId = ? ORDER BY created DESC", accountID)
+}
+
+//getPersonalTokens - returns every personal access token found by the query
+func (db *SQLStore) getPersonalTokens(ctx context.Context, query string, args ...interface{}) (*[]types.PersonalToken, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []types.PersonalToken{}
+	for rows.Next() {
+		token := types.PersonalToken{}
+		err = sqlstruct.Scan(&token, rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return &tokens, rows.Err()
+}
+
+//TouchPersonalToken - records when a personal access token was last used
+func (db *SQLStore) TouchPersonalToken(ctx context.Context, id string, used time.Time) error {
+	_, err := db.Exec(ctx, "UPDATE personaltokens SET lastUsed = ? WHERE id = ?", used, id)
+	return err
+}
+
+//DeletePersonalToken - removes a personal access token
+func (db *SQLStore) DeletePersonalToken(ctx context.Context, id string) error {
+	_, err := db.Exec(ctx, "DELETE FROM personaltokens WHERE id = ?", id)
+	return err
+}
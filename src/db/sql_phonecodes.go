@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"time"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertPhoneCode - saves a new phone code
+func (db *SQLStore) InsertPhoneCode(ctx context.Context, code *types.PhoneCode) error {
+	_, err := db.Exec(ctx, "INSERT INTO phonecodes (id, accountId, phone, purpose, channel, hash, attempts, used, created, expires) VALUES(?,?,?,?,?,?,?,?,?,?)",
+		code.ID, code.AccountID, code.Phone, code.Purpose, code.Channel, code.Hash, code.Attempts, code.Used, code.Created, code.Expires)
+	return err
+}
+
+//GetPhoneCode - returns the newest unused code of an account for a purpose
+func (db *SQLStore) GetPhoneCode(ctx context.Context, accountID string, purpose string) (*types.PhoneCode, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM phonecodes WHERE accountId = ? AND purpose = ? AND used = ? ORDER BY created DESC", accountID, purpose, false)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		code := types.PhoneCode{}
+		err = sqlstruct.Scan(&code, rows)
+		if err != nil {
+			return nil, err
+		}
+		return &code, nil
+	}
+	return nil, rows.Err()
+}
+
+//CountPhoneCodes - returns how many codes have been sent to a number since the time given
+func (db *SQLStore) CountPhoneCodes(ctx context.Context, phone string, since time.Time) (int, error) {
+	rows, err := db.Query(ctx, "SELECT COUNT(*) FROM phonecodes WHERE phone = ? AND created >= ?", phone, since)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, rows.Err()
+}
+
+//AddPhoneCodeAttempt - counts a try of a phone code. Returns false if it is used or has no attempts left
+func (db *SQLStore) AddPhoneCodeAttempt(ctx context.Context, id string, max int) (bool, error) {
+	res, err := db.Exec(ctx, "UPDATE phonecodes SET attempts = attempts + 1 WHERE id = ? AND used = ? AND attempts < ?", id, false, max)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+//UsePhoneCode - marks a phone code used. Returns false if it already was
+func (db *SQLStore) UsePhoneCode(ctx context.Context, id string) (bool, error) {
+	res, err := db.Exec(ctx, "UPDATE phonecodes SET used = ? WHERE id = ? AND used = ?", true, id, false)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
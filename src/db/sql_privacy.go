@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertErasureRequest - saves a new erasure request
+func (db *SQLStore) InsertErasureRequest(ctx context.Context, request *types.ErasureRequest) error {
+	_, err := db.Exec(ctx, "INSERT INTO erasurerequests (id, accountId, token, status, note, handledBy, created, updated) VALUES(?,?,?,?,?,?,?,?)",
+		request.ID, request.AccountID, request.Token, request.Status, request.Note, request.HandledBy, request.Created, request.Updated)
+	return err
+}
+
+//GetErasureRequest - returns an erasure request
+func (db *SQLStore) GetErasureRequest(ctx context.Context, id string) (*types.ErasureRequest, error) {
+	requests, err := db.getErasureRequests(ctx, "SELECT * FROM erasurerequests WHERE id = ?", id)
+	if err != nil || len(*requests) == 0 {
+		return nil, err
+	}
+	return &(*requests)[0], nil
+}
+
+//GetErasureRequests - returns up to limit erasure requests, newest first
+func (db *SQLStore) GetErasureRequests(ctx context.Context, accountID string, status string, limit int) (*[]types.ErasureRequest, error) {
+	query := "SELECT * FROM erasurerequests WHERE 1 = 1"
+	args := []interface{}{}
+
+	if accountID != "" {
+		query += " AND accountId = ?"
+		args = append(args, accountID)
+	}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	query += " ORDER BY created DESC LIMIT ?"
+	args = append(args, limit)
+
+	return db.getErasureRequests(ctx, query, args...)
+}
+
+//getErasureRequests - returns every erasure request found by the query
+func (db *SQLStore) getErasureRequests(ctx context.Context, query string, args ...interface{}) (*[]types.ErasureRequest, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []types.ErasureRequest{}
+	for rows.Next() {
+		request := types.ErasureRequest{}
+		err = sqlstruct.Scan(&request, rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return &requests, rows.Err()
+}
+
+//UpdateErasureRequest - saves the status of an erasure request
+func (db *SQLStore) UpdateErasureRequest(ctx context.Context, request *types.ErasureRequest) error {
+	_, err := db.Exec(ctx, "UPDATE erasurerequests SET status = ?, note = ?, handledBy = ?, updated = ? WHERE id = ?",
+		request.Status, request.Note, request.HandledBy, request.Updated, request.ID)
+	return err
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertRecovery - saves a new recovery
+func (db *SQLStore) InsertRecovery(ctx context.Context, recovery *types.Recovery) error {
+	_, err := db.Exec(ctx, "INSERT INTO recover (id, accountId, created, email) VALUES(?,?,?,?)",
+		recovery.ID, recovery.AccountID, recovery.Created, recovery.Email)
+	return err
+}
+
+//GetRecovery - returns a recovery
+func (db *SQLStore) GetRecovery(ctx context.Context, id string) (*types.Recovery, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM recover WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rec := types.Recovery{}
+		err = sqlstruct.Scan(&rec, rows)
+		if err != nil {
+			return nil, err
+		}
+		return &rec, nil
+	}
+	return nil, rows.Err()
+}
+
+//DeleteRecovery - deletes a recovery. Returns false if it was already gone
+func (db *SQLStore) DeleteRecovery(ctx context.Context, id string) (bool, error) {
+	res, err := db.Exec(ctx, "DELETE FROM recover WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
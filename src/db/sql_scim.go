@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"time"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertSCIMToken - saves a new SCIM token
+func (db *SQLStore) InsertSCIMToken(ctx context.Context, token *types.SCIMToken) error {
+	_, err := db.Exec(ctx, "INSERT INTO scimtokens (id, organization, hash, description, manageAdmins, created, lastUsed) VALUES(?,?,?,?,?,?,NULL)",
+		token.ID, token.Organization, token.Hash, token.Description, token.ManageAdmins, token.Created)
+	return err
+}
+
+//GetSCIMToken - returns a SCIM token
+func (db *SQLStore) GetSCIMToken(ctx context.Context, id string) (*types.SCIMToken, error) {
+	tokens, err := db.getSCIMTokens(ctx, "SELECT * FROM scimtokens WHERE id = ?", id)
+	if err != nil || len(*tokens) == 0 {
+		return nil, err
+	}
+	return &(*tokens)[0], nil
+}
+
+//GetSCIMTokens - returns every SCIM token, newest first
+func (db *SQLStore) GetSCIMTokens(ctx context.Context) (*[]types.SCIMToken, error) {
+	return db.getSCIMTokens(ctx, "SELECT * FROM scimtokens ORDER BY created DESC")
+}
+
+//getSCIMTokens - returns every SCIM token found by the query
+func (db *SQLStore) getSCIMTokens(ctx context.Context, query string, args ...interface{}) (*[]types.SCIMToken, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []types.SCIMToken{}
+	for rows.Next() {
+		token := types.SCIMToken{}
+		err = sqlstruct.Scan(&token, rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return &tokens, rows.Err()
+}
+
+//TouchSCIMToken - records when a SCIM token was last used
+func (db *SQLStore) TouchSCIMToken(ctx context.Context, id string, used time.Time) error {
+	_, err := db.Exec(ctx, "UPDATE scimtokens SET lastUsed = ? WHERE id = ?", used, id)
+	return err
+}
+
+//DeleteSCIMToken - removes a SCIM token
+func (db *SQLStore) DeleteSCIMToken(ctx context.Context, id string) error {
+	_, err := db.Exec(ctx, "DELETE FROM scimtokens WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertRefreshToken - saves a refresh token
+func (db *SQLStore) InsertRefreshToken(ctx context.Context, token *types.RefreshToken) error {
+	_, err := db.Exec(ctx, "INSERT INTO refreshtokens (id, accountId, deviceId, created, firstIp, lastIp, userAgent, browser, os, country, location, lastSeen) VALUES(?,?,?,?,?,?,?,?,?,?,?,?)",
+		token.ID, token.AccountID, token.DeviceID, token.Created, token.FirstIP, token.LastIP, token.UserAgent, token.Browser, token.OS, token.Country, token.Location, token.LastSeen)
+	return err
+}
+
+//GetRefreshToken - returns a refresh token
+func (db *SQLStore) GetRefreshToken(ctx context.Context, id string) (*types.RefreshToken, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM refreshtokens WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		token := types.RefreshToken{}
+		err = sqlstruct.Scan(&token, rows)
+		if err != nil {
+			return nil, err
+		}
+		return &token, nil
+	}
+	return nil, rows.Err()
+}
+
+//DeleteRefreshToken - deletes a refresh token
+func (db *SQLStore) DeleteRefreshToken(ctx context.Context, id string) error {
+	_, err := db.Exec(ctx, "DELETE FROM refreshtokens WHERE id = ?", id)
+	return err
+}
+
+//TouchRefreshToken - saves the client a session was last used from
+func (db *SQLStore) TouchRefreshToken(ctx context.Context, token *types.RefreshToken) error {
+	_, err := db.Exec(ctx, "UPDATE refreshtokens SET firstIp = ?, lastIp = ?, userAgent = ?, browser = ?, os = ?, country = ?, location = ?, lastSeen = ? WHERE id = ?",
+		token.FirstIP, token.LastIP, token.UserAgent, token.Browser, token.OS, token.Country, token.Location, token.LastSeen, token.ID)
+	return err
+}
+
+//GetRefreshTokensByAccount - returns every refresh token of an account, oldest first
+func (db *SQLStore) GetRefreshTokensByAccount(ctx context.Context, accountID string) (*[]types.RefreshToken, error) {
+	rows, err := db.Query(ctx, "SELECT * FROM refreshtokens WHERE accountId = ? ORDER BY created ASC", accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []types.RefreshToken{}
+	for rows.Next() {
+		token := types.RefreshToken{}
+		err = sqlstruct.Scan(&token, rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return &tokens, rows.Err()
+}
+
+//DeleteRefreshTokensByAccount - deletes every refresh token of an account
+func (db *SQLStore) DeleteRefreshTokensByAccount(ctx context.Context, accountID string) error {
+	_, err := db.Exec(ctx, "DELETE FROM refreshtokens WHERE accountId = ?", accountID)
+	return err
+}
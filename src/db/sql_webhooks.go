@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"time"
+	"types"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+//InsertSubscription - saves a new webhook subscription
+func (db *SQLStore) InsertSubscription(ctx context.Context, sub *types.WebhookSubscription) error {
+	_, err := db.Exec(ctx, "INSERT INTO webhooks (id, url, secret, events, active, created) VALUES(?,?,?,?,?,?)",
+		sub.ID, sub.URL, sub.Secret, sub.Events, sub.Active, sub.Created)
+	return err
+}
+
+//GetSubscription - returns a webhook subscription
+func (db *SQLStore) GetSubscription(ctx context.Context, id string) (*types.WebhookSubscription, error) {
+	subs, err := db.getSubscriptions(ctx, "SELECT * FROM webhooks WHERE id = ?", id)
+	if err != nil || len(*subs) == 0 {
+		return nil, err
+	}
+	return &(*subs)[0], nil
+}
+
+//GetSubscriptions - returns every webhook subscription
+func (db *SQLStore) GetSubscriptions(ctx context.Context) (*[]types.WebhookSubscription, error) {
+	return db.getSubscriptions(ctx, "SELECT * FROM webhooks ORDER BY created ASC")
+}
+
+//getSubscriptions - returns the subscriptions found by the query
+func (db *SQLStore) getSubscriptions(ctx context.Context, query string, args ...interface{}) (*[]types.WebhookSubscription, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []types.WebhookSubscription{}
+	for rows.Next() {
+		sub := types.WebhookSubscription{}
+		err = sqlstruct.Scan(&sub, rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return &subs, rows.Err()
+}
+
+//DeleteSubscription - removes a subscription and its delivery log
+func (db *SQLStore) DeleteSubscription(ctx context.Context, id string) error {
+	return db.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM webhookdeliveries WHERE subscriptionId = ?", id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+		return err
+	})
+}
+
+//InsertDelivery - queues a webhook delivery
+func (db *SQLStore) InsertDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	_, err := db.Exec(ctx, "INSERT INTO webhookdeliveries (id, subscriptionId, accountId, event, payload, status, attempts, nextAttempt, responseCode, lastError, created, updated) VALUES(?,?,?,?,?,?,?,?,?,?,?,?)",
+		delivery.ID, delivery.SubscriptionID, delivery.AccountID, delivery.Event, delivery.Payload, delivery.Status, delivery.Attempts, delivery.NextAttempt, delivery.ResponseCode, delivery.LastError, delivery.Created, delivery.Updated)
+	return err
+}
+
+//GetDelivery - returns a webhook delivery
+func (db *SQLStore) GetDelivery(ctx context.Context, id string) (*types.WebhookDelivery, error) {
+	deliveries, err := db.getDeliveries(ctx, "SELECT * FROM webhookdeliveries WHERE id = ?", id)
+	if err != nil || len(*deliveries) == 0 {
+		return nil, err
+	}
+	return &(*deliveries)[0], nil
+}
+
+//GetDeliveries - returns the delivery log newest first, optionally filtered by subscription and status
+func (db *SQLStore) GetDeliveries(ctx context.Context, subscriptionID string, status string, limit int) (*[]types.WebhookDelivery, error) {
+	query := "SELECT * FROM webhookdeliveries WHERE 1 = 1"
+	args := []interface{}{}
+	if subscriptionID != "" {
+		query += " AND subscriptionId = ?"
+		args = append(args, subscriptionID)
+	}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY created DESC LIMIT ?"
+	args = append(args, limit)
+
+	return db.getDeliveries(ctx, query, args...)
+}
+
+//GetDueDeliveries - returns pending deliveries whose next attempt is due
+func (db *SQLStore) GetDueDeliveries(ctx context.Context, now time.Time, limit int) (*[]types.WebhookDelivery, error) {
+	return db.getDeliveries(ctx, "SELECT * FROM webhookdeliveries WHERE status = ? AND nextAttempt <= ? ORDER BY nextAttempt ASC LIMIT ?",
+		types.DeliveryPending, now, limit)
+}
+
+//getDeliveries - returns the deliveries found by the query
+func (db *SQLStore) getDeliveries(ctx context.Context, query string, args ...interface{}) (*[]types.WebhookDelivery, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	for rows.Next() {
+		delivery := types.WebhookDelivery{}
+		err = sqlstruct.Scan(&delivery, rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return &deliveries, rows.Err()
+}
+
+//ClaimDelivery - pushes a due delivery's next attempt out to lockUntil so no other worker picks it up.
+//Returns false if another worker claimed it first.
+func (db *SQLStore) ClaimDelivery(ctx context.Context, delivery *types.WebhookDelivery, lockUntil time.Time) (bool, error) {
+	res, err := db.Exec(ctx, "UPDATE webhookdeliveries SET nextAttempt = ? WHERE id = ? AND status = ? AND nextAttempt = ?",
+		lockUntil, delivery.ID, types.DeliveryPending, delivery.NextAttempt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+//UpdateDelivery - saves the result of a delivery attempt
+func (db *SQLStore) UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	_, err := db.Exec(ctx, "UPDATE webhookdeliveries SET status = ?, attempts = ?, nextAttempt = ?, responseCode = ?, lastError = ?, updated = ? WHERE id = ?",
+		delivery.Status, delivery.Attempts, delivery.NextAttempt, delivery.ResponseCode, delivery.LastError, delivery.Updated, delivery.ID)
+	return err
+}
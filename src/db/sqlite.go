@@ -0,0 +1,35 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3" //SQLite driver
+)
+
+//SQLite - SQLite database connector for single binary deployments
+type SQLite struct {
+}
+
+//Init - Opens the SQLite database file at SQLITE_PATH
+func (db SQLite) Init() (*SQLStore, error) {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = "./auth.db"
+	}
+
+	pool, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+
+	//SQLite only allows one writer, queue everything through a single connection instead of failing with "database is locked"
+	pool.SetMaxOpenConns(1)
+
+	if pool.Ping() != nil {
+		return nil, errors.New("Cannot open database: " + path)
+	}
+
+	return SQLStore{}.Init(pool, SQLiteDialect), nil
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+	"types"
+	"utils"
+)
+
+//AccountRepository - stores accounts
+type AccountRepository interface {
+	EmailTaken(ctx context.Context, email string, excludeID string) (bool, error)
+	InsertAccount(ctx context.Context, account *types.Account) error
+	GetAccountByID(ctx context.Context, id string) (*types.Account, error)
+	GetAccountByEmail(ctx context.Context, email string) (*types.Account, error)
+	//SearchAccounts - returns up to limit accounts matching the search in its sort order. If after is set only accounts past it are returned
+	SearchAccounts(ctx context.Context, search *types.AccountSearchRequest, after *types.Account, limit int) (*[]types.Account, error)
+	//CountAccounts - returns how many accounts match the search, ignoring paging
+	CountAccounts(ctx context.Context, search *types.AccountSearchRequest) (int, error)
+	UpdateSettings(ctx context.Context, account *types.Account) error
+	UpdateAccount(ctx context.Context, account *types.Account) error
+	UpdatePassword(ctx context.Context, id string, password string) error
+	SetDisabled(ctx context.Context, id string, disabled bool, reason string) error
+	SetVerifiedPhone(ctx context.Context, id string, phone string) error
+	//SetDeleted - soft deletes an account at the time given, or restores it when deleted is nil
+	SetDeleted(ctx context.Context, id string, deleted *time.Time) error
+	//GetDeletedBefore - returns up to limit accounts soft deleted before the time given
+	GetDeletedBefore(ctx context.Context, before time.Time, limit int) (*[]types.Account, error)
+	//DeleteAccount - removes an account along with its devices, refresh tokens, recoveries, personal access tokens, impersonations, queued emails and the invitation it registered with
+	DeleteAccount(ctx context.Context, id string) error
+	//AnonymizeAccount - overwrites the personal details of an account with those given and removes its devices, refresh tokens, recoveries, personal access tokens, impersonations, queued emails and invitation
+	AnonymizeAccount(ctx context.Context, account *types.Account) error
+}
+
+//DeviceRepository - stores login devices
+type DeviceRepository interface {
+	GetDevice(ctx context.Context, id string) (*types.Device, error)
+	InsertDevice(ctx context.Context, device *types.Device) error
+	ActivateDevice(ctx context.Context, id string) error
+	//TouchDevice - saves the client a device was last used from
+	TouchDevice(ctx context.Context, device *types.Device) error
+	GetDevicesByAccount(ctx context.Context, accountID string) (*[]types.Device, error)
+}
+
+//TokenRepository - stores refresh tokens
+type TokenRepository interface {
+	InsertRefreshToken(ctx context.Context, token *types.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id string) (*types.RefreshToken, error)
+	DeleteRefreshToken(ctx context.Context, id string) error
+	//TouchRefreshToken - saves the client a session was last used from
+	TouchRefreshToken(ctx context.Context, token *types.RefreshToken) error
+	GetRefreshTokensByAccount(ctx context.Context, accountID string) (*[]types.RefreshToken, error)
+	//DeleteRefreshTokensByAccount - deletes every refresh token of an account, ending its sessions
+	DeleteRefreshTokensByAccount(ctx context.Context, accountID string) error
+}
+
+//RecoveryRepository - stores account recoveries
+type RecoveryRepository interface {
+	InsertRecovery(ctx context.Context, recovery *types.Recovery) error
+	GetRecovery(ctx context.Context, id string) (*types.Recovery, error)
+	DeleteRecovery(ctx context.Context, id string) (bool, error)
+}
+
+//AuditRepository - stores audit events
+type AuditRepository interface {
+	InsertEvent(ctx context.Context, event *types.AuditEvent) error
+	//SearchEvents - returns up to limit events newest first. If after is set only events older than it are returned
+	SearchEvents(ctx context.Context, search *types.AuditSearchRequest, after *types.AuditEvent, limit int) (*[]types.AuditEvent, error)
+	//ScrubEvents - blanks the email, client and detail of every event about the account or email
+	ScrubEvents(ctx context.Context, accountID string, email string) error
+}
+
+//PrivacyRepository - stores erasure requests
+type PrivacyRepository interface {
+	InsertErasureRequest(ctx context.Context, request *types.ErasureRequest) error
+	GetErasureRequest(ctx context.Context, id string) (*types.ErasureRequest, error)
+	//GetErasureRequests - returns up to limit requests newest first. Empty accountID or status match every request
+	GetErasureRequests(ctx context.Context, accountID string, status string, limit int) (*[]types.ErasureRequest, error)
+	UpdateErasureRequest(ctx context.Context, request *types.ErasureRequest) error
+}
+
+//InvitationRepository - stores invitations to register
+type InvitationRepository interface {
+	InsertInvitation(ctx context.Context, invitation *types.Invitation) error
+	GetInvitation(ctx context.Context, id string) (*types.Invitation, error)
+	//GetInvitations - returns up to limit invitations newest first. pending leaves out accepted invitations
+	GetInvitations(ctx context.Context, pending bool, limit int) (*[]types.Invitation, error)
+	//AcceptInvitation - marks an invitation as used by the account. Returns false if it was already used
+	AcceptInvitation(ctx context.Context, id string, accountID string, accepted time.Time) (bool, error)
+	DeleteInvitation(ctx context.Context, id string) error
+}
+
+//SCIMRepository - stores the tokens SCIM clients authenticate with
+type SCIMRepository interface {
+	InsertSCIMToken(ctx context.Context, token *types.SCIMToken) error
+	GetSCIMToken(ctx context.Context, id string) (*types.SCIMToken, error)
+	GetSCIMTokens(ctx context.Context) (*[]types.SCIMToken, error)
+	//TouchSCIMToken - records when a token was last used
+	TouchSCIMToken(ctx context.Context, id string, used time.Time) error
+	DeleteSCIMToken(ctx context.Context, id string) error
+}
+
+//PersonalTokenRepository - stores personal access tokens
+type PersonalTokenRepository interface {
+	InsertPersonalToken(ctx context.Context, token *types.PersonalToken) error
+	GetPersonalToken(ctx context.Context, id string) (*types.PersonalToken, error)
+	//GetPersonalTokens - returns the tokens of an account newest first
+	GetPersonalTokens(ctx context.Context, accountID string) (*[]types.PersonalToken, error)
+	//TouchPersonalToken - records when a token was last used
+	TouchPersonalToken(ctx context.Context, id string, used time.Time) error
+	DeletePersonalToken(ctx context.Context, id string) error
+}
+
+//OutboxRepository - stores emails waiting to be sent and the ones that were
+type OutboxRepository interface {
+	InsertEmail(ctx context.Context, email *types.OutboxEmail) error
+	GetEmail(ctx context.Context, id string) (*types.OutboxEmail, error)
+	//GetEmails - returns up to limit emails newest first, all of them when status is empty
+	GetEmails(ctx context.Context, status string, limit int) (*[]types.OutboxEmail, error)
+	GetDueEmails(ctx context.Context, now time.Time, limit int) (*[]types.OutboxEmail, error)
+	//ClaimEmail - pushes a due email's next attempt out to lockUntil. Returns false if another worker claimed it first
+	ClaimEmail(ctx context.Context, email *types.OutboxEmail, lockUntil time.Time) (bool, error)
+	UpdateEmail(ctx context.Context, email *types.OutboxEmail) error
+}
+
+//ImpersonationRepository - stores admin impersonations
+type ImpersonationRepository interface {
+	InsertImpersonation(ctx context.Context, impersonation *types.Impersonation) error
+	GetImpersonation(ctx context.Context, id string) (*types.Impersonation, error)
+	//StopImpersonation - records when an impersonation was stopped early
+	StopImpersonation(ctx context.Context, id string, stopped time.Time) error
+	//StopImpersonationsByAccount - stops every running impersonation an account is doing or is the target of
+	StopImpersonationsByAccount(ctx context.Context, accountID string, stopped time.Time) error
+}
+
+//MagicLinkRepository - stores magic login links
+type MagicLinkRepository interface {
+	InsertMagicLink(ctx context.Context, link *types.MagicLink) error
+	GetMagicLink(ctx context.Context, id string) (*types.MagicLink, error)
+	//UseMagicLink - deletes a magic link as it is used, returns false if it was already used
+	UseMagicLink(ctx context.Context, id string) (bool, error)
+	DeleteMagicLinks(ctx context.Context, accountID string) error
+}
+
+//LoginCodeRepository - stores emailed login codes
+type LoginCodeRepository interface {
+	InsertLoginCode(ctx context.Context, code *types.LoginCode) error
+	GetLoginCode(ctx context.Context, accountID string) (*types.LoginCode, error)
+	//AddLoginCodeAttempt - counts a try of a login code, returns false if it is gone or has no attempts left
+	AddLoginCodeAttempt(ctx context.Context, id string, max int) (bool, error)
+	//UseLoginCode - deletes a login code as it is used, returns false if it was already used
+	UseLoginCode(ctx context.Context, id string) (bool, error)
+	DeleteLoginCodes(ctx context.Context, accountID string) error
+}
+
+//PhoneCodeRepository - stores codes sent by text message or phone call
+type PhoneCodeRepository interface {
+	InsertPhoneCode(ctx context.Context, code *types.PhoneCode) error
+	//GetPhoneCode - returns the newest unused code of an account for a purpose
+	GetPhoneCode(ctx context.Context, accountID string, purpose string) (*types.PhoneCode, error)
+	//CountPhoneCodes - returns how many codes have been sent to a number since the time given
+	CountPhoneCodes(ctx context.Context, phone string, since time.Time) (int, error)
+	//AddPhoneCodeAttempt - counts a try of a phone code, returns false if it is used or has no attempts left
+	AddPhoneCodeAttempt(ctx context.Context, id string, max int) (bool, error)
+	//UsePhoneCode - marks a phone code used, returns false if it already was
+	UsePhoneCode(ctx context.Context, id string) (bool, error)
+}
+
+//WebhookRepository - stores webhook subscriptions and their delivery queue
+type WebhookRepository interface {
+	InsertSubscription(ctx context.Context, sub *types.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id string) (*types.WebhookSubscription, error)
+	GetSubscriptions(ctx context.Context) (*[]types.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	InsertDelivery(ctx context.Context, delivery *types.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*types.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, subscriptionID string, status string, limit int) (*[]types.WebhookDelivery, error)
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) (*[]types.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, delivery *types.WebhookDelivery, lockUntil time.Time) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error
+}
+
+//Store - everything the service keeps in a database
+type Store interface {
+	AccountRepository
+	DeviceRepository
+	TokenRepository
+	RecoveryRepository
+	AuditRepository
+	WebhookRepository
+	PrivacyRepository
+	InvitationRepository
+	SCIMRepository
+	PersonalTokenRepository
+	ImpersonationRepository
+	OutboxRepository
+	MagicLinkRepository
+	LoginCodeRepository
+	PhoneCodeRepository
+
+	//InTx - runs fn as one unit of work. Everything fn does through tx is committed if it returns nil and rolled back otherwise.
+	//fn must only use tx, and calls made while already in a transaction join it
+	InTx(ctx context.Context, fn func(tx Store) error) error
+
+	//DeleteExpired - removes all expired recoveries, devices, refresh tokens, unconfirmed erasure requests, unused invitations, personal access tokens, impersonations and old outbox emails
+	DeleteExpired()
+}
+
+//Open - connects to the database selected by DB_DRIVER, brings the schema up to date and starts removing expired data. Defaults to mysql
+func Open() (Store, error) {
+	if os.Getenv("DB_DRIVER") == "memory" {
+		store := Memory{}.Init()
+
+		//Setup interval to remove expired data
+		utils.Schedule(store.DeleteExpired, 1*time.Hour)
+
+		return store, nil
+	}
+
+	store, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	//Migrate on start unless turned off. Either way never run against a schema this binary doesnt know
+	if os.Getenv("DB_AUTO_MIGRATE") != "false" {
+		err = store.Migrate(false, os.Stderr)
+	} else {
+		err = store.CheckSchema()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	//Setup interval to remove expired data
+	utils.Schedule(store.DeleteExpired, 1*time.Hour)
+
+	return store, nil
+}
+
+//Connect - connects to the SQL database selected by DB_DRIVER without touching the schema
+func Connect() (*SQLStore, error) {
+	switch os.Getenv("DB_DRIVER") {
+	case "", "mysql":
+		return MySQL{}.Init()
+	case "postgres":
+		return Postgres{}.Init()
+	case "sqlite":
+		return SQLite{}.Init()
+	default:
+		return nil, errors.New("No SQL database for DB_DRIVER: " + os.Getenv("DB_DRIVER"))
+	}
+}
+
+//refreshTokenDays - how many days refresh tokens are kept for
+func refreshTokenDays() int {
+	days, err := strconv.Atoi(os.Getenv("TOKENS_REFRESH_TOKEN_DURATION"))
+	if err != nil {
+		return 60
+	}
+	return days
+}
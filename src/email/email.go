@@ -1,350 +1,150 @@
 package email
 
 import (
+	"context"
+	"dao"
+	"db"
+	"net/mail"
+	"net/url"
 	"os"
-	"strconv"
+	"time"
 	"types"
-
-	"gopkg.in/gomail.v2"
 )
 
 //Emailer - emailer struct
 type Emailer struct {
-	Email       string
-	Username    string
-	Password    string
-	SMTPAddress string
-	SMTPPort    int
-	Host        string
+	Email     string
+	Host      string
+	Transport Transport
+	Renderer  *Renderer
 }
 
-//Init - start email service
-func (e Emailer) Init() *Emailer {
-
-	defaultPort, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+//Init - start email service with the transport selected by EMAIL_TRANSPORT. Emails are queued in the outbox and sent by Outbox
+func (e Emailer) Init() (*Emailer, error) {
+	transport, err := NewTransport()
+	if err != nil {
+		return nil, err
+	}
+	renderer, err := Renderer{}.Init()
 	if err != nil {
-		defaultPort = 587
+		return nil, err
 	}
 
 	e.Email = os.Getenv("EMAIL_ADDRESS")
-	e.Username = os.Getenv("SMTP_USERNAME")
-	e.Password = os.Getenv("SMTP_PASSWORD")
-	e.SMTPAddress = os.Getenv("SMTP_HOST")
-	e.SMTPPort = defaultPort
 	e.Host = os.Getenv("HOST")
-	return &e
+	e.Transport = transport
+	e.Renderer = renderer
+	return &e, nil
 }
 
-//NewDeviceEmail - send the account email a new device code
-func (e Emailer) NewDeviceEmail(account *types.Account, device *types.Device) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", e.Email)
-	m.SetHeader("To", account.Email)
-	m.SetHeader("Subject", "New Device Activation")
-	m.SetBody("text/html", e.getTemplate("Your new device code is: <b>"+device.Code+"</b>", "New Login Device", e.Host))
+//render - renders an email in the recipients language and organization branding, from the sender name of the branding
+func (e Emailer) render(name string, language string, organization string, data *Data) (*Message, error) {
+	data.Brand = e.Renderer.Brand(organization)
+	data.Host = e.Host
+
+	message, err := e.Renderer.Render(name, language, data)
+	if err != nil {
+		return nil, err
+	}
 
-	d := gomail.NewDialer(e.SMTPAddress, e.SMTPPort, e.Username, e.Password)
+	message.From = e.Email
+	if data.Brand.Name != "" {
+		message.From = (&mail.Address{Name: data.Brand.Name, Address: e.Email}).String()
+	}
+	message.To = []string{data.Email}
+	return message, nil
+}
 
-	if err := d.DialAndSend(m); err != nil {
+//queue - renders an email and queues it to be sent by the outbox worker
+func (e Emailer) queue(ctx context.Context, accountID string, name string, language string, organization string, data *Data, db db.Store) error {
+	message, err := e.render(name, language, organization, data)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return dao.OutboxDAO{}.Enqueue(ctx, &types.OutboxEmail{
+		AccountID: accountID,
+		From:      message.From,
+		To:        data.Email,
+		Subject:   message.Subject,
+		Body:      message.HTML,
+		Text:      message.Text,
+	}, db)
 }
 
-//RecoverAccount - send a recovery email to the given account
-func (e Emailer) RecoverAccount(recovery *types.Recovery) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", e.Email)
-	m.SetHeader("To", recovery.Email)
-	m.SetHeader("Subject", "Password Reset")
-	m.SetBody("text/html", e.getTemplate("Email: <b>"+recovery.Email+"</b><br/><br/>To reset your password <a href='"+e.Host+"/complete/recovery/"+recovery.ID+"'>Click Here</a>", "Recover Account", e.Host))
+//MagicLink - queue the account a single use login link
+func (e Emailer) MagicLink(ctx context.Context, account *types.Account, link *types.MagicLink, token string, db db.Store) error {
+	return e.queue(ctx, account.ID, TemplateMagicLink, account.Language, account.Organization, &Data{Email: account.Email, Name: account.FirstName, Link: e.Host + "/complete/magic/" + link.ID + "?token=" + url.QueryEscape(token), Expires: link.Expires}, db)
+}
 
-	d := gomail.NewDialer(e.SMTPAddress, e.SMTPPort, e.Username, e.Password)
+//LoginCode - queue the account a code to log in with
+func (e Emailer) LoginCode(ctx context.Context, account *types.Account, code *types.LoginCode, secret string, db db.Store) error {
+	return e.queue(ctx, account.ID, TemplateLoginCode, account.Language, account.Organization, &Data{Email: account.Email, Name: account.FirstName, Code: secret, Expires: code.Expires}, db)
+}
 
-	if err := d.DialAndSend(m); err != nil {
-		return err
+//Preview - renders an email without queueing it. Values not given are filled with samples
+func (e Emailer) Preview(name string, language string, organization string, data *Data) (*Message, error) {
+	if data.Email == "" {
+		data.Email = "jane.doe@example.com"
+	}
+	if data.Name == "" {
+		data.Name = "Jane"
+	}
+	if data.Link == "" {
+		data.Link = e.Host + "/complete/" + name + "/sample"
+	}
+	if data.Code == "" {
+		data.Code = "123456"
+	}
+	if data.Expires.IsZero() {
+		data.Expires = time.Now().Add(7 * 24 * time.Hour)
+	}
+	if data.IP == "" {
+		data.IP = "203.0.113.7"
+	}
+	if data.Browser == "" {
+		data.Browser = "Firefox 118"
+	}
+	if data.OS == "" {
+		data.OS = "Windows"
+	}
+	if data.Location == "" {
+		data.Location = "Paris, Ile-de-France, FR"
 	}
 
-	return nil
+	return e.render(name, language, organization, data)
 }
 
-//ChangeEmail - send a request to change email
-// func (e Emailer) ChangeEmail(emailRequest *types.EmailChange) error {
-// 	m := gomail.NewMessage()
-// 	m.SetHeader("From", e.Email)
-// 	m.SetHeader("To", emailRequest.NewEmail)
-// 	m.SetHeader("Subject", "Change Account Email")
-// 	m.SetBody("text/html", e.getTemplate("To Change your email <a href='"+e.Host+"/changeEmail?id="+emailRequest.ID+"'>Click Here</a>", "Change Account Email", e.Host))
+//SendTest - sends a rendered email to an address right away through the transport, skipping the outbox
+func (e Emailer) SendTest(message *Message, to string) error {
+	message.To = []string{to}
+	message.Subject = "[Test] " + message.Subject
+	return e.Transport.Send(message)
+}
+
+//NewDeviceEmail - queue the account email a new device code with where the device was used from
+func (e Emailer) NewDeviceEmail(ctx context.Context, account *types.Account, device *types.Device, db db.Store) error {
+	data := &Data{Email: account.Email, Name: account.FirstName, Code: device.Code, IP: device.LastIP, Browser: device.Browser, OS: device.OS, Location: device.Location}
+	return e.queue(ctx, account.ID, TemplateDevice, account.Language, account.Organization, data, db)
+}
 
-// 	d := gomail.NewDialer(e.SMTPAddress, e.SMTPPort, e.Email, e.Password)
+//LoginAlert - queue the account a warning about an unusual login, and whether it was blocked
+func (e Emailer) LoginAlert(ctx context.Context, account *types.Account, client *types.ClientDetails, blocked bool, db db.Store) error {
+	data := &Data{Email: account.Email, Name: account.FirstName, IP: client.IP, Browser: client.Browser, OS: client.OS, Location: client.Location, Blocked: blocked}
+	return e.queue(ctx, account.ID, TemplateLoginAlert, account.Language, account.Organization, data, db)
+}
 
-// 	if err := d.DialAndSend(m); err != nil {
-// 		return err
-// 	}
+//RecoverAccount - queue a recovery email to the given account
+func (e Emailer) RecoverAccount(ctx context.Context, account *types.Account, recovery *types.Recovery, db db.Store) error {
+	return e.queue(ctx, account.ID, TemplateRecovery, account.Language, account.Organization, &Data{Email: recovery.Email, Name: account.FirstName, Link: e.Host + "/complete/recovery/" + recovery.ID}, db)
+}
 
-// 	return nil
-// }
+//ConfirmErasure - queue the account a link to confirm it wants its data erased
+func (e Emailer) ConfirmErasure(ctx context.Context, account *types.Account, request *types.ErasureRequest, db db.Store) error {
+	return e.queue(ctx, account.ID, TemplateErasure, account.Language, account.Organization, &Data{Email: account.Email, Name: account.FirstName, Link: e.Host + "/complete/erasure/" + request.ID + "?token=" + url.QueryEscape(request.Token)}, db)
+}
 
-func (e Emailer) getTemplate(body string, title string, domain string) string {
-	return `
-	<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
-        <html xmlns="http://www.w3.org/1999/xhtml">
-        <head>
-            <title>
-            </title>
-            <meta http-equiv="Content-Type" content="text/html; charset=utf-8">
-            <meta name="viewport" content="width=device-width">
-            <link rel="stylesheet" href="https://use.fontawesome.com/releases/v5.7.2/css/all.css" integrity="sha384-fnmOCqbTlWIlj8LyTjo7mOUStjsKC4pOpQbqyi7RrhN7udi9RwhKkMHpvLbHG9Sr" crossorigin="anonymous">
-            <style type="text/css">body, html {
-            margin: 0px;
-            padding: 0px;
-            -webkit-font-smoothing: antialiased;
-            text-size-adjust: none;
-            width: 100% !important;
-            }
-            table td, table {
-            }
-            #outlook a {
-                padding: 0px;
-            }
-            .ExternalClass, .ExternalClass p, .ExternalClass span, .ExternalClass font, .ExternalClass td, .ExternalClass div {
-                line-height: 100%;
-            }
-            .ExternalClass {
-                width: 100%;
-            }
-            @media only screen and (max-width: 480px) {
-                table, table tr td, table td {
-                width: 100% !important;
-                }
-                img {
-                width: inherit;
-                }
-                .layer_2 {
-                max-width: 100% !important;
-                }
-                .edsocialfollowcontainer table {
-                max-width: 25% !important;
-                }
-                .edsocialfollowcontainer table td {
-                padding: 10px !important;
-                }
-                .edsocialfollowcontainer table {
-                max-width: 25% !important;
-                }
-                .edsocialfollowcontainer table td {
-                padding: 10px !important;
-                }
-            }
-            </style>
-            <link href="https://fonts.googleapis.com/css?family=Open+Sans:400,400i,600,600i,700,700i &subset=cyrillic,latin-ext" data-name="open_sans" rel="stylesheet" type="text/css">
-            <link rel="stylesheet" type="text/css" href="https://cdnjs.cloudflare.com/ajax/libs/spectrum/1.8.0/spectrum.min.css">
-        </head>
-        <body style="padding:0; margin: 0;background: #e4e6ec">
-            <table style="height: 100%; width: 100%; background-color: #e4e6ec;" align="center">
-            <tbody>
-                <tr>
-                <td valign="top" id="dbody" data-version="2.31" style="width: 100%; height: 100%; padding-top: 50px; padding-bottom: 50px; background-color: #e4e6ec;">
-                    <!--[if (gte mso 9)|(IE)]><table align="center" style="max-width:600px" width="600" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                    <table class="layer_1" align="center" border="0" cellpadding="0" cellspacing="0" style="max-width: 600px; box-sizing: border-box; width: 100%; margin: 0px auto;">
-                    <tbody>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #ffffff; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="width: 100%; display: inline-block; vertical-align: top; max-width: 600px;">
-                            <table border="0" cellspacing="0" cellpadding="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="emptycell" style="padding: 20px;">
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #ffffff; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="max-width: 596px; display: inline-block; vertical-align: top; width: 100%;">
-                            <table class="edcontent" style="border-collapse: collapse;width:100%" border="0" cellpadding="0" cellspacing="0">
-                                <tbody>
-                                <tr>
-                                    <td class="edimg" valign="top" style="padding: 0px; box-sizing: border-box; text-align: center;">
-                                    <img style="border-width: 0px; border-style: none; max-width: 255px; width: 100%;" width="255" alt="Image" src="https://competition.boxingontario.com/img/logo.png">
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #ffffff; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="max-width: 596px; display: inline-block; vertical-align: top; width: 100%;">
-                            <table border="0" cellspacing="0" cellpadding="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="emptycell" style="padding: 10px;">
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #000000; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="max-width: 600px; display: inline-block; vertical-align: top; width: 100%;">
-                            <table border="0" cellspacing="0" cellpadding="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="emptycell" style="padding: 1px;">
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #ffffff; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="max-width: 596px; display: inline-block; vertical-align: top; width: 100%;">
-                            <table border="0" cellspacing="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="edtext" style="padding: 20px; text-align: left; color: #5f5f5f; font-size: 12px; font-family: &quot;Open Sans&quot;, &quot;Helvetica Neue&quot;, Helvetica, Arial, sans-serif; word-break: break-word; direction: ltr; box-sizing: border-box;">
-                                    <p class="style1 text-center" style="text-align: center; margin: 0px; padding: 0px; color: #000000; font-size: 32px; font-family: &quot;Open Sans&quot;, &quot;Helvetica Neue&quot;, Helvetica, Arial, sans-serif;">` + title + `
-                                    </p>
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #ffffff; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="max-width: 596px; display: inline-block; vertical-align: top; width: 100%;">
-                            <table border="0" cellspacing="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="edtext" style="padding: 20px; text-align: left; color: #5f5f5f; font-size: 14px; font-family: &quot;Open Sans&quot;, &quot;Helvetica Neue&quot;, Helvetica, Arial, sans-serif; word-break: break-word; direction: ltr; box-sizing: border-box;">
-                                    <p class="text-center" style="text-align: center; margin: 0px; padding: 0px;">` + body + `
-                                    </p>
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <div class="layer_2" style="max-width: 596px; display: inline-block; vertical-align: top; width: 100%;">
-                            <table border="0" cellspacing="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="edtext" style="padding: 20px; text-align: left; color: #5f5f5f; font-size: 12px; font-family: &quot;Open Sans&quot;, &quot;Helvetica Neue&quot;, Helvetica, Arial, sans-serif; word-break: break-word; direction: ltr; box-sizing: border-box;">
-                                    <p class="text-center" style="text-align: center; margin: 0px; padding: 0px;">Having trouble finding your account? Please go <a href="` + domain + `/login">here</a> and click forgot password.
-                                    </p>
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #ffffff; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="max-width: 596px; display: inline-block; vertical-align: top; width: 100%;">
-                            <table border="0" cellspacing="0" cellpadding="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="emptycell" style="padding: 10px;">
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #f4f4f3; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="display: inline-block; vertical-align: top; width: 100%; max-width: 600px;">
-                            <table border="0" cellspacing="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="edsocialfollow" style="padding: 20px;">
-                                    <table align="center" style="margin:0 auto" class="edsocialfollowcontainer" cellpadding="0" border="0" cellspacing="0">
-                                        <tbody>
-                                        <tr>
-                                            <td>
-                                            <!--[if mso]><table align="center" border="0" cellspacing="0" cellpadding="0"><tr><td align="center" valign="top"><![endif]-->
-                                            <table align="left" border="0" cellpadding="0" cellspacing="0" data-service="facebook">
-                                                <tbody>
-                                                <tr>
-                                                    <td align="center" valign="middle" style="padding:10px;">
-                                                    <a href="https://www.facebook.com/boxingontario" target="_blank" style="color:#5457ff;font-size:12px;font-family:"><img src="https://api.etrck.com/userfile/a18de9fc-4724-42f2-b203-4992ceddc1de/ro_sol_co_32_facebook.png" style="display:block;width:32px;max-width:32px;border:none" alt="Facebook"></a></td>
-                                                </tr>
-                                                </tbody>
-                                            </table>
-                                            <!--[if mso]></td><td align="center" valign="top"><![endif]-->
-                                            <table align="left" border="0" cellpadding="0" cellspacing="0" data-service="twitter">
-                                                <tbody>
-                                                <tr>
-                                                    <td align="center" valign="middle" style="padding:10px;">
-                                                    <a href="https://twitter.com/BoxingOntario" target="_blank" style="color:#5457ff;font-size:12px;font-family:"><img src="https://api.etrck.com/userfile/a18de9fc-4724-42f2-b203-4992ceddc1de/ro_sol_co_32_twitter.png" style="display:block;width:32px;max-width:32px;border:none" alt="Twitter"></a></td>
-                                                </tr>
-                                                </tbody>
-                                            </table>
-                                            <!--[if mso]></td></tr></table><![endif]-->
-                                            </td>
-                                        </tr>
-                                        </tbody>
-                                    </table>
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                        <tr>
-                        <td class="drow" valign="top" align="center" style="background-color: #ffffff; box-sizing: border-box; font-size: 0px; text-align: center;">
-                            <!--[if (gte mso 9)|(IE)]><table width="100%" align="center" cellpadding="0" cellspacing="0" border="0"><tr><td valign="top"><![endif]-->
-                            <div class="layer_2" style="max-width: 596px; display: inline-block; vertical-align: top; width: 100%;">
-                            <table border="0" cellspacing="0" cellpadding="0" class="edcontent" style="border-collapse: collapse;width:100%">
-                                <tbody>
-                                <tr>
-                                    <td valign="top" class="emptycell" style="padding: 10px;">
-                                    </td>
-                                </tr>
-                                </tbody>
-                            </table>
-                            </div>
-                            <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                        </td>
-                        </tr>
-                    </tbody>
-                    </table>
-                    <!--[if (gte mso 9)|(IE)]></td></tr></table><![endif]-->
-                </td>
-                </tr>
-            </tbody>
-            </table>
-        </body>
-        </html>
-	`
+//InviteAccount - queue an invitation to register. There is no account yet so it is sent in the default language
+func (e Emailer) InviteAccount(ctx context.Context, invitation *types.Invitation, db db.Store) error {
+	return e.queue(ctx, "", TemplateInvitation, "", invitation.Organization, &Data{Email: invitation.Email, Link: e.Host + "/complete/invitation/" + invitation.ID + "?token=" + url.QueryEscape(invitation.Token), Expires: invitation.Expires}, db)
 }
@@ -0,0 +1,31 @@
+package email
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+//Log - prints emails as plain text instead of sending them, for local development
+type Log struct {
+	Out io.Writer
+
+	mu *sync.Mutex
+}
+
+//Init - print emails to the writer given
+func (l Log) Init(out io.Writer) *Log {
+	l.Out = out
+	l.mu = &sync.Mutex{}
+	return &l
+}
+
+//Send - prints the message
+func (l *Log) Send(message *Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := fmt.Fprintf(l.Out, "Email from %s to %s: %s\n%s\n\n", message.From, strings.Join(message.To, ", "), message.Subject, message.PlainText())
+	return err
+}
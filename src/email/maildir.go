@@ -0,0 +1,58 @@
+package email
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+//Maildir - writes each email to a maildir folder instead of sending it, for local development
+type Maildir struct {
+	Dir string
+
+	hostname string
+	count    *int64
+}
+
+//Init - creates the maildir at the path given if it does not exist
+func (m Maildir) Init(dir string) (*Maildir, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	m.Dir = dir
+	m.hostname = hostname
+	m.count = new(int64)
+	return &m, nil
+}
+
+//Send - writes the message to tmp and moves it to new once complete, so readers never see a partial email
+func (m *Maildir) Send(message *Message) error {
+	name := fmt.Sprintf("%d.%d_%d.%s", time.Now().Unix(), os.Getpid(), atomic.AddInt64(m.count, 1), m.hostname)
+	tmp := filepath.Join(m.Dir, "tmp", name)
+
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := message.mime().WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(m.Dir, "new", name))
+}
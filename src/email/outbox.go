@@ -0,0 +1,114 @@
+package email
+
+import (
+	"context"
+	"dao"
+	"db"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+	"types"
+	"utils"
+)
+
+//Outbox - sends queued emails in the background with retries
+type Outbox struct {
+	DB          db.Store
+	Transport   Transport
+	MaxAttempts int
+	RetryBase   time.Duration
+	RetryMax    time.Duration
+}
+
+//Init - start sending queued emails through the transport given
+func (o Outbox) Init(db db.Store, transport Transport) *Outbox {
+	o.DB = db
+	o.Transport = transport
+
+	attempts, err := strconv.Atoi(os.Getenv("EMAIL_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		attempts = 6
+	}
+	o.MaxAttempts = attempts
+
+	base, err := strconv.Atoi(os.Getenv("EMAIL_RETRY_SECONDS"))
+	if err != nil || base < 1 {
+		base = 30
+	}
+	o.RetryBase = time.Duration(base) * time.Second
+	o.RetryMax = time.Hour
+
+	outbox := &o
+
+	//Setup interval to send due emails. Shorter than webhooks since people are waiting on these
+	utils.Schedule(outbox.SendDue, 5*time.Second)
+
+	return outbox
+}
+
+//SendDue - attempts every email that is due
+func (o *Outbox) SendDue() {
+	ctx := context.Background()
+
+	emails, err := dao.OutboxDAO{}.GetDueEmails(ctx, 100, o.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Outbox Error: "+err.Error())
+		return
+	}
+
+	for i := range *emails {
+		email := &(*emails)[i]
+
+		//Hold the email long enough for a slow server so it isnt sent twice
+		claimed, err := dao.OutboxDAO{}.ClaimEmail(ctx, email, time.Now().Add(5*time.Minute), o.DB)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Outbox Error: "+err.Error())
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		o.attempt(email)
+
+		if err := (dao.OutboxDAO{}).UpdateEmail(ctx, email, o.DB); err != nil {
+			fmt.Fprintln(os.Stderr, "Outbox Error: "+err.Error())
+		}
+	}
+}
+
+//attempt - sends an email once and records the outcome on it
+func (o *Outbox) attempt(email *types.OutboxEmail) {
+	email.Attempts++
+
+	err := o.Transport.Send(&Message{From: email.From, To: []string{email.To}, Subject: email.Subject, HTML: email.Body, Text: email.Text})
+	if err != nil {
+		o.retry(email, err.Error())
+		return
+	}
+
+	//The body can hold one time links and codes, nothing needs it once sent
+	email.Status = types.EmailSent
+	email.Body = ""
+	email.Text = ""
+	email.LastError = ""
+}
+
+//retry - schedules the next attempt with exponential back off, or dead letters the email once out of attempts
+func (o *Outbox) retry(email *types.OutboxEmail, reason string) {
+	email.LastError = reason
+
+	if email.Attempts >= o.MaxAttempts {
+		email.Status = types.EmailDead
+		return
+	}
+
+	delay := o.RetryBase << uint(email.Attempts-1)
+	if delay > o.RetryMax || delay <= 0 {
+		delay = o.RetryMax
+	}
+
+	email.Status = types.EmailPending
+	email.NextAttempt = time.Now().Add(delay)
+}
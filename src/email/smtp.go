@@ -0,0 +1,114 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+//SMTP - sends emails through an SMTP server over one connection that is reused until it has been idle a while
+type SMTP struct {
+	Dialer *gomail.Dialer
+	//Idle - how long the connection is kept open without sending
+	Idle time.Duration
+
+	mu     *sync.Mutex
+	sender gomail.SendCloser
+	timer  *time.Timer
+}
+
+//Init - connect to the SMTP server in SMTP_HOST. SMTP_TLS is starttls, or tls for implicit TLS which is the default on port 465
+func (s SMTP) Init() (*SMTP, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, errors.New("SMTP_HOST is required for the smtp email transport")
+	}
+
+	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil {
+		port = 587
+	}
+
+	s.Dialer = gomail.NewDialer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	switch os.Getenv("SMTP_TLS") {
+	case "":
+	case "starttls":
+		s.Dialer.SSL = false
+	case "tls":
+		s.Dialer.SSL = true
+	default:
+		return nil, errors.New("SMTP_TLS must be starttls or tls")
+	}
+	s.Dialer.TLSConfig = &tls.Config{ServerName: host, InsecureSkipVerify: os.Getenv("SMTP_TLS_SKIP_VERIFY") == "true"}
+
+	idle, err := strconv.Atoi(os.Getenv("SMTP_IDLE_SECONDS"))
+	if err != nil || idle < 0 {
+		idle = 30
+	}
+	s.Idle = time.Duration(idle) * time.Second
+
+	s.mu = &sync.Mutex{}
+	return &s, nil
+}
+
+//Send - sends a message, reconnecting once if the open connection was dropped by the server
+func (s *SMTP) Send(message *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := message.mime()
+
+	reused := s.sender != nil
+	if err := s.send(m); err != nil {
+		s.close()
+		if !reused {
+			return err
+		}
+		if err := s.send(m); err != nil {
+			s.close()
+			return err
+		}
+	}
+
+	//Close the connection once nothing has been sent for a while
+	if s.Idle == 0 {
+		s.close()
+	} else if s.timer == nil {
+		s.timer = time.AfterFunc(s.Idle, s.closeIdle)
+	} else {
+		s.timer.Reset(s.Idle)
+	}
+	return nil
+}
+
+//send - sends a message over the open connection, dialing first if there is none. Caller must hold the lock
+func (s *SMTP) send(m *gomail.Message) error {
+	if s.sender == nil {
+		sender, err := s.Dialer.Dial()
+		if err != nil {
+			return err
+		}
+		s.sender = sender
+	}
+	return gomail.Send(s.sender, m)
+}
+
+//closeIdle - closes the connection after it has been idle
+func (s *SMTP) closeIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.close()
+}
+
+//close - closes the open connection if there is one. Caller must hold the lock
+func (s *SMTP) close() {
+	if s.sender != nil {
+		s.sender.Close()
+		s.sender = nil
+	}
+}
@@ -0,0 +1,265 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+//Email templates
+const (
+	TemplateDevice     = "device"
+	TemplateRecovery   = "recovery"
+	TemplateErasure    = "erasure"
+	TemplateInvitation = "invitation"
+	TemplateMagicLink  = "magiclink"
+	TemplateLoginCode  = "logincode"
+	TemplateLoginAlert = "loginalert"
+)
+
+//TemplateNames - every email template
+var TemplateNames = []string{TemplateDevice, TemplateRecovery, TemplateErasure, TemplateInvitation, TemplateMagicLink, TemplateLoginCode, TemplateLoginAlert}
+
+//Brand - how emails for an organization look and who they are from
+type Brand struct {
+	//Name - the sender name shown with EMAIL_ADDRESS
+	Name       string      `json:"name"`
+	Logo       string      `json:"logo"`
+	Color      string      `json:"color"`
+	Background string      `json:"background"`
+	Links      []BrandLink `json:"links"`
+}
+
+//BrandLink - a link shown at the bottom of emails, as its icon if it has one
+type BrandLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Icon string `json:"icon"`
+}
+
+//defaultBrand - the look emails had before branding could be configured
+var defaultBrand = Brand{
+	Logo:       "https://competition.boxingontario.com/img/logo.png",
+	Color:      "#000000",
+	Background: "#e4e6ec",
+	Links: []BrandLink{
+		{Name: "Facebook", URL: "https://www.facebook.com/boxingontario", Icon: "https://api.etrck.com/userfile/a18de9fc-4724-42f2-b203-4992ceddc1de/ro_sol_co_32_facebook.png"},
+		{Name: "Twitter", URL: "https://twitter.com/BoxingOntario", Icon: "https://api.etrck.com/userfile/a18de9fc-4724-42f2-b203-4992ceddc1de/ro_sol_co_32_twitter.png"},
+	},
+}
+
+//Data - the values email templates are rendered with
+type Data struct {
+	Brand   Brand
+	Host    string
+	Email   string
+	Name    string
+	Link    string
+	Code    string
+	Expires time.Time
+	//Where a new device or unusual login came from
+	IP       string
+	Browser  string
+	OS       string
+	Location string
+	//Blocked - the unusual login was stopped rather than let through
+	Blocked bool
+}
+
+//Renderer - renders emails from templates. Templates in Dir replace the built in ones file by file
+type Renderer struct {
+	Dir      string
+	Language string
+	Brands   map[string]Brand
+}
+
+//Init - loads the template directory, default language and branding from the environment
+func (r Renderer) Init() (*Renderer, error) {
+	r.Dir = os.Getenv("EMAIL_TEMPLATE_DIR")
+	r.Language = strings.ToLower(os.Getenv("EMAIL_LANGUAGE"))
+	if r.Language == "" {
+		r.Language = "en"
+	}
+	if !languageTag.MatchString(r.Language) {
+		return nil, errors.New("Invalid EMAIL_LANGUAGE: " + r.Language)
+	}
+
+	r.Brands = map[string]Brand{}
+	if file := os.Getenv("EMAIL_BRANDING_FILE"); file != "" {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(contents, &r.Brands); err != nil {
+			return nil, errors.New("Invalid EMAIL_BRANDING_FILE: " + err.Error())
+		}
+	}
+
+	return &r, nil
+}
+
+//Brand - returns the branding of an organization. Anything it does not set comes from the default branding, keyed by an empty organization
+func (r *Renderer) Brand(organization string) Brand {
+	brand := defaultBrand
+	if base, ok := r.Brands[""]; ok {
+		brand = mergeBrand(brand, base)
+	}
+	if organization != "" {
+		if custom, ok := r.Brands[organization]; ok {
+			brand = mergeBrand(brand, custom)
+		}
+	}
+	return brand
+}
+
+//mergeBrand - returns the base brand with the fields set on the override replacing it
+func mergeBrand(base Brand, override Brand) Brand {
+	if override.Name != "" {
+		base.Name = override.Name
+	}
+	if override.Logo != "" {
+		base.Logo = override.Logo
+	}
+	if override.Color != "" {
+		base.Color = override.Color
+	}
+	if override.Background != "" {
+		base.Background = override.Background
+	}
+	if override.Links != nil {
+		base.Links = override.Links
+	}
+	return base
+}
+
+//Render - renders the subject, html and text of an email in the language closest to the one given
+func (r *Renderer) Render(name string, language string, data *Data) (*Message, error) {
+	if !templateName.MatchString(name) {
+		return nil, errors.New("Unknown email template: " + name)
+	}
+	languages := r.languages(language)
+
+	html, err := r.renderHTML(name, languages, data)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := r.parseText(name, languages)
+	if err != nil {
+		return nil, err
+	}
+	subject := &bytes.Buffer{}
+	if err := text.ExecuteTemplate(subject, "subject", data); err != nil {
+		return nil, err
+	}
+	body := &bytes.Buffer{}
+	if err := text.ExecuteTemplate(body, "layout.txt", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{Subject: strings.Join(strings.Fields(subject.String()), " "), HTML: html, Text: strings.TrimSpace(body.String()) + "\n"}, nil
+}
+
+//renderHTML - renders the html layout with the email and shared html templates
+func (r *Renderer) renderHTML(name string, languages []string, data *Data) (string, error) {
+	t := htmltemplate.New("email").Funcs(htmltemplate.FuncMap{"date": formatDate})
+	for _, file := range []string{"layout.html", "common.html", name + ".html"} {
+		contents, err := r.read(file, languages)
+		if err != nil {
+			return "", err
+		}
+		if _, err := t.New(file).Parse(contents); err != nil {
+			return "", err
+		}
+	}
+
+	html := &bytes.Buffer{}
+	if err := t.ExecuteTemplate(html, "layout.html", data); err != nil {
+		return "", err
+	}
+	return html.String(), nil
+}
+
+//parseText - parses the text layout with the email and shared text templates
+func (r *Renderer) parseText(name string, languages []string) (*texttemplate.Template, error) {
+	t := texttemplate.New("email").Funcs(texttemplate.FuncMap{"date": formatDate})
+	for _, file := range []string{"layout.txt", "common.txt", name + ".txt"} {
+		contents, err := r.read(file, languages)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.New(file).Parse(contents); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+//read - returns the first template file found for the languages, then without a language. Each is looked for in Dir before the built in templates
+func (r *Renderer) read(file string, languages []string) (string, error) {
+	for _, language := range append(languages, "") {
+		if r.Dir != "" {
+			contents, err := os.ReadFile(filepath.Join(r.Dir, language, file))
+			if err == nil {
+				return string(contents), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+
+		contents, err := templateFiles.ReadFile(path.Join("templates", language, file))
+		if err == nil {
+			return string(contents), nil
+		}
+	}
+	return "", errors.New("Missing email template: " + file)
+}
+
+//languages - returns the languages to look for templates in, most specific first. fr-CA falls back to fr then the default language
+func (r *Renderer) languages(language string) []string {
+	languages := []string{}
+	language = strings.ToLower(language)
+	if !languageTag.MatchString(language) {
+		language = ""
+	}
+
+	for language != "" {
+		languages = append(languages, language)
+		i := strings.LastIndex(language, "-")
+		if i < 0 {
+			break
+		}
+		language = language[:i]
+	}
+
+	for _, existing := range languages {
+		if existing == r.Language {
+			return languages
+		}
+	}
+	return append(languages, r.Language)
+}
+
+//formatDate - formats a date in templates, so each language can lay it out its own way
+func formatDate(date time.Time, layout string) string {
+	return date.Format(layout)
+}
+
+var (
+	templateName = regexp.MustCompile(`^[a-z_]+$`)
+	languageTag  = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
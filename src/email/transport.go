@@ -0,0 +1,98 @@
+package email
+
+import (
+	"errors"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+//Transport - delivers emails. Chosen with EMAIL_TRANSPORT
+type Transport interface {
+	Send(message *Message) error
+}
+
+//Message - an email ready to be delivered
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	HTML    string
+	//Text - the plain text alternative of HTML. Sent as its own part when set
+	Text string
+}
+
+//mime - returns the message as a gomail message
+func (m *Message) mime() *gomail.Message {
+	message := gomail.NewMessage()
+	message.SetHeader("From", m.From)
+	message.SetHeader("To", m.To...)
+	message.SetHeader("Subject", m.Subject)
+	if m.Text == "" {
+		message.SetBody("text/html", m.HTML)
+		return message
+	}
+
+	//Clients show the last alternative they support, so html goes after the text
+	message.SetBody("text/plain", m.Text)
+	message.AddAlternative("text/html", m.HTML)
+	return message
+}
+
+//PlainText - returns the text alternative, or the html as plain text without one. Links are kept after their text so they can still be followed
+func (m *Message) PlainText() string {
+	if m.Text != "" {
+		return m.Text
+	}
+
+	body := skipped.ReplaceAllString(m.HTML, "")
+	body = links.ReplaceAllString(body, "$2 ($1)")
+	body = breaks.ReplaceAllString(body, "\n")
+	body = html.UnescapeString(tags.ReplaceAllString(body, ""))
+
+	lines := []string{}
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	skipped = regexp.MustCompile(`(?is)<(head|style)[^>]*>.*?</(head|style)>|<!--.*?-->`)
+	links   = regexp.MustCompile(`(?is)<a\s[^>]*href=["']([^"']*)["'][^>]*>(.*?)</a>`)
+	breaks  = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+	tags    = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+//NewTransport - returns the transport selected by EMAIL_TRANSPORT. Without one SMTP is used if SMTP_HOST is set, otherwise emails are logged
+func NewTransport() (Transport, error) {
+	name := os.Getenv("EMAIL_TRANSPORT")
+	if name == "" {
+		name = "log"
+		if os.Getenv("SMTP_HOST") != "" {
+			name = "smtp"
+		}
+	}
+
+	switch name {
+	case "smtp":
+		return SMTP{}.Init()
+	case "file":
+		dir := os.Getenv("EMAIL_FILE_DIR")
+		if dir == "" {
+			dir = "./mail"
+		}
+		return Maildir{}.Init(dir)
+	case "log":
+		return Log{}.Init(os.Stdout), nil
+	case "memory":
+		return Memory{}.Init(), nil
+	default:
+		return nil, errors.New("Unknown EMAIL_TRANSPORT: " + name)
+	}
+}
@@ -0,0 +1,215 @@
+package geoip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Location - where an ip address is, never finer than a city
+type Location struct {
+	Country string
+	Region  string
+	City    string
+	//Coordinates - whether Latitude and Longitude are known
+	Coordinates bool
+	Latitude    float64
+	Longitude   float64
+}
+
+//String - "City, Region, Country" leaving out the parts that are not known
+func (l *Location) String() string {
+	parts := []string{}
+	for _, part := range []string{l.City, l.Region, l.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+//Database - a local CSV of ip ranges sorted by start address with IPv4 before IPv6, as in the DB-IP lite downloads.
+//Lines are either "start,end,country" or "start,end,continent,country,region,city[,latitude,longitude]"
+type Database struct {
+	Path string
+	file *os.File
+	size int64
+}
+
+//Init - opens the file in GEOIP_FILE. Nothing is located if it is not set
+func (d Database) Init() (*Database, error) {
+	path := os.Getenv("GEOIP_FILE")
+	if path == "" {
+		return &d, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		file.Close()
+		return nil, errors.New("GeoIP file is empty: " + path)
+	}
+
+	d.Path = path
+	d.file = file
+	d.size = info.Size()
+	return &d, nil
+}
+
+//Lookup - returns the location of an ip. nil if no file is configured, the ip is private or it is in no range
+func (d *Database) Lookup(ip string) (*Location, error) {
+	if d == nil || d.file == nil {
+		return nil, nil
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() {
+		return nil, nil
+	}
+	key := rangeKey(addr)
+
+	//Find the last range starting at or before the ip. Every line before lo starts at or before it, none from hi on do
+	var found []string
+	lo, hi := int64(0), d.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		line, next, err := d.lineFrom(mid)
+		if err == io.EOF {
+			//No line starts at or after mid
+			hi = mid
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record, start, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.Compare(start, key) <= 0 {
+			found = record
+			lo = next
+		} else {
+			hi = mid
+		}
+	}
+
+	if found == nil {
+		return nil, nil
+	}
+
+	//The closest range ends before the ip
+	end := net.ParseIP(found[1])
+	if end == nil || bytes.Compare(key, rangeKey(end)) > 0 {
+		return nil, nil
+	}
+
+	location := &Location{Country: found[2]}
+	if len(found) >= 6 {
+		location.Country, location.Region, location.City = found[3], found[4], found[5]
+	}
+	if len(found) >= 8 {
+		latitude, latErr := strconv.ParseFloat(found[6], 64)
+		longitude, lonErr := strconv.ParseFloat(found[7], 64)
+		if latErr == nil && lonErr == nil {
+			location.Coordinates, location.Latitude, location.Longitude = true, latitude, longitude
+		}
+	}
+
+	//ZZ marks reserved ranges
+	if location.Country == "ZZ" {
+		return nil, nil
+	}
+	return location, nil
+}
+
+//Distance - returns the great circle distance between two locations in km, -1 if either has no coordinates
+func Distance(a *Location, b *Location) float64 {
+	if a == nil || b == nil || !a.Coordinates || !b.Coordinates {
+		return -1
+	}
+
+	const earthRadius = 6371
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+//lineFrom - returns the first full line starting at or after offset, and the offset just past it
+func (d *Database) lineFrom(offset int64) (string, int64, error) {
+	start := offset
+	if offset > 0 {
+		//Read from the byte before so we know if offset is already the start of a line
+		start = offset - 1
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(d.file, start, d.size-start))
+
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err != nil {
+			return "", 0, io.EOF
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", 0, err
+	}
+
+	return line, start + int64(len(line)), nil
+}
+
+//Close - closes the underlying file
+func (d *Database) Close() error {
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Close()
+}
+
+//parseLine - splits a CSV line and returns the sort key of its start address
+func parseLine(line string) ([]string, []byte, error) {
+	record, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil || len(record) < 3 {
+		return nil, nil, errors.New("Malformed GeoIP line: " + strings.TrimSpace(line))
+	}
+
+	start := net.ParseIP(record[0])
+	if start == nil {
+		return nil, nil, errors.New("Malformed GeoIP line: " + strings.TrimSpace(line))
+	}
+
+	return record, rangeKey(start), nil
+}
+
+//rangeKey - orders IPv4 addresses before IPv6 ones, then by address
+func rangeKey(ip net.IP) []byte {
+	if v4 := ip.To4(); v4 != nil {
+		return append([]byte{4}, v4...)
+	}
+	return append([]byte{6}, ip.To16()...)
+}
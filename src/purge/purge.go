@@ -0,0 +1,73 @@
+package purge
+
+import (
+	"audit"
+	"context"
+	"dao"
+	"db"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+	"types"
+	"utils"
+)
+
+//Purger - permanently removes soft deleted accounts once their grace period is over
+type Purger struct {
+	DB    db.Store
+	Audit *audit.Auditor
+	Grace time.Duration
+}
+
+//Init - start purging deleted accounts in the background
+func (p Purger) Init(db db.Store, auditor *audit.Auditor) *Purger {
+	p.DB = db
+	p.Audit = auditor
+
+	days, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETE_GRACE_DAYS"))
+	if err != nil || days < 0 {
+		days = 30
+	}
+	p.Grace = time.Duration(days) * 24 * time.Hour
+
+	purger := &p
+
+	//Setup interval to purge deleted accounts
+	utils.Schedule(purger.PurgeDeleted, 1*time.Hour)
+
+	return purger
+}
+
+//PurgeDeleted - purges every account that was deleted longer ago than the grace period
+func (p *Purger) PurgeDeleted() {
+	ctx := context.Background()
+
+	for {
+		accounts, err := dao.AccountDAO{}.GetDeletedBefore(ctx, time.Now().Add(-p.Grace), 100, p.DB)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Purge Error: "+err.Error())
+			return
+		}
+
+		for i := range *accounts {
+			if err := p.purge(ctx, &(*accounts)[i]); err != nil {
+				//Stop so a failing account is not retried forever, the next run picks it up again
+				fmt.Fprintln(os.Stderr, "Purge Error: "+err.Error())
+				return
+			}
+		}
+
+		if len(*accounts) < 100 {
+			return
+		}
+	}
+}
+
+//purge - removes one account and records it in the audit log
+func (p *Purger) purge(ctx context.Context, account *types.Account) (err error) {
+	event := &types.AuditEvent{Type: types.AuditAccountPurge, TargetID: account.ID}
+	defer func() { p.Audit.Record(event, "", err) }()
+
+	return dao.AccountDAO{}.PurgeAccount(ctx, account, p.DB)
+}
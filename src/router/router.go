@@ -2,10 +2,14 @@ package router
 
 import (
 	"auth"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"scim"
+	"strconv"
 	"strings"
 	"time"
 	"types"
@@ -17,22 +21,32 @@ import (
 //Router type
 type Router struct {
 	Host         string
+	TrustProxy   bool
+	ProxyCount   int
 	Authenticate *auth.Authenticate
 	Authorize    *auth.Authorize
+	SCIM         *scim.Provisioner
 }
 
 //Init - inits all routes.
-func (router Router) Init(authenticate *auth.Authenticate, authorize *auth.Authorize) error {
+func (router Router) Init(authenticate *auth.Authenticate, authorize *auth.Authorize, provisioner *scim.Provisioner) error {
 
 	router.Authenticate = authenticate
 	router.Authorize = authorize
+	router.SCIM = provisioner
 	router.Host = os.Getenv("HOST")
+	router.TrustProxy = os.Getenv("TRUST_PROXY") == "true"
+	proxies, err := strconv.Atoi(os.Getenv("TRUSTED_PROXY_COUNT"))
+	if err != nil || proxies < 1 {
+		proxies = 1
+	}
+	router.ProxyCount = proxies
 
 	//Setup mux router
 	r := mux.NewRouter()
 	router.setUpRoutes(r)
 	fmt.Fprintln(os.Stderr, "Server Started")
-	err := http.ListenAndServe(os.Getenv("PORT"), r)
+	err = http.ListenAndServe(os.Getenv("PORT"), r)
 	if err != nil {
 		return err
 	}
@@ -44,18 +58,60 @@ func (router Router) Init(authenticate *auth.Authenticate, authorize *auth.Autho
 func (router Router) setUpRoutes(r *mux.Router) {
 	r.HandleFunc("/api/auth/login", router.login)
 	r.HandleFunc("/api/auth/logout", router.logout)
+	r.HandleFunc("/api/auth/magic", router.requestMagicLink)
+	r.HandleFunc("/api/auth/magic/login", router.magicLogin)
+	r.HandleFunc("/api/auth/code", router.requestLoginCode)
+	r.HandleFunc("/api/auth/code/login", router.codeLogin)
 	r.HandleFunc("/api/auth/register", router.register)
 	r.HandleFunc("/api/auth/delete", router.delete)
+	r.HandleFunc("/api/auth/restore", router.restore)
+	r.HandleFunc("/api/auth/disable", router.disable)
+	r.HandleFunc("/api/auth/enable", router.enable)
 	r.HandleFunc("/api/auth/updatesettings", router.updateSettings)
 	r.HandleFunc("/api/auth/updateaccount", router.updateAccount)
 	r.HandleFunc("/api/auth/refresh", router.refreshToken)
+	r.HandleFunc("/api/auth/reauthenticate", router.reauthenticate)
+	r.HandleFunc("/api/auth/reauthenticate/code", router.sendReauthenticateCode)
 	r.HandleFunc("/api/auth/getaccount", router.getAccount)
 	r.HandleFunc("/api/auth/getaccounts", router.getAccounts)
 	r.HandleFunc("/api/auth/activatedevice", router.activateDevice)
+	r.HandleFunc("/api/auth/activatedevice/phone", router.sendDevicePhoneCode)
+	r.HandleFunc("/api/auth/phone/code", router.sendPhoneVerification)
+	r.HandleFunc("/api/auth/phone/verify", router.verifyPhone)
 	r.HandleFunc("/api/auth/recoveraccount", router.recoverAccount)
 	r.HandleFunc("/api/auth/getrecovery", router.getRecovery)
 	r.HandleFunc("/api/auth/finishrecovery", router.finishRecovery)
 	r.HandleFunc("/api/auth/changepassword", router.changeAccountPassword)
+	r.HandleFunc("/api/auth/audit", router.searchAudit)
+	r.HandleFunc("/api/auth/audit/export", router.exportAudit)
+	r.HandleFunc("/api/auth/webhooks/create", router.createWebhook)
+	r.HandleFunc("/api/auth/webhooks/list", router.getWebhooks)
+	r.HandleFunc("/api/auth/webhooks/delete", router.deleteWebhook)
+	r.HandleFunc("/api/auth/webhooks/deliveries", router.getWebhookDeliveries)
+	r.HandleFunc("/api/auth/webhooks/redeliver", router.redeliverWebhook)
+	r.HandleFunc("/api/auth/emails", router.getOutbox)
+	r.HandleFunc("/api/auth/emails/retry", router.retryEmail)
+	r.HandleFunc("/api/auth/emails/preview", router.previewEmail)
+	r.HandleFunc("/api/auth/emails/test", router.sendTestEmail)
+	r.HandleFunc("/api/auth/accounts/import", router.importAccounts)
+	r.HandleFunc("/api/auth/accounts/export", router.exportAccounts)
+	r.HandleFunc("/api/auth/invitations", router.getInvitations)
+	r.HandleFunc("/api/auth/invitations/create", router.createInvitation)
+	r.HandleFunc("/api/auth/invitations/revoke", router.revokeInvitation)
+	r.HandleFunc("/api/auth/privacy/export", router.exportData)
+	r.HandleFunc("/api/auth/privacy/erase", router.requestErasure)
+	r.HandleFunc("/api/auth/privacy/erase/confirm", router.confirmErasure)
+	r.HandleFunc("/api/auth/privacy/requests", router.getErasureRequests)
+	r.HandleFunc("/api/auth/privacy/requests/process", router.processErasureRequest)
+	r.HandleFunc("/api/auth/scim/tokens", router.getSCIMTokens)
+	r.HandleFunc("/api/auth/scim/tokens/create", router.createSCIMToken)
+	r.HandleFunc("/api/auth/scim/tokens/revoke", router.revokeSCIMToken)
+	r.HandleFunc("/api/auth/tokens", router.getPersonalTokens)
+	r.HandleFunc("/api/auth/tokens/create", router.createPersonalToken)
+	r.HandleFunc("/api/auth/tokens/revoke", router.revokePersonalToken)
+	r.HandleFunc("/api/auth/impersonate", router.impersonate)
+	r.HandleFunc("/api/auth/impersonate/stop", router.stopImpersonation)
+	router.setUpSCIMRoutes(r.PathPrefix("/scim/v2").Subrouter())
 }
 
 //-----------------HELPERS BELOW-----------------\\
@@ -122,6 +178,16 @@ func (router Router) getDeviceID(r *http.Request) string {
 	return ""
 }
 
+//getMagicNonce - returns the nonce set when a magic link was requested from request cookies
+func (router Router) getMagicNonce(r *http.Request) string {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == "magicNonce" {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
 //getAccessToken - returns access token from authorization header
 func (router Router) getAccessToken(r *http.Request) string {
 	reqToken := r.Header.Get("Authorization")
@@ -146,6 +212,35 @@ func (router Router) getRefreshToken(r *http.Request) string {
 	return ""
 }
 
+//getTokens - returns the tokens and client details sent with a request
+func (router Router) getTokens(r *http.Request) *types.AuthTokens {
+	return &types.AuthTokens{
+		AccessToken:  router.getAccessToken(r),
+		RefreshToken: router.getRefreshToken(r),
+		Client:       router.getClient(r),
+	}
+}
+
+//getClient - returns the ip and user agent of the request. Forwarded headers are only used behind a trusted proxy.
+//Clients can send their own X-Forwarded-For, so the address is the one the outermost trusted proxy appended, counting from the right
+func (router Router) getClient(r *http.Request) types.Client {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if router.TrustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(strings.Join(forwarded, ","), ",")
+			if len(entries) >= router.ProxyCount {
+				ip = strings.TrimSpace(entries[len(entries)-router.ProxyCount])
+			}
+		}
+	}
+
+	return types.Client{IP: ip, UserAgent: r.UserAgent()}
+}
+
 //addCookie - adds a cookie to a response
 func (router Router) addCookie(w http.ResponseWriter, name string, value string) {
 	expire := time.Now().AddDate(1, 0, 0)
@@ -176,15 +271,21 @@ func (router Router) login(w http.ResponseWriter, r *http.Request) {
 
 	//Get device id from cookie
 	loginDetails.DeviceID = router.getDeviceID(r)
+	loginDetails.Client = router.getClient(r)
 
 	//Get results from login attempt
-	result, err := router.Authenticate.Login(&loginDetails)
+	result, err := router.Authenticate.Login(r.Context(), &loginDetails)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Login Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
+	router.loginResponse(w, result, "Login")
+}
+
+//loginResponse - responds to a login with the access token and refresh token cookie, or the device cookie if the device must be activated first
+func (router Router) loginResponse(w http.ResponseWriter, result *types.LoginResponse, name string) {
 	//Device is not active, tell the client to activate it
 	if !result.DeviceActive {
 		responseInfo := &types.LoginResponseData{
@@ -194,7 +295,7 @@ func (router Router) login(w http.ResponseWriter, r *http.Request) {
 		//Create the json response
 		data, err := json.Marshal(responseInfo)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, "Login Error: "+err.Error())
+			fmt.Fprintln(os.Stderr, name+" Error: "+err.Error())
 			router.errorResponse(w, 406, 5, "Invalid Request")
 			return
 		}
@@ -209,7 +310,7 @@ func (router Router) login(w http.ResponseWriter, r *http.Request) {
 
 	//Make sure tokens exists
 	if result.Tokens == nil {
-		fmt.Fprintln(os.Stderr, "Login Error: Device is active but no tokens were provided")
+		fmt.Fprintln(os.Stderr, name+" Error: Device is active but no tokens were provided")
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
@@ -223,7 +324,7 @@ func (router Router) login(w http.ResponseWriter, r *http.Request) {
 	//Create the json response
 	data, err := json.Marshal(responseInfo)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Login Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, name+" Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
@@ -235,51 +336,52 @@ func (router Router) login(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-//logout - endpoint to logout. removes and deletes refresh token.
-func (router Router) logout(w http.ResponseWriter, r *http.Request) {
+//requestMagicLink - endpoint to email a login link. Responds the same whether or not the email has an account
+func (router Router) requestMagicLink(w http.ResponseWriter, r *http.Request) {
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
 
-	tokens := &types.AuthTokens{
-		RefreshToken: router.getRefreshToken(r),
+	var request types.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "RequestMagicLink Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
 	}
+	request.Client = router.getClient(r)
 
-	err := router.Authenticate.Logout(tokens)
+	nonce, err := router.Authenticate.RequestMagicLink(r.Context(), &request)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Logout Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "RequestMagicLink Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	//Remove Refresh token
-	router.addCookie(w, "refreshToken", "")
+	//The link only works in a browser with this cookie
+	router.addCookie(w, "magicNonce", nonce)
 
 	router.goodRequest(w)
 }
 
-//register - endpoint to register a new account
-func (router Router) register(w http.ResponseWriter, r *http.Request) {
-
+//magicLogin - endpoint to login with a magic link
+func (router Router) magicLogin(w http.ResponseWriter, r *http.Request) {
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
 
-	var account types.Account
-	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
-		fmt.Fprintln(os.Stderr, "Register Error: "+err.Error())
+	var request types.MagicLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "MagicLogin Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
+	request.Nonce = router.getMagicNonce(r)
+	request.DeviceID = router.getDeviceID(r)
+	request.Client = router.getClient(r)
 
-	tokens := &types.AuthTokens{
-		AccessToken: router.getAccessToken(r),
-	}
-
-	res, err := router.Authorize.RegisterAccount(tokens, &account)
-	//Some error occured while trying to create the account
+	result, res, err := router.Authenticate.MagicLogin(r.Context(), &request)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Register Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "MagicLogin Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
@@ -290,31 +392,53 @@ func (router Router) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Account created
-	router.goodRequest(w)
-}
+	//The nonce is used up with the link
+	router.addCookie(w, "magicNonce", "")
 
-//delete - endpoint to delete an account
-func (router Router) delete(w http.ResponseWriter, r *http.Request) {
+	router.loginResponse(w, result, "MagicLogin")
+}
 
+//requestLoginCode - endpoint to email a login code. Responds the same whether or not the email has an account
+func (router Router) requestLoginCode(w http.ResponseWriter, r *http.Request) {
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
-	var del types.DeleteAccountRequest
-	if err := json.NewDecoder(r.Body).Decode(&del); err != nil {
-		fmt.Fprintln(os.Stderr, "Delete Error: "+err.Error())
+
+	var request types.LoginCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "RequestLoginCode Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+	request.Client = router.getClient(r)
+
+	if err := router.Authenticate.RequestLoginCode(r.Context(), &request); err != nil {
+		fmt.Fprintln(os.Stderr, "RequestLoginCode Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	tokens := &types.AuthTokens{
-		AccessToken: router.getAccessToken(r),
+	router.goodRequest(w)
+}
+
+//codeLogin - endpoint to login with an emailed code
+func (router Router) codeLogin(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
 	}
 
-	res, err := router.Authorize.DeleteAccount(tokens, &del)
-	//Some error occured while trying to delete the account
+	var request types.CodeLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "CodeLogin Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+	request.DeviceID = router.getDeviceID(r)
+	request.Client = router.getClient(r)
+
+	result, res, err := router.Authenticate.CodeLogin(r.Context(), &request)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Delete Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "CodeLogin Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
@@ -325,132 +449,131 @@ func (router Router) delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Account deleted
-	router.goodRequest(w)
+	router.loginResponse(w, result, "CodeLogin")
 }
 
-//refreshToken - Endpoint to refresh an Access Token
-func (router Router) refreshToken(w http.ResponseWriter, r *http.Request) {
+//logout - endpoint to logout. removes and deletes refresh token.
+func (router Router) logout(w http.ResponseWriter, r *http.Request) {
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
 
-	newToken, err := router.Authenticate.RefreshAccessToken(&types.AuthTokens{AccessToken: router.getAccessToken(r), RefreshToken: router.getRefreshToken(r)})
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "RefreshToken Error: "+err.Error())
-		router.errorResponse(w, 406, 5, "Invalid Request")
-		return
-	}
-
-	//Data that will be sent as a response
-	responseInfo := &types.AccessTokenResponse{
-		AccessToken: newToken,
-	}
+	tokens := router.getTokens(r)
 
-	//Create the json response
-	data, err := json.Marshal(responseInfo)
+	err := router.Authenticate.Logout(r.Context(), tokens)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "RefreshToken Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "Logout Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	w.WriteHeader(200)
-	w.Write(data)
+	//Remove Refresh token
+	router.addCookie(w, "refreshToken", "")
+
+	router.goodRequest(w)
 }
 
-//getAccount - Endpoint to get account details of the requesting user.
-func (router Router) getAccount(w http.ResponseWriter, r *http.Request) {
+//register - endpoint to register a new account
+func (router Router) register(w http.ResponseWriter, r *http.Request) {
+
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
 
-	account, err := router.Authorize.GetAccount(&types.AuthTokens{AccessToken: router.getAccessToken(r)})
+	var request types.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "Register Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	tokens := router.getTokens(r)
+
+	res, err := router.Authorize.RegisterAccount(r.Context(), tokens, &request)
+	//Some error occured while trying to create the account
 	if err != nil {
-		//Check if the error is a JWT related error
 		if utils.IsExpired(err) {
-			fmt.Fprintln(os.Stderr, "GetAccount Error: "+err.Error())
+			fmt.Fprintln(os.Stderr, "Register Error: "+err.Error())
 			router.errorResponse(w, 401, 10, "Access token is invalid")
 			return
 		}
-		fmt.Fprintln(os.Stderr, "GetAccount Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "Register Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	//Create the json response
-	data, err := json.Marshal(&account)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "GetAccount Error: "+err.Error())
-		router.errorResponse(w, 406, 5, "Invalid Request")
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
 		return
 	}
 
-	w.WriteHeader(200)
-	w.Write(data)
+	//Account created
+	router.goodRequest(w)
 }
 
-//getAccounts - endpoint to get all accounts that match the roles provided. role 0 will get all accounts
-func (router Router) getAccounts(w http.ResponseWriter, r *http.Request) {
+//delete - endpoint to delete an account
+func (router Router) delete(w http.ResponseWriter, r *http.Request) {
+
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
-
-	var request types.GetAccountsRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		fmt.Fprintln(os.Stderr, "GetAccounts Error: "+err.Error())
+	var del types.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&del); err != nil {
+		fmt.Fprintln(os.Stderr, "Delete Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	tokens := &types.AuthTokens{
-		AccessToken: router.getAccessToken(r),
-	}
+	tokens := router.getTokens(r)
 
-	accounts, err := router.Authorize.GetAccounts(tokens, request.Roles)
+	res, err := router.Authorize.DeleteAccount(r.Context(), tokens, &del)
+	//Some error occured while trying to delete the account
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "GetAccounts Error: "+err.Error())
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "Delete Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Delete Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	data, err := json.Marshal(types.AllUsersResponse{Accounts: accounts})
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "GetAccounts Error: "+err.Error())
-		router.errorResponse(w, 406, 5, "Invalid Request")
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
 		return
 	}
 
-	w.WriteHeader(200)
-	w.Write(data)
-
+	//Account deleted
+	router.goodRequest(w)
 }
 
-//updateSettings - endpoint to update account settings
-func (router Router) updateSettings(w http.ResponseWriter, r *http.Request) {
+//restore - endpoint to restore a deleted account before it is purged
+func (router Router) restore(w http.ResponseWriter, r *http.Request) {
+
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
-	var account types.Account
-	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
-		fmt.Fprintln(os.Stderr, "UpdateSettings Error: "+err.Error())
+	var request types.AccountIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "Restore Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	tokens := &types.AuthTokens{
-		AccessToken: router.getAccessToken(r),
-	}
+	tokens := router.getTokens(r)
 
-	res, err := router.Authorize.UpdateSettings(tokens, &account)
-	//Some error occured while trying to create the account
+	res, err := router.Authorize.RestoreAccount(r.Context(), tokens, &request)
 	if err != nil {
+		//Check if the error is a JWT related error
 		if utils.IsExpired(err) {
-			fmt.Fprintln(os.Stderr, "Update Settings Error: "+err.Error())
+			fmt.Fprintln(os.Stderr, "Restore Error: "+err.Error())
 			router.errorResponse(w, 401, 10, "Access token is invalid")
 			return
 		}
-		fmt.Fprintln(os.Stderr, "UpdateSettings Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "Restore Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
@@ -461,31 +584,34 @@ func (router Router) updateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Account Updated
+	//Account restored
 	router.goodRequest(w)
 }
 
-//updateAccount - endpoint to update another users account settings
-func (router Router) updateAccount(w http.ResponseWriter, r *http.Request) {
+//disable - endpoint to disable an account with a reason
+func (router Router) disable(w http.ResponseWriter, r *http.Request) {
+
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
-
-	var account types.Account
-	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
-		fmt.Fprintln(os.Stderr, "UpdateAccount Error: "+err.Error())
+	var request types.DisableAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "Disable Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	tokens := &types.AuthTokens{
-		AccessToken: router.getAccessToken(r),
-	}
+	tokens := router.getTokens(r)
 
-	res, err := router.Authorize.UpdateAccount(tokens, &account)
-	//Some error occured while trying to create the account
+	res, err := router.Authorize.DisableAccount(r.Context(), tokens, &request)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "UpdateAccount Error: "+err.Error())
+		//Check if the error is a JWT related error
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "Disable Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Disable Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
@@ -496,109 +622,1311 @@ func (router Router) updateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Account Updated
+	//Account disabled
 	router.goodRequest(w)
 }
 
-//activateDevice - endpoint to active a device.
-func (router Router) activateDevice(w http.ResponseWriter, r *http.Request) {
+//enable - endpoint to enable a disabled account
+func (router Router) enable(w http.ResponseWriter, r *http.Request) {
+
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
-
-	var deviceRequest types.ActivateDevice
-	if err := json.NewDecoder(r.Body).Decode(&deviceRequest); err != nil {
-		fmt.Fprintln(os.Stderr, "ActivateDevice Error: "+err.Error())
+	var request types.AccountIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "Enable Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	//Check code is not empty.
-	if deviceRequest.Code == "" {
-		fmt.Fprintln(os.Stderr, "ActivateDevice Error: Empty Code")
-		router.errorResponse(w, 406, 5, "Invalid Request")
-	}
-
-	//Get device id from cookie.
-	deviceRequest.DeviceID = router.getDeviceID(r)
+	tokens := router.getTokens(r)
 
-	//Check if activation is good
-	err := router.Authorize.ActivateDevice(&deviceRequest)
+	res, err := router.Authorize.EnableAccount(r.Context(), tokens, &request)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "ActivateDevice Error: "+err.Error())
+		//Check if the error is a JWT related error
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "Enable Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Enable Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	//Request was successful
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	//Account enabled
 	router.goodRequest(w)
 }
 
-//recoverAccount - endpoint to recover a account
-func (router Router) recoverAccount(w http.ResponseWriter, r *http.Request) {
+//refreshToken - Endpoint to refresh an Access Token
+func (router Router) refreshToken(w http.ResponseWriter, r *http.Request) {
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
 
-	var recoveryRequest types.RecoveryRequest
-	if err := json.NewDecoder(r.Body).Decode(&recoveryRequest); err != nil {
-		fmt.Fprintln(os.Stderr, "RecoverAccount Error: "+err.Error())
+	newToken, err := router.Authenticate.RefreshAccessToken(r.Context(), router.getTokens(r))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "RefreshToken Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	err := router.Authorize.RecoverAccount(&recoveryRequest)
+	//Data that will be sent as a response
+	responseInfo := &types.AccessTokenResponse{
+		AccessToken: newToken,
+	}
+
+	//Create the json response
+	data, err := json.Marshal(responseInfo)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "RecoverAccount Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "RefreshToken Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	//Request was successful
-	router.goodRequest(w)
+	w.WriteHeader(200)
+	w.Write(data)
 }
 
-//getRecover - endpoint to get an account recovery
-func (router Router) getRecovery(w http.ResponseWriter, r *http.Request) {
+//reauthenticate - endpoint to log in again with a password or phone code for operations that need a recent login
+func (router Router) reauthenticate(w http.ResponseWriter, r *http.Request) {
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
 
-	var recovery types.Recovery
-	if err := json.NewDecoder(r.Body).Decode(&recovery); err != nil {
-		fmt.Fprintln(os.Stderr, "GetRecovery Error: "+err.Error())
+	var request types.ReauthenticateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "Reauthenticate Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
+	request.Client = router.getClient(r)
 
-	_, err := router.Authorize.GetRecovery(&recovery)
+	token, res, err := router.Authorize.Reauthenticate(r.Context(), router.getTokens(r), &request)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "GetRecovery Error: "+err.Error())
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "Reauthenticate Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Reauthenticate Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	router.goodRequest(w)
-}
-
-//finishRecovery - endpoint to complete an account recovery
-func (router Router) finishRecovery(w http.ResponseWriter, r *http.Request) {
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
 
-	if !router.setUpHeaders(w, r) {
+	data, err := json.Marshal(&types.AccessTokenResponse{AccessToken: token})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Reauthenticate Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//sendReauthenticateCode - endpoint to send a code to re-authenticate with to the verified phone of the requesting account
+func (router Router) sendReauthenticateCode(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.PhoneCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "SendReauthenticateCode Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+	request.Client = router.getClient(r)
+
+	response, res, err := router.Authorize.SendReauthenticateCode(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "SendReauthenticateCode Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "SendReauthenticateCode Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	router.phoneCodeResponse(w, response, res, "SendReauthenticateCode")
+}
+
+//getAccount - Endpoint to get account details of the requesting user.
+func (router Router) getAccount(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	account, err := router.Authorize.GetAccount(r.Context(), router.getTokens(r))
+	if err != nil {
+		//Check if the error is a JWT related error
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "GetAccount Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "GetAccount Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Create the json response
+	data, err := json.Marshal(&account)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetAccount Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//getAccounts - endpoint to list accounts. Filters, sorts and pages by the search provided, role 0 matches all accounts
+func (router Router) getAccounts(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.AccountSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "GetAccounts Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	tokens := router.getTokens(r)
+
+	response, err := router.Authorize.GetAccounts(r.Context(), tokens, &request)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetAccounts Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetAccounts Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+
+}
+
+//updateSettings - endpoint to update account settings
+func (router Router) updateSettings(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+	var account types.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		fmt.Fprintln(os.Stderr, "UpdateSettings Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	tokens := router.getTokens(r)
+
+	res, err := router.Authorize.UpdateSettings(r.Context(), tokens, &account)
+	//Some error occured while trying to create the account
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "Update Settings Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "UpdateSettings Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	//Account Updated
+	router.goodRequest(w)
+}
+
+//updateAccount - endpoint to update another users account settings
+func (router Router) updateAccount(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var account types.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		fmt.Fprintln(os.Stderr, "UpdateAccount Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	tokens := router.getTokens(r)
+
+	res, err := router.Authorize.UpdateAccount(r.Context(), tokens, &account)
+	//Some error occured while trying to create the account
+	if err != nil {
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "UpdateAccount Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "UpdateAccount Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	//Account Updated
+	router.goodRequest(w)
+}
+
+//activateDevice - endpoint to active a device.
+func (router Router) activateDevice(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var deviceRequest types.ActivateDevice
+	if err := json.NewDecoder(r.Body).Decode(&deviceRequest); err != nil {
+		fmt.Fprintln(os.Stderr, "ActivateDevice Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Check code is not empty.
+	if deviceRequest.Code == "" {
+		fmt.Fprintln(os.Stderr, "ActivateDevice Error: Empty Code")
+		router.errorResponse(w, 406, 5, "Invalid Request")
+	}
+
+	//Get device id from cookie.
+	deviceRequest.DeviceID = router.getDeviceID(r)
+	deviceRequest.Client = router.getClient(r)
+
+	//Check if activation is good
+	err := router.Authorize.ActivateDevice(r.Context(), &deviceRequest)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ActivateDevice Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Request was successful
+	router.goodRequest(w)
+}
+
+//sendDevicePhoneCode - endpoint to send the code that activates a device to the verified phone of its account
+func (router Router) sendDevicePhoneCode(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.PhoneCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "SendDevicePhoneCode Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Get device id from cookie.
+	request.DeviceID = router.getDeviceID(r)
+	request.Client = router.getClient(r)
+
+	response, res, err := router.Authorize.SendDevicePhoneCode(r.Context(), &request)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "SendDevicePhoneCode Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	router.phoneCodeResponse(w, response, res, "SendDevicePhoneCode")
+}
+
+//sendPhoneVerification - endpoint to send a code to the phone of the requesting account
+func (router Router) sendPhoneVerification(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.PhoneCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "SendPhoneVerification Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+	request.Client = router.getClient(r)
+
+	response, res, err := router.Authorize.SendPhoneVerification(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "SendPhoneVerification Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "SendPhoneVerification Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	router.phoneCodeResponse(w, response, res, "SendPhoneVerification")
+}
+
+//phoneCodeResponse - writes where a phone code was sent, or the reason it was not
+func (router Router) phoneCodeResponse(w http.ResponseWriter, response *types.PhoneCodeResponse, res string, name string) {
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, name+" Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//verifyPhone - endpoint to verify the phone of the requesting account with the code sent to it
+func (router Router) verifyPhone(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.PhoneVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "VerifyPhone Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+	request.Client = router.getClient(r)
+
+	res, err := router.Authorize.VerifyPhone(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "VerifyPhone Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "VerifyPhone Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//recoverAccount - endpoint to recover a account
+func (router Router) recoverAccount(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var recoveryRequest types.RecoveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&recoveryRequest); err != nil {
+		fmt.Fprintln(os.Stderr, "RecoverAccount Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	recoveryRequest.Client = router.getClient(r)
+
+	err := router.Authorize.RecoverAccount(r.Context(), &recoveryRequest)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "RecoverAccount Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Request was successful
+	router.goodRequest(w)
+}
+
+//getRecover - endpoint to get an account recovery
+func (router Router) getRecovery(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var recovery types.Recovery
+	if err := json.NewDecoder(r.Body).Decode(&recovery); err != nil {
+		fmt.Fprintln(os.Stderr, "GetRecovery Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	_, err := router.Authorize.GetRecovery(r.Context(), &recovery)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetRecovery Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//finishRecovery - endpoint to complete an account recovery
+func (router Router) finishRecovery(w http.ResponseWriter, r *http.Request) {
+
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var recovery types.FinalRecoveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&recovery); err != nil {
+		fmt.Fprintln(os.Stderr, "FinishRecovery Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	recovery.Client = router.getClient(r)
+
+	//Attempt to finish recovery
+	res, err := router.Authorize.FinishRecovery(r.Context(), &recovery)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "FinishRecovery Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//changeAccountPassword - endpoint to update requesting accounts password
+func (router Router) changeAccountPassword(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+	var request types.UpdateAccountPassword
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "ChangePassword Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	tokens := router.getTokens(r)
+
+	res, err := router.Authorize.ChangeAccountPassword(r.Context(), tokens, &request)
+	//Some error occured while trying to create the account
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "ChangePassword Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "ChangePassword Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "ChangePassword Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	//Password Updated
+	router.goodRequest(w)
+}
+
+//searchAudit - endpoint to search the audit log
+func (router Router) searchAudit(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var search types.AuditSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		fmt.Fprintln(os.Stderr, "SearchAudit Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	result, err := router.Authorize.SearchAuditEvents(r.Context(), router.getTokens(r), &search)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "SearchAudit Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "SearchAudit Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "SearchAudit Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//exportAudit - endpoint to export the audit log as JSON lines
+func (router Router) exportAudit(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var search types.AuditSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		fmt.Fprintln(os.Stderr, "ExportAudit Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Stream the events as they are read. Errors before the first event, like an invalid token, still get an error response
+	download := &downloadWriter{w: w, contentType: "application/x-ndjson", filename: "audit.jsonl"}
+	err := router.Authorize.ExportAuditEvents(r.Context(), router.getTokens(r), &search, download)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ExportAudit Error: "+err.Error())
+		if download.started {
+			//Too late for an error response, the download is cut short instead
+			return
+		}
+		if utils.IsExpired(err) {
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Nothing matched, send an empty download
+	download.start()
+}
+
+//downloadWriter - sends the download headers on the first write, so nothing is committed to until there is data
+type downloadWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+//Write - starts the download if needed and writes p to the response
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	d.start()
+	return d.w.Write(p)
+}
+
+//start - writes the download headers once
+func (d *downloadWriter) start() {
+	if d.started {
+		return
+	}
+	d.started = true
+	d.w.Header().Set("Content-Type", d.contentType)
+	d.w.Header().Set("Content-Disposition", "attachment; filename="+d.filename)
+	d.w.WriteHeader(200)
+}
+
+//createWebhook - endpoint to subscribe a url to events
+func (router Router) createWebhook(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "CreateWebhook Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	sub, res, err := router.Authorize.CreateWebhook(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "CreateWebhook Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "CreateWebhook Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "CreateWebhook Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	data, err := json.Marshal(sub)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "CreateWebhook Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//getWebhooks - endpoint to list webhook subscriptions
+func (router Router) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	subs, err := router.Authorize.GetWebhooks(r.Context(), router.getTokens(r))
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "GetWebhooks Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "GetWebhooks Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.Marshal(types.WebhooksResponse{Webhooks: subs})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetWebhooks Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//deleteWebhook - endpoint to remove a webhook subscription
+func (router Router) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.WebhookIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "DeleteWebhook Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	err := router.Authorize.DeleteWebhook(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "DeleteWebhook Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "DeleteWebhook Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//getWebhookDeliveries - endpoint to view the webhook delivery log
+func (router Router) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.WebhookDeliveriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "GetWebhookDeliveries Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	deliveries, err := router.Authorize.GetWebhookDeliveries(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "GetWebhookDeliveries Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "GetWebhookDeliveries Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.Marshal(types.WebhookDeliveriesResponse{Deliveries: deliveries})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetWebhookDeliveries Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//redeliverWebhook - endpoint to queue a webhook delivery again
+func (router Router) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.WebhookIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "RedeliverWebhook Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	err := router.Authorize.RedeliverWebhook(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "RedeliverWebhook Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "RedeliverWebhook Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//importAccounts - endpoint to create accounts in bulk
+func (router Router) importAccounts(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.ImportAccountsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "ImportAccounts Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	report, res, err := router.Authorize.ImportAccounts(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "ImportAccounts Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "ImportAccounts Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ImportAccounts Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//exportAccounts - endpoint to export accounts as CSV or JSON
+func (router Router) exportAccounts(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.ExportAccountsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "ExportAccounts Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Buffer the export so a failure part way through can still return an error response
+	var buf bytes.Buffer
+	err := router.Authorize.ExportAccounts(r.Context(), router.getTokens(r), &request, &buf)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "ExportAccounts Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "ExportAccounts Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "ExportAccounts Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	if request.Format == types.BulkFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename=accounts."+request.Format)
+	w.WriteHeader(200)
+	w.Write(buf.Bytes())
+}
+
+//createInvitation - endpoint to invite an email to register
+func (router Router) createInvitation(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.InvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "CreateInvitation Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	invitation, res, err := router.Authorize.CreateInvitation(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "CreateInvitation Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "CreateInvitation Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	data, err := json.Marshal(invitation)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "CreateInvitation Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//getInvitations - endpoint to list invitations
+func (router Router) getInvitations(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.InvitationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "GetInvitations Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	invitations, err := router.Authorize.GetInvitations(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "GetInvitations Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "GetInvitations Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.Marshal(types.InvitationsResponse{Invitations: invitations})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetInvitations Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//revokeInvitation - endpoint to revoke an unused invitation
+func (router Router) revokeInvitation(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.InvitationIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "RevokeInvitation Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	res, err := router.Authorize.RevokeInvitation(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "RevokeInvitation Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "RevokeInvitation Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//exportData - endpoint to download everything stored about the requesting account
+func (router Router) exportData(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	export, err := router.Authorize.ExportData(r.Context(), router.getTokens(r))
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "ExportData Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "ExportData Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ExportData Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", "attachment; filename=account-data.json")
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//requestErasure - endpoint for an account to ask for all of its data to be erased
+func (router Router) requestErasure(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	res, err := router.Authorize.RequestErasure(r.Context(), router.getTokens(r))
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "RequestErasure Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "RequestErasure Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "RequestErasure Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//confirmErasure - endpoint to confirm an erasure request from the emailed link
+func (router Router) confirmErasure(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var confirm types.ErasureConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&confirm); err != nil {
+		fmt.Fprintln(os.Stderr, "ConfirmErasure Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+	confirm.Client = router.getClient(r)
+
+	res, err := router.Authorize.ConfirmErasure(r.Context(), &confirm)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ConfirmErasure Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//getErasureRequests - endpoint to view the erasure request queue
+func (router Router) getErasureRequests(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.ErasureRequestsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "GetErasureRequests Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	requests, err := router.Authorize.GetErasureRequests(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "GetErasureRequests Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "GetErasureRequests Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.Marshal(types.ErasureRequestsResponse{Requests: requests})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetErasureRequests Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//processErasureRequest - endpoint for an admin to approve or reject an erasure request
+func (router Router) processErasureRequest(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var decision types.ErasureDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+		fmt.Fprintln(os.Stderr, "ProcessErasureRequest Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	res, err := router.Authorize.ProcessErasureRequest(r.Context(), router.getTokens(r), &decision)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "ProcessErasureRequest Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "ProcessErasureRequest Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//createSCIMToken - endpoint to create a token for a SCIM provisioning client
+func (router Router) createSCIMToken(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.SCIMTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "CreateSCIMToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	token, res, err := router.Authorize.CreateSCIMToken(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "CreateSCIMToken Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "CreateSCIMToken Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "CreateSCIMToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "CreateSCIMToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//getSCIMTokens - endpoint to list SCIM tokens
+func (router Router) getSCIMTokens(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
 
-	var recovery types.FinalRecoveryRequest
-	if err := json.NewDecoder(r.Body).Decode(&recovery); err != nil {
-		fmt.Fprintln(os.Stderr, "FinishRecovery Error: "+err.Error())
+	tokens, err := router.Authorize.GetSCIMTokens(r.Context(), router.getTokens(r))
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "GetSCIMTokens Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "GetSCIMTokens Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	//Attempt to finish recovery
-	res, err := router.Authorize.FinishRecovery(&recovery)
+	data, err := json.Marshal(types.SCIMTokensResponse{Tokens: tokens})
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "FinishRecovery Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "GetSCIMTokens Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//revokeSCIMToken - endpoint to revoke a SCIM token
+func (router Router) revokeSCIMToken(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.SCIMTokenIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "RevokeSCIMToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	err := router.Authorize.RevokeSCIMToken(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "RevokeSCIMToken Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "RevokeSCIMToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//createPersonalToken - endpoint to create a personal access token
+func (router Router) createPersonalToken(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.PersonalTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "CreatePersonalToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	token, res, err := router.Authorize.CreatePersonalToken(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "CreatePersonalToken Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "CreatePersonalToken Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "CreatePersonalToken Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
@@ -609,34 +1937,275 @@ func (router Router) finishRecovery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data, err := json.Marshal(token)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "CreatePersonalToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//getPersonalTokens - endpoint to list the personal access tokens of the requesting account
+func (router Router) getPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	tokens, err := router.Authorize.GetPersonalTokens(r.Context(), router.getTokens(r))
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "GetPersonalTokens Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "GetPersonalTokens Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.Marshal(types.PersonalTokensResponse{Tokens: tokens})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetPersonalTokens Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//revokePersonalToken - endpoint to revoke a personal access token
+func (router Router) revokePersonalToken(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.PersonalTokenIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "RevokePersonalToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	err := router.Authorize.RevokePersonalToken(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "RevokePersonalToken Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "RevokePersonalToken Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
 	router.goodRequest(w)
 }
 
-//changeAccountPassword - endpoint to update requesting accounts password
-func (router Router) changeAccountPassword(w http.ResponseWriter, r *http.Request) {
+//impersonate - endpoint for an admin to get a short lived access token for another account
+func (router Router) impersonate(w http.ResponseWriter, r *http.Request) {
 	if !router.setUpHeaders(w, r) {
 		return //request was an OPTIONS which was handled.
 	}
-	var request types.UpdateAccountPassword
+
+	var request types.ImpersonateRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		fmt.Fprintln(os.Stderr, "ChangePassword Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "Impersonate Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
 
-	tokens := &types.AuthTokens{
-		AccessToken: router.getAccessToken(r),
+	response, res, err := router.Authorize.Impersonate(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "Impersonate Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		if utils.IsStepUpRequired(err) {
+			fmt.Fprintln(os.Stderr, "Impersonate Error: "+err.Error())
+			router.errorResponse(w, 403, 11, "Re-authentication required")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Impersonate Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
 	}
 
-	res, err := router.Authorize.ChangeAccountPassword(tokens, &request)
-	//Some error occured while trying to create the account
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Impersonate Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//stopImpersonation - endpoint to end the impersonation the access token belongs to
+func (router Router) stopImpersonation(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	err := router.Authorize.StopImpersonation(r.Context(), router.getTokens(r))
 	if err != nil {
 		if utils.IsExpired(err) {
-			fmt.Fprintln(os.Stderr, "ChangePassword Error: "+err.Error())
+			fmt.Fprintln(os.Stderr, "StopImpersonation Error: "+err.Error())
 			router.errorResponse(w, 401, 10, "Access token is invalid")
 			return
 		}
-		fmt.Fprintln(os.Stderr, "ChangePassword Error: "+err.Error())
+		fmt.Fprintln(os.Stderr, "StopImpersonation Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//getOutbox - endpoint to list queued, sent and dead emails
+func (router Router) getOutbox(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.OutboxRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "GetOutbox Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	emails, err := router.Authorize.GetOutbox(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "GetOutbox Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "GetOutbox Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	data, err := json.Marshal(types.OutboxResponse{Emails: emails})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "GetOutbox Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//retryEmail - endpoint to queue a dead email again
+func (router Router) retryEmail(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.OutboxIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "RetryEmail Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	res, err := router.Authorize.RetryEmail(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "RetryEmail Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "RetryEmail Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	router.goodRequest(w)
+}
+
+//previewEmail - endpoint to render an email template without sending it
+func (router Router) previewEmail(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.EmailPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "PreviewEmail Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	preview, res, err := router.Authorize.PreviewEmail(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "PreviewEmail Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "PreviewEmail Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	//Return a bad response with the reason
+	if res != "" {
+		router.errorResponse(w, 406, 5, res)
+		return
+	}
+
+	data, err := json.Marshal(preview)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "PreviewEmail Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+//sendTestEmail - endpoint to send an email template to an address
+func (router Router) sendTestEmail(w http.ResponseWriter, r *http.Request) {
+	if !router.setUpHeaders(w, r) {
+		return //request was an OPTIONS which was handled.
+	}
+
+	var request types.EmailTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "SendTestEmail Error: "+err.Error())
+		router.errorResponse(w, 406, 5, "Invalid Request")
+		return
+	}
+
+	res, err := router.Authorize.SendTestEmail(r.Context(), router.getTokens(r), &request)
+	if err != nil {
+		if utils.IsExpired(err) {
+			fmt.Fprintln(os.Stderr, "SendTestEmail Error: "+err.Error())
+			router.errorResponse(w, 401, 10, "Access token is invalid")
+			return
+		}
+		fmt.Fprintln(os.Stderr, "SendTestEmail Error: "+err.Error())
 		router.errorResponse(w, 406, 5, "Invalid Request")
 		return
 	}
@@ -647,6 +2216,5 @@ func (router Router) changeAccountPassword(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	//Password Updated
 	router.goodRequest(w)
 }
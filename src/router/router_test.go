@@ -0,0 +1,37 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		proxyCount int
+		forwarded  []string
+		ip         string
+	}{
+		{name: "no proxy", forwarded: []string{"203.0.113.7"}, ip: "192.0.2.1"},
+		{name: "one proxy", trustProxy: true, proxyCount: 1, forwarded: []string{"203.0.113.7"}, ip: "203.0.113.7"},
+		{name: "spoofed entry", trustProxy: true, proxyCount: 1, forwarded: []string{"10.0.0.1, 203.0.113.7"}, ip: "203.0.113.7"},
+		{name: "two proxies", trustProxy: true, proxyCount: 2, forwarded: []string{"10.0.0.1, 203.0.113.7, 198.51.100.2"}, ip: "203.0.113.7"},
+		{name: "repeated headers", trustProxy: true, proxyCount: 2, forwarded: []string{"10.0.0.1", "203.0.113.7, 198.51.100.2"}, ip: "203.0.113.7"},
+		{name: "fewer entries than proxies", trustProxy: true, proxyCount: 2, forwarded: []string{"203.0.113.7"}, ip: "192.0.2.1"},
+		{name: "no header", trustProxy: true, proxyCount: 1, ip: "192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/api/auth/login", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		for _, forwarded := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", forwarded)
+		}
+
+		router := Router{TrustProxy: test.trustProxy, ProxyCount: test.proxyCount}
+		if ip := router.getClient(r).IP; ip != test.ip {
+			t.Errorf("%s: got %s, expected %s", test.name, ip, test.ip)
+		}
+	}
+}
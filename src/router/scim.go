@@ -0,0 +1,300 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"scim"
+	"strconv"
+	"strings"
+	"types"
+
+	"github.com/gorilla/mux"
+)
+
+//scimHandler - a SCIM endpoint that has been given a valid SCIM token
+type scimHandler func(w http.ResponseWriter, r *http.Request, token *types.SCIMToken)
+
+//setUpSCIMRoutes - sets up the SCIM 2.0 endpoints. Discovery endpoints do not need a token
+func (router Router) setUpSCIMRoutes(s *mux.Router) {
+	s.HandleFunc("/Users", router.scimAuth(router.getSCIMUsers)).Methods(http.MethodGet)
+	s.HandleFunc("/Users", router.scimAuth(router.createSCIMUser)).Methods(http.MethodPost)
+	s.HandleFunc("/Users/{id}", router.scimAuth(router.getSCIMUser)).Methods(http.MethodGet)
+	s.HandleFunc("/Users/{id}", router.scimAuth(router.replaceSCIMUser)).Methods(http.MethodPut)
+	s.HandleFunc("/Users/{id}", router.scimAuth(router.patchSCIMUser)).Methods(http.MethodPatch)
+	s.HandleFunc("/Users/{id}", router.scimAuth(router.deleteSCIMUser)).Methods(http.MethodDelete)
+	s.HandleFunc("/Groups", router.scimAuth(router.getSCIMGroups)).Methods(http.MethodGet)
+	s.HandleFunc("/Groups", router.scimAuth(router.createSCIMGroup)).Methods(http.MethodPost)
+	s.HandleFunc("/Groups/{id}", router.scimAuth(router.getSCIMGroup)).Methods(http.MethodGet)
+	s.HandleFunc("/Groups/{id}", router.scimAuth(router.replaceSCIMGroup)).Methods(http.MethodPut)
+	s.HandleFunc("/Groups/{id}", router.scimAuth(router.patchSCIMGroup)).Methods(http.MethodPatch)
+	s.HandleFunc("/Groups/{id}", router.scimAuth(router.deleteSCIMGroup)).Methods(http.MethodDelete)
+	s.HandleFunc("/ServiceProviderConfig", router.getSCIMServiceProviderConfig).Methods(http.MethodGet)
+	s.HandleFunc("/ResourceTypes", router.getSCIMResourceTypes).Methods(http.MethodGet)
+	s.HandleFunc("/ResourceTypes/{id}", router.getSCIMResourceTypes).Methods(http.MethodGet)
+	s.HandleFunc("/Schemas", router.getSCIMSchemas).Methods(http.MethodGet)
+	s.HandleFunc("/Schemas/{id}", router.getSCIMSchemas).Methods(http.MethodGet)
+}
+
+//-----------------SCIM HELPERS BELOW-----------------\\
+
+//scimAuth - only calls the handler with a valid SCIM token
+func (router Router) scimAuth(handler scimHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := router.SCIM.Authenticate(r.Context(), router.getAccessToken(r))
+		if err != nil {
+			router.scimError(w, err)
+			return
+		}
+		if token == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="SCIM"`)
+			router.scimError(w, types.NewSCIMError(401, "", "A valid SCIM token is required"))
+			return
+		}
+
+		handler(w, r, token)
+	}
+}
+
+//scimResponse - returns a SCIM resource
+func (router Router) scimResponse(w http.ResponseWriter, httpStatusCode int, resource interface{}) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(httpStatusCode)
+	w.Write(data)
+}
+
+//scimError - returns a SCIM error. Anything that is not a SCIM error is logged and hidden behind a 500
+func (router Router) scimError(w http.ResponseWriter, err error) {
+	scimErr, ok := err.(*types.SCIMError)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "SCIM Error: "+err.Error())
+		scimErr = types.NewSCIMError(500, "", "Internal Server Error")
+	}
+
+	data, err := json.Marshal(scimErr)
+	if err != nil {
+		w.Write([]byte("BACKEND ERROR"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(scimErr.StatusCode)
+	w.Write(data)
+}
+
+//scimDecode - reads a SCIM request body
+func (router Router) scimDecode(w http.ResponseWriter, r *http.Request, body interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		router.scimError(w, types.NewSCIMError(400, types.SCIMInvalidSyntax, "Invalid request body: "+err.Error()))
+		return false
+	}
+	return true
+}
+
+//scimListRequest - reads the filter and paging query parameters
+func (router Router) scimListRequest(r *http.Request) *types.SCIMListRequest {
+	query := r.URL.Query()
+	request := &types.SCIMListRequest{Filter: query.Get("filter"), StartIndex: 1, Count: types.SCIMDefaultPageLimit}
+	if start, err := strconv.Atoi(query.Get("startIndex")); err == nil {
+		request.StartIndex = start
+	}
+	if count, err := strconv.Atoi(query.Get("count")); err == nil {
+		request.Count = count
+	}
+	return request
+}
+
+//excludeMembers - checks if a group request asks for members to be left out
+func (router Router) excludeMembers(r *http.Request) bool {
+	for _, attribute := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attribute), "members") {
+			return true
+		}
+	}
+	return false
+}
+
+//-----------------SCIM ENDPOINTS BELOW-----------------\\
+
+//getSCIMUsers - endpoint to list and filter users
+func (router Router) getSCIMUsers(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	users, err := router.SCIM.GetUsers(r.Context(), token, router.scimListRequest(r))
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, users)
+}
+
+//getSCIMUser - endpoint to get a user
+func (router Router) getSCIMUser(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	user, err := router.SCIM.GetUser(r.Context(), token, mux.Vars(r)["id"])
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, user)
+}
+
+//createSCIMUser - endpoint to provision a user
+func (router Router) createSCIMUser(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	var user types.SCIMUser
+	if !router.scimDecode(w, r, &user) {
+		return
+	}
+
+	created, err := router.SCIM.CreateUser(r.Context(), token, router.getClient(r), &user)
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	w.Header().Set("Location", created.Meta.Location)
+	router.scimResponse(w, 201, created)
+}
+
+//replaceSCIMUser - endpoint to replace a user
+func (router Router) replaceSCIMUser(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	var user types.SCIMUser
+	if !router.scimDecode(w, r, &user) {
+		return
+	}
+
+	replaced, err := router.SCIM.ReplaceUser(r.Context(), token, router.getClient(r), mux.Vars(r)["id"], &user)
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, replaced)
+}
+
+//patchSCIMUser - endpoint to change some attributes of a user
+func (router Router) patchSCIMUser(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	var patch types.SCIMPatchRequest
+	if !router.scimDecode(w, r, &patch) {
+		return
+	}
+
+	patched, err := router.SCIM.PatchUser(r.Context(), token, router.getClient(r), mux.Vars(r)["id"], &patch)
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, patched)
+}
+
+//deleteSCIMUser - endpoint to deprovision a user
+func (router Router) deleteSCIMUser(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	err := router.SCIM.DeleteUser(r.Context(), token, router.getClient(r), mux.Vars(r)["id"])
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+//getSCIMGroups - endpoint to list and filter groups
+func (router Router) getSCIMGroups(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	groups, err := router.SCIM.GetGroups(r.Context(), token, router.scimListRequest(r), router.excludeMembers(r))
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, groups)
+}
+
+//getSCIMGroup - endpoint to get a group
+func (router Router) getSCIMGroup(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	group, err := router.SCIM.GetGroup(r.Context(), token, mux.Vars(r)["id"], router.excludeMembers(r))
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, group)
+}
+
+//createSCIMGroup - endpoint to create a group, which always fails as groups are the fixed roles
+func (router Router) createSCIMGroup(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	var group types.SCIMGroup
+	if !router.scimDecode(w, r, &group) {
+		return
+	}
+
+	router.scimError(w, router.SCIM.CreateGroup(r.Context(), token, router.getClient(r), &group))
+}
+
+//replaceSCIMGroup - endpoint to replace the members of a group
+func (router Router) replaceSCIMGroup(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	var group types.SCIMGroup
+	if !router.scimDecode(w, r, &group) {
+		return
+	}
+
+	replaced, err := router.SCIM.ReplaceGroup(r.Context(), token, router.getClient(r), mux.Vars(r)["id"], &group)
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, replaced)
+}
+
+//patchSCIMGroup - endpoint to add or remove group members
+func (router Router) patchSCIMGroup(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	var patch types.SCIMPatchRequest
+	if !router.scimDecode(w, r, &patch) {
+		return
+	}
+
+	patched, err := router.SCIM.PatchGroup(r.Context(), token, router.getClient(r), mux.Vars(r)["id"], &patch)
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, patched)
+}
+
+//deleteSCIMGroup - endpoint to delete a group, which always fails as groups are the fixed roles
+func (router Router) deleteSCIMGroup(w http.ResponseWriter, r *http.Request, token *types.SCIMToken) {
+	router.scimError(w, router.SCIM.DeleteGroup(r.Context(), token, router.getClient(r), mux.Vars(r)["id"]))
+}
+
+//getSCIMServiceProviderConfig - endpoint describing the SCIM features supported
+func (router Router) getSCIMServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	router.scimResponse(w, 200, router.SCIM.ServiceProviderConfig())
+}
+
+//getSCIMResourceTypes - endpoint to list the resource types, or get one by id
+func (router Router) getSCIMResourceTypes(w http.ResponseWriter, r *http.Request) {
+	router.scimDiscovery(w, r, router.SCIM.ResourceTypes())
+}
+
+//getSCIMSchemas - endpoint to list the schemas, or get one by id
+func (router Router) getSCIMSchemas(w http.ResponseWriter, r *http.Request) {
+	router.scimDiscovery(w, r, router.SCIM.Schemas())
+}
+
+//scimDiscovery - returns a list of discovery resources, or the one named in the path
+func (router Router) scimDiscovery(w http.ResponseWriter, r *http.Request, resources []interface{}) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		router.scimResponse(w, 200, types.SCIMListResponse{
+			Schemas:      []string{types.SCIMListSchema},
+			TotalResults: len(resources),
+			StartIndex:   1,
+			ItemsPerPage: len(resources),
+			Resources:    resources,
+		})
+		return
+	}
+
+	resource, err := scim.Find(resources, id)
+	if err != nil {
+		router.scimError(w, err)
+		return
+	}
+	router.scimResponse(w, 200, resource)
+}
@@ -0,0 +1,385 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+	"types"
+)
+
+//filter - a parsed SCIM filter expression
+type filter interface {
+	matches(resource map[string]interface{}) bool
+}
+
+//andFilter - both sides match
+type andFilter struct {
+	left  filter
+	right filter
+}
+
+func (f andFilter) matches(resource map[string]interface{}) bool {
+	return f.left.matches(resource) && f.right.matches(resource)
+}
+
+//orFilter - either side matches
+type orFilter struct {
+	left  filter
+	right filter
+}
+
+func (f orFilter) matches(resource map[string]interface{}) bool {
+	return f.left.matches(resource) || f.right.matches(resource)
+}
+
+//notFilter - the inner filter does not match
+type notFilter struct {
+	inner filter
+}
+
+func (f notFilter) matches(resource map[string]interface{}) bool {
+	return !f.inner.matches(resource)
+}
+
+//compareFilter - an attribute compared to a value, such as userName eq "bob@example.com"
+type compareFilter struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (f compareFilter) matches(resource map[string]interface{}) bool {
+	values := resolve(resource, f.path)
+
+	switch f.op {
+	case "pr":
+		for _, v := range values {
+			if v != nil && v != "" {
+				return true
+			}
+		}
+		return false
+	case "ne":
+		for _, v := range values {
+			if compare(f.path, v, "eq", f.value) {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, v := range values {
+			if compare(f.path, v, f.op, f.value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//compare - compares a resource value to a filter value. Strings compare without case except for ids
+func compare(path []string, have interface{}, op string, want interface{}) bool {
+	switch w := want.(type) {
+	case string:
+		h, ok := have.(string)
+		if !ok {
+			return false
+		}
+		last := strings.ToLower(path[len(path)-1])
+		if last != "id" && last != "externalid" && last != "value" {
+			h = strings.ToLower(h)
+			w = strings.ToLower(w)
+		}
+		switch op {
+		case "eq":
+			return h == w
+		case "co":
+			return strings.Contains(h, w)
+		case "sw":
+			return strings.HasPrefix(h, w)
+		case "ew":
+			return strings.HasSuffix(h, w)
+		case "gt":
+			return h > w
+		case "ge":
+			return h >= w
+		case "lt":
+			return h < w
+		case "le":
+			return h <= w
+		}
+	case bool:
+		h, ok := have.(bool)
+		return ok && op == "eq" && h == w
+	case float64:
+		h, ok := have.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return h == w
+		case "gt":
+			return h > w
+		case "ge":
+			return h >= w
+		case "lt":
+			return h < w
+		case "le":
+			return h <= w
+		}
+	case nil:
+		return op == "eq" && have == nil
+	}
+	return false
+}
+
+//resolve - returns every value at the attribute path. Names match without case and multi valued attributes are flattened,
+//so emails and emails.value both return every email address
+func resolve(resource map[string]interface{}, path []string) []interface{} {
+	current := []interface{}{resource}
+	for _, name := range path {
+		next := []interface{}{}
+		for _, item := range current {
+			object, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, ok := findKey(object, name)
+			if !ok {
+				continue
+			}
+			if list, ok := object[key].([]interface{}); ok {
+				next = append(next, list...)
+			} else {
+				next = append(next, object[key])
+			}
+		}
+		current = next
+	}
+
+	//A complex value on its own means its value sub attribute
+	values := []interface{}{}
+	for _, item := range current {
+		if object, ok := item.(map[string]interface{}); ok {
+			if key, ok := findKey(object, "value"); ok {
+				values = append(values, object[key])
+			}
+			continue
+		}
+		values = append(values, item)
+	}
+	return values
+}
+
+//findKey - returns the key of the object that matches the name without case
+func findKey(object map[string]interface{}, name string) (string, bool) {
+	if _, ok := object[name]; ok {
+		return name, true
+	}
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+//splitPath - splits an attribute path into names. Core schema prefixes are dropped and extension schemas are kept as the first name
+func splitPath(path string) []string {
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		i := strings.LastIndex(path, ":")
+		urn, attribute := path[:i], path[i+1:]
+		if strings.EqualFold(urn, types.SCIMUserSchema) || strings.EqualFold(urn, types.SCIMGroupSchema) {
+			return strings.Split(attribute, ".")
+		}
+		return append([]string{urn}, strings.Split(attribute, ".")...)
+	}
+	return strings.Split(path, ".")
+}
+
+//parseFilter - parses a filter such as userName eq "bob@example.com" and active eq true.
+//Supports and, or, not, parentheses and every comparison operator, but not filters on values of multi valued attributes
+func parseFilter(expression string) (filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, invalidFilter("Unexpected " + p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+//filterToken - a word, quoted string or parenthesis in a filter
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+//tokenize - splits a filter into tokens
+func tokenize(expression string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, filterToken{text: string(c)})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, invalidFilter("Unterminated string")
+			}
+			var text string
+			if err := json.Unmarshal([]byte(expression[i:end+1]), &text); err != nil {
+				return nil, invalidFilter("Invalid string " + expression[i:end+1])
+			}
+			tokens = append(tokens, filterToken{text: text, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(expression) && !strings.ContainsRune(" \t()\"", rune(expression[end])) {
+				if expression[end] == '[' {
+					return nil, invalidFilter("Filters on values of multi valued attributes are not supported")
+				}
+				end++
+			}
+			tokens = append(tokens, filterToken{text: expression[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+//filterParser - recursive descent parser over filter tokens
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+//keyword - checks if the next token is the unquoted keyword given, and consumes it if so
+func (p *filterParser) keyword(word string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseFactor() (filter, error) {
+	if p.keyword("not") {
+		inner, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{inner: inner}, nil
+	}
+
+	if p.keyword("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, invalidFilter("Missing )")
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filter, error) {
+	if p.pos+1 >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return nil, invalidFilter("Expected an attribute and operator")
+	}
+	path := splitPath(p.tokens[p.pos].text)
+	op := strings.ToLower(p.tokens[p.pos+1].text)
+	p.pos += 2
+
+	switch op {
+	case "pr":
+		return compareFilter{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, invalidFilter("Unknown operator " + op)
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, invalidFilter("Expected a value after " + op)
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+
+	if token.quoted {
+		return compareFilter{path: path, op: op, value: token.text}, nil
+	}
+
+	//Unquoted values are true, false, null or numbers
+	var value interface{}
+	if err := json.Unmarshal([]byte(token.text), &value); err != nil {
+		return nil, invalidFilter("Invalid value " + token.text)
+	}
+	if _, ok := value.(string); ok {
+		return nil, invalidFilter("Invalid value " + token.text)
+	}
+	return compareFilter{path: path, op: op, value: value}, nil
+}
+
+//equalsValue - returns the attribute and value of a filter that is a single eq comparison
+func equalsValue(f filter) ([]string, string, bool) {
+	c, ok := f.(compareFilter)
+	if !ok || c.op != "eq" {
+		return nil, "", false
+	}
+	value, ok := c.value.(string)
+	return c.path, value, ok
+}
+
+//invalidFilter - returns a SCIM invalid filter error
+func invalidFilter(detail string) error {
+	return types.NewSCIMError(400, types.SCIMInvalidFilter, detail)
+}
@@ -0,0 +1,247 @@
+package scim
+
+import (
+	"context"
+	"dao"
+	"db"
+	"fmt"
+	"strings"
+	"types"
+)
+
+//groupRoles - the groups SCIM clients see, one per role. Every account is in DEFAULT, admins are in ADMIN too
+var groupRoles = map[string]int{"ADMIN": 999, "DEFAULT": 100}
+
+//groupNames - the group names in the order they are listed
+var groupNames = []string{"ADMIN", "DEFAULT"}
+
+//GetGroups - returns the groups matching the filter. Members are left out when excludeMembers is set
+func (p *Provisioner) GetGroups(ctx context.Context, token *types.SCIMToken, request *types.SCIMListRequest, excludeMembers bool) (*types.SCIMListResponse, error) {
+	var f filter
+	if request.Filter != "" {
+		var err error
+		if f, err = parseFilter(request.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	groups := []interface{}{}
+	for _, name := range groupNames {
+		group, err := p.getGroup(ctx, token, name, excludeMembers, p.DB)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			resource, err := toMap(group)
+			if err != nil {
+				return nil, err
+			}
+			if !f.matches(resource) {
+				continue
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	return page(groups, request), nil
+}
+
+//GetGroup - returns a group and its members in the organization
+func (p *Provisioner) GetGroup(ctx context.Context, token *types.SCIMToken, id string, excludeMembers bool) (*types.SCIMGroup, error) {
+	return p.getGroup(ctx, token, id, excludeMembers, p.DB)
+}
+
+//CreateGroup - groups are fixed to the roles, so this only explains why one cannot be created
+func (p *Provisioner) CreateGroup(ctx context.Context, token *types.SCIMToken, client types.Client, group *types.SCIMGroup) (err error) {
+	event := p.newEvent(types.AuditSCIMCreate, token, client)
+	event.TargetID = group.DisplayName
+	defer func() { p.record(event, err) }()
+
+	if _, ok := groupRoles[strings.ToUpper(group.DisplayName)]; ok {
+		return types.NewSCIMError(409, types.SCIMUniqueness, "Group "+group.DisplayName+" already exists")
+	}
+	return types.NewSCIMError(400, types.SCIMInvalidValue, "Only the ADMIN and DEFAULT groups are supported")
+}
+
+//ReplaceGroup - sets the members of a group. Only ADMIN membership can change, which promotes and demotes accounts, and only with a token that manages admins
+func (p *Provisioner) ReplaceGroup(ctx context.Context, token *types.SCIMToken, client types.Client, id string, group *types.SCIMGroup) (replaced *types.SCIMGroup, err error) {
+	event := p.newEvent(types.AuditSCIMUpdate, token, client)
+	event.TargetID = id
+	defer func() { p.record(event, err) }()
+
+	err = p.DB.InTx(ctx, func(tx db.Store) error {
+		current, err := p.getGroup(ctx, token, id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		event.Detail, err = p.setMembers(ctx, token, current, group, tx)
+		if err != nil {
+			return err
+		}
+
+		replaced, err = p.getGroup(ctx, token, id, false, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return replaced, nil
+}
+
+//PatchGroup - applies PATCH operations to a group, usually adding or removing members
+func (p *Provisioner) PatchGroup(ctx context.Context, token *types.SCIMToken, client types.Client, id string, patch *types.SCIMPatchRequest) (patched *types.SCIMGroup, err error) {
+	event := p.newEvent(types.AuditSCIMUpdate, token, client)
+	event.TargetID = id
+	defer func() { p.record(event, err) }()
+
+	err = p.DB.InTx(ctx, func(tx db.Store) error {
+		current, err := p.getGroup(ctx, token, id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		resource, err := toMap(current)
+		if err != nil {
+			return err
+		}
+		if err := applyPatch(resource, patch.Operations); err != nil {
+			return err
+		}
+
+		group := &types.SCIMGroup{}
+		if err := fromMap(resource, group); err != nil {
+			return err
+		}
+
+		event.Detail, err = p.setMembers(ctx, token, current, group, tx)
+		if err != nil {
+			return err
+		}
+
+		patched, err = p.getGroup(ctx, token, id, false, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
+
+//DeleteGroup - groups are fixed to the roles, so this only explains why one cannot be deleted
+func (p *Provisioner) DeleteGroup(ctx context.Context, token *types.SCIMToken, client types.Client, id string) (err error) {
+	event := p.newEvent(types.AuditSCIMDelete, token, client)
+	event.TargetID = id
+	defer func() { p.record(event, err) }()
+
+	if _, ok := groupRoles[id]; !ok {
+		return notFound("Group", id)
+	}
+	return types.NewSCIMError(400, types.SCIMMutability, "Group "+id+" cannot be deleted")
+}
+
+//setMembers - promotes and demotes accounts so the group has the members given. Returns what changed for the audit log
+func (p *Provisioner) setMembers(ctx context.Context, token *types.SCIMToken, current *types.SCIMGroup, group *types.SCIMGroup, tx db.Store) (string, error) {
+	if group.DisplayName != "" && group.DisplayName != current.DisplayName {
+		return "", types.NewSCIMError(400, types.SCIMMutability, "Group names cannot be changed")
+	}
+
+	want := map[string]bool{}
+	for _, member := range group.Members {
+		want[member.Value] = true
+	}
+	have := map[string]bool{}
+	for _, member := range current.Members {
+		have[member.Value] = true
+	}
+
+	added := []string{}
+	for id := range want {
+		if !have[id] {
+			added = append(added, id)
+		}
+	}
+	removed := []string{}
+	for id := range have {
+		if !want[id] {
+			removed = append(removed, id)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return "", nil
+	}
+
+	if current.ID != "ADMIN" {
+		return "", types.NewSCIMError(400, types.SCIMMutability, "Every account is a member of "+current.ID)
+	}
+	//ADMIN can manage every organization, not just the one of the token
+	if !token.ManageAdmins {
+		return "", types.NewSCIMError(403, "", "This token cannot change the members of ADMIN")
+	}
+
+	for _, id := range added {
+		account, err := p.getAccount(ctx, token, id, true, tx)
+		if err != nil {
+			if _, ok := err.(*types.SCIMError); ok {
+				return "", types.NewSCIMError(400, types.SCIMInvalidValue, "Member "+id+" not found")
+			}
+			return "", err
+		}
+		if err := (dao.SCIMDAO{}).SetRole(ctx, account, groupRoles["ADMIN"], tx); err != nil {
+			return "", err
+		}
+	}
+
+	for _, id := range removed {
+		account, err := p.getAccount(ctx, token, id, true, tx)
+		if err != nil {
+			return "", err
+		}
+		if err := (dao.SCIMDAO{}).SetRole(ctx, account, groupRoles["DEFAULT"], tx); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("added %d, removed %d", len(added), len(removed)), nil
+}
+
+//getGroup - returns a group with its members in the organization
+func (p *Provisioner) getGroup(ctx context.Context, token *types.SCIMToken, id string, excludeMembers bool, db db.Store) (*types.SCIMGroup, error) {
+	role, ok := groupRoles[id]
+	if !ok {
+		return nil, notFound("Group", id)
+	}
+
+	group := &types.SCIMGroup{
+		Schemas:     []string{types.SCIMGroupSchema},
+		ID:          id,
+		DisplayName: id,
+		Members:     []types.SCIMMember{},
+		Meta:        &types.SCIMMeta{ResourceType: "Group", Location: p.BaseURL + "/Groups/" + id},
+	}
+	if excludeMembers {
+		group.Members = nil
+		return group, nil
+	}
+
+	search := &types.AccountSearchRequest{Organization: token.Organization, Sort: types.AccountSortCreated}
+	if role == groupRoles["ADMIN"] {
+		search.Roles = []int{role}
+	}
+
+	err := dao.AccountDAO{}.EachAccount(ctx, search, func(account *types.Account) error {
+		group.Members = append(group.Members, types.SCIMMember{
+			Value:   account.ID,
+			Ref:     p.BaseURL + "/Users/" + account.ID,
+			Display: account.Email,
+		})
+		return nil
+	}, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
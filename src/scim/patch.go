@@ -0,0 +1,255 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+	"types"
+)
+
+//patchPath - a PATCH target such as name.givenName or emails[type eq "work"].value
+type patchPath struct {
+	names []string
+	//filter - selects values of a multi valued attribute, nil for a plain path
+	filter filter
+	//sub - sub attribute of the selected values, empty for the values themselves
+	sub string
+}
+
+//parsePatchPath - parses a PATCH path
+func parsePatchPath(path string) (*patchPath, error) {
+	open := strings.Index(path, "[")
+	if open < 0 {
+		return &patchPath{names: splitPath(path)}, nil
+	}
+
+	end := strings.Index(path, "]")
+	if end < open {
+		return nil, types.NewSCIMError(400, types.SCIMInvalidPath, "Invalid path "+path)
+	}
+	f, err := parseFilter(path[open+1 : end])
+	if err != nil {
+		return nil, err
+	}
+
+	p := &patchPath{names: splitPath(path[:open]), filter: f}
+	if rest := path[end+1:]; rest != "" {
+		if !strings.HasPrefix(rest, ".") || strings.Contains(rest[1:], ".") {
+			return nil, types.NewSCIMError(400, types.SCIMInvalidPath, "Invalid path "+path)
+		}
+		p.sub = rest[1:]
+	}
+	return p, nil
+}
+
+//applyPatch - applies PATCH operations to the JSON form of a resource
+func applyPatch(resource map[string]interface{}, operations []types.SCIMPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return types.NewSCIMError(400, types.SCIMInvalidSyntax, "Unknown operation "+operation.Op)
+		}
+
+		var value interface{}
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return types.NewSCIMError(400, types.SCIMInvalidSyntax, "Invalid value for "+operation.Path)
+			}
+		}
+
+		if operation.Path == "" {
+			if op == "remove" {
+				return types.NewSCIMError(400, types.SCIMNoTarget, "Remove requires a path")
+			}
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return types.NewSCIMError(400, types.SCIMInvalidValue, "A value without a path must be an object")
+			}
+			if err := patchObject(resource, op, "", object); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := patchAttribute(resource, op, operation.Path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//patchObject - applies every attribute of a value sent without a path. Extension schemas nest their attributes under the schema URN
+func patchObject(resource map[string]interface{}, op string, prefix string, object map[string]interface{}) error {
+	for key, value := range object {
+		if nested, ok := value.(map[string]interface{}); ok && strings.EqualFold(key, types.SCIMEnterpriseUserSchema) {
+			if err := patchObject(resource, op, key+":", nested); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := patchAttribute(resource, op, prefix+key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//patchAttribute - applies one operation to the attribute at path
+func patchAttribute(resource map[string]interface{}, op string, path string, value interface{}) error {
+	target, err := parsePatchPath(path)
+	if err != nil {
+		return err
+	}
+
+	//Find the object holding the attribute, creating it when adding
+	parent := resource
+	for _, name := range target.names[:len(target.names)-1] {
+		key, ok := findKey(parent, name)
+		if !ok {
+			if op == "remove" {
+				return nil
+			}
+			key = name
+			parent[key] = map[string]interface{}{}
+		}
+		next, ok := parent[key].(map[string]interface{})
+		if !ok {
+			return types.NewSCIMError(400, types.SCIMInvalidPath, "Invalid path "+path)
+		}
+		parent = next
+	}
+
+	name := target.names[len(target.names)-1]
+	key, ok := findKey(parent, name)
+	if !ok {
+		key = name
+	}
+
+	if target.filter != nil {
+		return patchValues(parent, key, op, target, value)
+	}
+
+	existing, isList := parent[key].([]interface{})
+	switch op {
+	case "add":
+		if isList {
+			if values, ok := value.([]interface{}); ok {
+				parent[key] = append(existing, values...)
+			} else {
+				parent[key] = append(existing, value)
+			}
+			return nil
+		}
+		parent[key] = value
+	case "replace":
+		parent[key] = value
+	case "remove":
+		values, ok := value.([]interface{})
+		if !isList || !ok {
+			delete(parent, key)
+			return nil
+		}
+		//Remove only the values given, eg: members to take out of a group
+		kept := []interface{}{}
+		for _, item := range existing {
+			if !containsValue(values, item) {
+				kept = append(kept, item)
+			}
+		}
+		parent[key] = kept
+	}
+	return nil
+}
+
+//patchValues - applies an operation to the values of a multi valued attribute that match the path filter
+func patchValues(parent map[string]interface{}, key string, op string, target *patchPath, value interface{}) error {
+	existing, _ := parent[key].([]interface{})
+
+	matched := false
+	kept := []interface{}{}
+	for _, item := range existing {
+		object, ok := item.(map[string]interface{})
+		if !ok || !target.filter.matches(object) {
+			kept = append(kept, item)
+			continue
+		}
+		matched = true
+
+		switch {
+		case op == "remove" && target.sub == "":
+			continue
+		case op == "remove":
+			if subKey, ok := findKey(object, target.sub); ok {
+				delete(object, subKey)
+			}
+		case target.sub != "":
+			subKey, ok := findKey(object, target.sub)
+			if !ok {
+				subKey = target.sub
+			}
+			object[subKey] = value
+		default:
+			replacement, ok := value.(map[string]interface{})
+			if !ok {
+				return types.NewSCIMError(400, types.SCIMInvalidValue, "Value for "+key+" must be an object")
+			}
+			if op == "replace" {
+				object = map[string]interface{}{}
+			}
+			for k, v := range replacement {
+				object[k] = v
+			}
+		}
+		kept = append(kept, object)
+	}
+
+	if !matched {
+		if op == "remove" {
+			return nil
+		}
+
+		//Adding to a value that does not exist yet creates it, eg: phoneNumbers[type eq "work"].value
+		path, match, ok := equalsValue(target.filter)
+		if op != "add" || target.sub == "" || !ok || len(path) != 1 {
+			return types.NewSCIMError(400, types.SCIMNoTarget, "No values of "+key+" match the filter")
+		}
+		kept = append(kept, map[string]interface{}{path[0]: match, target.sub: value})
+	}
+
+	parent[key] = kept
+	return nil
+}
+
+//containsValue - checks if a value, or a complex value with the same value sub attribute, is in the list
+func containsValue(values []interface{}, item interface{}) bool {
+	want := resolve(map[string]interface{}{"v": item}, []string{"v"})
+	for _, value := range values {
+		have := resolve(map[string]interface{}{"v": value}, []string{"v"})
+		if len(want) == 1 && len(have) == 1 && want[0] == have[0] {
+			return true
+		}
+	}
+	return false
+}
+
+//toMap - returns the JSON form of a resource for filtering and patching
+func toMap(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	object := map[string]interface{}{}
+	err = json.Unmarshal(data, &object)
+	return object, err
+}
+
+//fromMap - reads a patched JSON form back into a resource
+func fromMap(object map[string]interface{}, resource interface{}) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, resource); err != nil {
+		return types.NewSCIMError(400, types.SCIMInvalidValue, "Patched resource is not valid: "+err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package scim
+
+import (
+	"types"
+)
+
+//attribute - describes an attribute in a schema
+type attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []attribute `json:"subAttributes,omitempty"`
+}
+
+//schema - a schema definition served from /Schemas
+type schema struct {
+	Schemas     []string       `json:"schemas"`
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Attributes  []attribute    `json:"attributes"`
+	Meta        types.SCIMMeta `json:"meta"`
+}
+
+//resourceType - a resource type served from /ResourceTypes
+type resourceType struct {
+	Schemas          []string          `json:"schemas"`
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Description      string            `json:"description"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []schemaExtension `json:"schemaExtensions"`
+	Meta             types.SCIMMeta    `json:"meta"`
+}
+
+//schemaExtension - an extension schema of a resource type
+type schemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+//supported - a feature of the service provider that is either supported or not
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+//filterSupport - filtering support of the service provider
+type filterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+//authenticationScheme - how clients authenticate
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+//serviceProviderConfig - the features of this service provider
+type serviceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 supported              `json:"patch"`
+	Bulk                  supported              `json:"bulk"`
+	Filter                filterSupport          `json:"filter"`
+	ChangePassword        supported              `json:"changePassword"`
+	Sort                  supported              `json:"sort"`
+	Etag                  supported              `json:"etag"`
+	AuthenticationSchemes []authenticationScheme `json:"authenticationSchemes"`
+	Meta                  types.SCIMMeta         `json:"meta"`
+}
+
+//simple - returns a single valued string attribute
+func simple(name string, required bool, mutability string, uniqueness string) attribute {
+	return attribute{Name: name, Type: "string", Required: required, Mutability: mutability, Returned: "default", Uniqueness: uniqueness}
+}
+
+//multi - returns a multi valued complex attribute with the sub attributes given
+func multi(name string, mutability string, subAttributes ...attribute) attribute {
+	return attribute{Name: name, Type: "complex", MultiValued: true, Mutability: mutability, Returned: "default", Uniqueness: "none", SubAttributes: subAttributes}
+}
+
+//ServiceProviderConfig - returns the features this service provider supports
+func (p *Provisioner) ServiceProviderConfig() interface{} {
+	return serviceProviderConfig{
+		Schemas:        []string{types.SCIMServiceProviderSchema},
+		Patch:          supported{Supported: true},
+		Bulk:           supported{Supported: false},
+		Filter:         filterSupport{Supported: true, MaxResults: types.SCIMMaxPageLimit},
+		ChangePassword: supported{Supported: true},
+		Sort:           supported{Supported: false},
+		Etag:           supported{Supported: false},
+		AuthenticationSchemes: []authenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "A SCIM token created by an admin, sent as Authorization: Bearer {token}",
+			Primary:     true,
+		}},
+		Meta: types.SCIMMeta{ResourceType: "ServiceProviderConfig", Location: p.BaseURL + "/ServiceProviderConfig"},
+	}
+}
+
+//ResourceTypes - returns the resource types this service provider serves
+func (p *Provisioner) ResourceTypes() []interface{} {
+	return []interface{}{
+		resourceType{
+			Schemas:          []string{types.SCIMResourceTypeSchema},
+			ID:               "User",
+			Name:             "User",
+			Endpoint:         "/Users",
+			Description:      "Accounts of the organization",
+			Schema:           types.SCIMUserSchema,
+			SchemaExtensions: []schemaExtension{{Schema: types.SCIMEnterpriseUserSchema, Required: false}},
+			Meta:             types.SCIMMeta{ResourceType: "ResourceType", Location: p.BaseURL + "/ResourceTypes/User"},
+		},
+		resourceType{
+			Schemas:          []string{types.SCIMResourceTypeSchema},
+			ID:               "Group",
+			Name:             "Group",
+			Endpoint:         "/Groups",
+			Description:      "Roles of the organization's accounts",
+			Schema:           types.SCIMGroupSchema,
+			SchemaExtensions: []schemaExtension{},
+			Meta:             types.SCIMMeta{ResourceType: "ResourceType", Location: p.BaseURL + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+//Schemas - returns the schemas of the resources this service provider serves
+func (p *Provisioner) Schemas() []interface{} {
+	value := simple("value", false, "readWrite", "none")
+	display := simple("display", false, "readOnly", "none")
+	ref := attribute{Name: "$ref", Type: "reference", Mutability: "readOnly", Returned: "default", Uniqueness: "none"}
+	kind := simple("type", false, "readWrite", "none")
+	primary := attribute{Name: "primary", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"}
+
+	password := simple("password", false, "writeOnly", "none")
+	password.Returned = "never"
+
+	return []interface{}{
+		schema{
+			Schemas:     []string{types.SCIMSchemaSchema},
+			ID:          types.SCIMUserSchema,
+			Name:        "User",
+			Description: "User Account",
+			Attributes: []attribute{
+				simple("userName", true, "readWrite", "server"),
+				{Name: "name", Type: "complex", Mutability: "readWrite", Returned: "default", Uniqueness: "none", SubAttributes: []attribute{
+					simple("formatted", false, "readOnly", "none"),
+					simple("familyName", false, "readWrite", "none"),
+					simple("givenName", false, "readWrite", "none"),
+				}},
+				simple("displayName", false, "readWrite", "none"),
+				simple("preferredLanguage", false, "readWrite", "none"),
+				password,
+				{Name: "active", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				multi("emails", "readOnly", value, kind, primary),
+				multi("phoneNumbers", "readWrite", value, kind, primary),
+				multi("roles", "readWrite", value, display, kind, primary),
+				multi("groups", "readOnly", value, ref, display),
+			},
+			Meta: types.SCIMMeta{ResourceType: "Schema", Location: p.BaseURL + "/Schemas/" + types.SCIMUserSchema},
+		},
+		schema{
+			Schemas:     []string{types.SCIMSchemaSchema},
+			ID:          types.SCIMEnterpriseUserSchema,
+			Name:        "EnterpriseUser",
+			Description: "Enterprise User",
+			Attributes: []attribute{
+				simple("organization", false, "readOnly", "none"),
+			},
+			Meta: types.SCIMMeta{ResourceType: "Schema", Location: p.BaseURL + "/Schemas/" + types.SCIMEnterpriseUserSchema},
+		},
+		schema{
+			Schemas:     []string{types.SCIMSchemaSchema},
+			ID:          types.SCIMGroupSchema,
+			Name:        "Group",
+			Description: "Group",
+			Attributes: []attribute{
+				simple("displayName", true, "readOnly", "server"),
+				multi("members", "readWrite", value, ref, display),
+			},
+			Meta: types.SCIMMeta{ResourceType: "Schema", Location: p.BaseURL + "/Schemas/" + types.SCIMGroupSchema},
+		},
+	}
+}
+
+//Find - returns the resource type or schema with the id given from a list, or a not found error
+func Find(resources []interface{}, id string) (interface{}, error) {
+	for _, resource := range resources {
+		switch r := resource.(type) {
+		case resourceType:
+			if r.ID == id {
+				return r, nil
+			}
+		case schema:
+			if r.ID == id {
+				return r, nil
+			}
+		}
+	}
+	return nil, notFound("Resource", id)
+}
@@ -0,0 +1,93 @@
+package scim
+
+import (
+	"audit"
+	"context"
+	"dao"
+	"db"
+	"os"
+	"strings"
+	"types"
+)
+
+//Provisioner - SCIM 2.0 service provider. Each token manages the accounts of its own organization
+type Provisioner struct {
+	DB    db.Store
+	Audit *audit.Auditor
+	//BaseURL - where the SCIM endpoints are served, used for resource locations
+	BaseURL string
+}
+
+//Init - start SCIM provisioning service
+func (p Provisioner) Init(db db.Store, auditor *audit.Auditor) *Provisioner {
+	p.DB = db
+	p.Audit = auditor
+
+	p.BaseURL = strings.TrimSuffix(os.Getenv("SCIM_BASE_URL"), "/")
+	if p.BaseURL == "" {
+		p.BaseURL = "/scim/v2"
+	}
+
+	return &p
+}
+
+//Authenticate - returns the SCIM token a bearer token belongs to, or nil if it is not valid
+func (p *Provisioner) Authenticate(ctx context.Context, bearer string) (*types.SCIMToken, error) {
+	if bearer == "" {
+		return nil, nil
+	}
+	return dao.SCIMDAO{}.VerifyToken(ctx, bearer, p.DB)
+}
+
+//newEvent - creates an audit event for a change made with a SCIM token
+func (p *Provisioner) newEvent(eventType string, token *types.SCIMToken, client types.Client) *types.AuditEvent {
+	event := &types.AuditEvent{Type: eventType, ActorID: token.ID}
+	event.SetClient(client)
+	return event
+}
+
+//record - records an event. SCIM errors are the client's fault so they count as rejected rather than failed
+func (p *Provisioner) record(event *types.AuditEvent, err error) {
+	if scimErr, ok := err.(*types.SCIMError); ok {
+		p.Audit.Record(event, scimErr.Detail, nil)
+		return
+	}
+	p.Audit.Record(event, "", err)
+}
+
+//page - returns the slice of resources a list request asks for. A count of 0 only returns the total
+func page(resources []interface{}, request *types.SCIMListRequest) *types.SCIMListResponse {
+	start := request.StartIndex
+	if start < 1 {
+		start = 1
+	}
+	count := request.Count
+	if count < 0 {
+		count = 0
+	}
+	if count > types.SCIMMaxPageLimit {
+		count = types.SCIMMaxPageLimit
+	}
+
+	response := &types.SCIMListResponse{
+		Schemas:      []string{types.SCIMListSchema},
+		TotalResults: len(resources),
+		StartIndex:   start,
+		Resources:    []interface{}{},
+	}
+	if start <= len(resources) {
+		end := start - 1 + count
+		if end > len(resources) {
+			end = len(resources)
+		}
+		response.Resources = resources[start-1 : end]
+	}
+	response.ItemsPerPage = len(response.Resources)
+
+	return response
+}
+
+//notFound - returns the error for a resource that does not exist or belongs to another organization
+func notFound(resource string, id string) error {
+	return types.NewSCIMError(404, "", resource+" "+id+" not found")
+}
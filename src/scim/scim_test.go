@@ -0,0 +1,221 @@
+package scim
+
+import (
+	"audit"
+	"context"
+	"db"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+	"types"
+)
+
+func TestParseFilter(t *testing.T) {
+	user := map[string]interface{}{
+		"userName": "Bob@Example.com",
+		"active":   true,
+		"name":     map[string]interface{}{"givenName": "Bob", "familyName": "Smith"},
+		"emails":   []interface{}{map[string]interface{}{"value": "bob@example.com", "type": "work"}},
+		"meta":     map[string]interface{}{"created": "2024-03-01T00:00:00Z"},
+	}
+
+	tests := []struct {
+		filter  string
+		matches bool
+		invalid bool
+	}{
+		{filter: `userName eq "bob@example.com"`, matches: true},
+		{filter: `USERNAME EQ "bob@example.com"`, matches: true},
+		{filter: `userName ne "bob@example.com"`, matches: false},
+		{filter: `userName sw "bob" and active eq true`, matches: true},
+		{filter: `userName sw "alice" or name.familyName co "mit"`, matches: true},
+		{filter: `not (active eq true)`, matches: false},
+		{filter: `(userName ew ".org" or active eq false) and name.givenName pr`, matches: false},
+		{filter: `emails.value eq "bob@example.com"`, matches: true},
+		{filter: `meta.created gt "2024-01-01T00:00:00Z"`, matches: true},
+		{filter: `title pr`, matches: false},
+		{filter: `userName eq`, invalid: true},
+		{filter: `userName like "bob"`, invalid: true},
+		{filter: `userName eq "bob`, invalid: true},
+		{filter: `(active eq true`, invalid: true},
+		{filter: `active eq true)`, invalid: true},
+		{filter: `userName eq bob`, invalid: true},
+		{filter: `emails[type eq "work"].value eq "bob@example.com"`, invalid: true},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(test.filter)
+		if test.invalid {
+			if scimErr, ok := err.(*types.SCIMError); !ok || scimErr.ScimType != types.SCIMInvalidFilter {
+				t.Errorf("%s: expected an invalid filter error, got %v", test.filter, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.filter, err)
+			continue
+		}
+		if got := f.matches(user); got != test.matches {
+			t.Errorf("%s: matches %v, expected %v", test.filter, got, test.matches)
+		}
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		expected   string
+		scimType   string
+	}{
+		{
+			name:       "replace attribute",
+			operations: `[{"op":"replace","path":"active","value":false}]`,
+			expected:   `{"active":false,"name":{"givenName":"Bob"},"emails":[{"type":"work","value":"bob@example.com"}]}`,
+		},
+		{
+			name:       "add nested attribute",
+			operations: `[{"op":"add","path":"name.familyName","value":"Smith"}]`,
+			expected:   `{"active":true,"name":{"givenName":"Bob","familyName":"Smith"},"emails":[{"type":"work","value":"bob@example.com"}]}`,
+		},
+		{
+			name:       "value without a path",
+			operations: `[{"op":"Replace","value":{"active":false,"name":{"givenName":"Rob"}}}]`,
+			expected:   `{"active":false,"name":{"givenName":"Rob"},"emails":[{"type":"work","value":"bob@example.com"}]}`,
+		},
+		{
+			name:       "replace filtered sub attribute",
+			operations: `[{"op":"replace","path":"emails[type eq \"work\"].value","value":"rob@example.com"}]`,
+			expected:   `{"active":true,"name":{"givenName":"Bob"},"emails":[{"type":"work","value":"rob@example.com"}]}`,
+		},
+		{
+			name:       "add creates a filtered value",
+			operations: `[{"op":"add","path":"phoneNumbers[type eq \"work\"].value","value":"555-555-1234"}]`,
+			expected:   `{"active":true,"name":{"givenName":"Bob"},"emails":[{"type":"work","value":"bob@example.com"}],"phoneNumbers":[{"type":"work","value":"555-555-1234"}]}`,
+		},
+		{
+			name:       "remove filtered value",
+			operations: `[{"op":"remove","path":"emails[type eq \"work\"]"}]`,
+			expected:   `{"active":true,"name":{"givenName":"Bob"},"emails":[]}`,
+		},
+		{
+			name:       "remove missing attribute",
+			operations: `[{"op":"remove","path":"title"}]`,
+			expected:   `{"active":true,"name":{"givenName":"Bob"},"emails":[{"type":"work","value":"bob@example.com"}]}`,
+		},
+		{
+			name:       "unknown operation",
+			operations: `[{"op":"move","path":"active","value":false}]`,
+			scimType:   types.SCIMInvalidSyntax,
+		},
+		{
+			name:       "remove without a path",
+			operations: `[{"op":"remove"}]`,
+			scimType:   types.SCIMNoTarget,
+		},
+		{
+			name:       "replace filtered value that does not exist",
+			operations: `[{"op":"replace","path":"emails[type eq \"home\"].value","value":"bob@example.org"}]`,
+			scimType:   types.SCIMNoTarget,
+		},
+		{
+			name:       "path through a value",
+			operations: `[{"op":"replace","path":"active.value","value":false}]`,
+			scimType:   types.SCIMInvalidPath,
+		},
+	}
+
+	for _, test := range tests {
+		resource := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(`{"active":true,"name":{"givenName":"Bob"},"emails":[{"type":"work","value":"bob@example.com"}]}`), &resource); err != nil {
+			t.Fatal(err)
+		}
+		operations := []types.SCIMPatchOperation{}
+		if err := json.Unmarshal([]byte(test.operations), &operations); err != nil {
+			t.Fatal(err)
+		}
+
+		err := applyPatch(resource, operations)
+		if test.scimType != "" {
+			if scimErr, ok := err.(*types.SCIMError); !ok || scimErr.ScimType != test.scimType {
+				t.Errorf("%s: expected a %s error, got %v", test.name, test.scimType, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		expected := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(test.expected), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(resource, expected) {
+			t.Errorf("%s: got %v, expected %v", test.name, resource, expected)
+		}
+	}
+}
+
+func TestAdminAccountsNeedManageAdmins(t *testing.T) {
+	ctx := context.Background()
+	store := db.Memory{}.Init()
+	p := Provisioner{}.Init(store, audit.Auditor{}.Init(store))
+
+	admin := &types.Account{ID: "admin", Email: "admin@example.com", FirstName: "Ada", LastName: "Admin", Phone: "555-555-1234", Organization: "acme", Role: 999, Created: time.Now()}
+	if err := store.InsertAccount(ctx, admin); err != nil {
+		t.Fatal(err)
+	}
+
+	replace := func(token *types.SCIMToken) error {
+		user := p.toUser(admin)
+		user.UserName = "attacker@example.com"
+		user.Emails = nil
+		_, err := p.ReplaceUser(ctx, token, types.Client{}, admin.ID, user)
+		return err
+	}
+	patch := func(token *types.SCIMToken) error {
+		request := &types.SCIMPatchRequest{Operations: []types.SCIMPatchOperation{{Op: "replace", Path: "active", Value: json.RawMessage(`false`)}}}
+		_, err := p.PatchUser(ctx, token, types.Client{}, admin.ID, request)
+		return err
+	}
+	remove := func(token *types.SCIMToken) error {
+		return p.DeleteUser(ctx, token, types.Client{}, admin.ID)
+	}
+
+	tests := []struct {
+		name   string
+		write  func(token *types.SCIMToken) error
+		status int
+	}{
+		{name: "PUT", write: replace, status: 403},
+		{name: "PATCH", write: patch, status: 403},
+		{name: "DELETE", write: remove, status: 403},
+	}
+
+	token := &types.SCIMToken{ID: "token", Organization: "acme"}
+	for _, test := range tests {
+		err := test.write(token)
+		if scimErr, ok := err.(*types.SCIMError); !ok || scimErr.StatusCode != test.status || scimErr.ScimType != types.SCIMMutability {
+			t.Errorf("%s: expected %d %s, got %v", test.name, test.status, types.SCIMMutability, err)
+		}
+	}
+
+	account, err := store.GetAccountByID(ctx, admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Email != admin.Email || account.Disabled || account.Deleted != nil {
+		t.Errorf("admin account was changed: %+v", account)
+	}
+
+	if _, err := p.GetUser(ctx, token, admin.ID); err != nil {
+		t.Errorf("GET: %v", err)
+	}
+
+	managing := &types.SCIMToken{ID: "managing", Organization: "acme", ManageAdmins: true}
+	if err := patch(managing); err != nil {
+		t.Errorf("PATCH with a token that manages admins: %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package scim
+
+import (
+	"context"
+	"dao"
+	"db"
+	"strings"
+	"types"
+)
+
+//deactivatedReason - disabled reason of accounts a SCIM client deactivates
+const deactivatedReason = "Deactivated over SCIM"
+
+//GetUsers - returns a page of the organization's accounts matching the filter
+func (p *Provisioner) GetUsers(ctx context.Context, token *types.SCIMToken, request *types.SCIMListRequest) (*types.SCIMListResponse, error) {
+	var f filter
+	if request.Filter != "" {
+		var err error
+		if f, err = parseFilter(request.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	users := []interface{}{}
+
+	//Clients look up users by userName before creating them, which does not need a scan
+	if path, value, ok := equalsValue(f); ok && len(path) == 1 && strings.EqualFold(path[0], "userName") {
+		account, err := dao.AccountDAO{}.GetAccountByEmail(ctx, value, p.DB)
+		if err != nil {
+			return nil, err
+		}
+		if p.visible(token, account) {
+			account.GetAccountPermissions()
+			users = append(users, p.toUser(account))
+		}
+		return page(users, request), nil
+	}
+
+	search := &types.AccountSearchRequest{Organization: token.Organization, Sort: types.AccountSortCreated}
+	err := dao.AccountDAO{}.EachAccount(ctx, search, func(account *types.Account) error {
+		user := p.toUser(account)
+		if f != nil {
+			resource, err := toMap(user)
+			if err != nil {
+				return err
+			}
+			if !f.matches(resource) {
+				return nil
+			}
+		}
+		users = append(users, user)
+		return nil
+	}, p.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	return page(users, request), nil
+}
+
+//GetUser - returns an account of the organization
+func (p *Provisioner) GetUser(ctx context.Context, token *types.SCIMToken, id string) (*types.SCIMUser, error) {
+	account, err := p.getAccount(ctx, token, id, false, p.DB)
+	if err != nil {
+		return nil, err
+	}
+	return p.toUser(account), nil
+}
+
+//CreateUser - creates an account in the organization. Users are active unless they say otherwise
+func (p *Provisioner) CreateUser(ctx context.Context, token *types.SCIMToken, client types.Client, user *types.SCIMUser) (created *types.SCIMUser, err error) {
+	event := p.newEvent(types.AuditSCIMCreate, token, client)
+	defer func() { p.record(event, err) }()
+
+	account := &types.Account{Role: 100}
+	if err := p.fromUser(account, user, token); err != nil {
+		return nil, err
+	}
+	account.Password = user.Password
+
+	err = p.DB.InTx(ctx, func(tx db.Store) error {
+		if err := p.checkDuplicate(ctx, account, tx); err != nil {
+			return err
+		}
+
+		res, err := dao.SCIMDAO{}.CreateAccount(ctx, account, tx)
+		if err != nil {
+			return err
+		}
+		if res != "" {
+			return types.NewSCIMError(400, types.SCIMInvalidValue, res)
+		}
+
+		return p.setActive(ctx, account, user.Active, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	event.TargetID = account.ID
+
+	account.GetAccountPermissions()
+	return p.toUser(account), nil
+}
+
+//ReplaceUser - replaces the details of an account with the user given
+func (p *Provisioner) ReplaceUser(ctx context.Context, token *types.SCIMToken, client types.Client, id string, user *types.SCIMUser) (replaced *types.SCIMUser, err error) {
+	event := p.newEvent(types.AuditSCIMUpdate, token, client)
+	event.TargetID = id
+	defer func() { p.record(event, err) }()
+
+	var account *types.Account
+	err = p.DB.InTx(ctx, func(tx db.Store) error {
+		account, err = p.getAccount(ctx, token, id, true, tx)
+		if err != nil {
+			return err
+		}
+		return p.saveUser(ctx, token, account, user, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toUser(account), nil
+}
+
+//PatchUser - applies PATCH operations to an account
+func (p *Provisioner) PatchUser(ctx context.Context, token *types.SCIMToken, client types.Client, id string, patch *types.SCIMPatchRequest) (patched *types.SCIMUser, err error) {
+	event := p.newEvent(types.AuditSCIMUpdate, token, client)
+	event.TargetID = id
+	defer func() { p.record(event, err) }()
+
+	var account *types.Account
+	err = p.DB.InTx(ctx, func(tx db.Store) error {
+		account, err = p.getAccount(ctx, token, id, true, tx)
+		if err != nil {
+			return err
+		}
+
+		resource, err := toMap(p.toUser(account))
+		if err != nil {
+			return err
+		}
+		if err := applyPatch(resource, patch.Operations); err != nil {
+			return err
+		}
+
+		//Some clients send active as a string
+		if active, ok := resource["active"].(string); ok {
+			resource["active"] = strings.EqualFold(active, "true")
+		}
+
+		user := &types.SCIMUser{}
+		if err := fromMap(resource, user); err != nil {
+			return err
+		}
+		return p.saveUser(ctx, token, account, user, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toUser(account), nil
+}
+
+//DeleteUser - soft deletes an account. It is purged after the grace period like any other deleted account
+func (p *Provisioner) DeleteUser(ctx context.Context, token *types.SCIMToken, client types.Client, id string) (err error) {
+	event := p.newEvent(types.AuditSCIMDelete, token, client)
+	event.TargetID = id
+	defer func() { p.record(event, err) }()
+
+	account, err := p.getAccount(ctx, token, id, true, p.DB)
+	if err != nil {
+		return err
+	}
+
+	return dao.AccountDAO{}.DeleteAccount(ctx, account, p.DB)
+}
+
+//saveUser - saves the details of a user to its account
+func (p *Provisioner) saveUser(ctx context.Context, token *types.SCIMToken, account *types.Account, user *types.SCIMUser, tx db.Store) error {
+	if err := p.fromUser(account, user, token); err != nil {
+		return err
+	}
+	if err := p.checkDuplicate(ctx, account, tx); err != nil {
+		return err
+	}
+
+	res, err := dao.SCIMDAO{}.UpdateAccount(ctx, account, tx)
+	if err != nil {
+		return err
+	}
+	if res != "" {
+		return types.NewSCIMError(400, types.SCIMInvalidValue, res)
+	}
+
+	if user.Password != "" {
+		res, err := dao.AccountDAO{}.ChangeAccountPassword(ctx, account, &types.UpdateAccountPassword{NewPassword: user.Password}, tx)
+		if err != nil {
+			return err
+		}
+		if res != "" {
+			return types.NewSCIMError(400, types.SCIMInvalidValue, res)
+		}
+	}
+
+	account.GetAccountPermissions()
+	return p.setActive(ctx, account, user.Active, tx)
+}
+
+//setActive - disables or enables an account when a user's active flag changes
+func (p *Provisioner) setActive(ctx context.Context, account *types.Account, active *bool, tx db.Store) error {
+	if active == nil || *active != account.Disabled {
+		return nil
+	}
+
+	if *active {
+		return dao.AccountDAO{}.EnableAccount(ctx, account, tx)
+	}
+	_, err := dao.AccountDAO{}.DisableAccount(ctx, account, deactivatedReason, tx)
+	return err
+}
+
+//checkDuplicate - returns a uniqueness error if another account has the email
+func (p *Provisioner) checkDuplicate(ctx context.Context, account *types.Account, tx db.Store) error {
+	res, err := dao.AccountDAO{}.CheckDuplicates(ctx, account.ID, account.Email, tx)
+	if err != nil {
+		return err
+	}
+	if res != "" {
+		return types.NewSCIMError(409, types.SCIMUniqueness, res)
+	}
+	return nil
+}
+
+//getAccount - returns an account of the organization, or a not found error. Changing an admin needs a token that manages admins, otherwise its email or password could be taken over
+func (p *Provisioner) getAccount(ctx context.Context, token *types.SCIMToken, id string, write bool, db db.Store) (*types.Account, error) {
+	account, err := dao.AccountDAO{}.GetAccountByID(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+	if !p.visible(token, account) {
+		return nil, notFound("User", id)
+	}
+	if write && account.Role >= 999 && !token.ManageAdmins {
+		return nil, types.NewSCIMError(403, types.SCIMMutability, "This token cannot change ADMIN accounts")
+	}
+	account.GetAccountPermissions()
+	return account, nil
+}
+
+//visible - checks if a token can see an account. Accounts of other organizations and deleted accounts are hidden
+func (p *Provisioner) visible(token *types.SCIMToken, account *types.Account) bool {
+	return account != nil && account.Deleted == nil && account.Organization == token.Organization
+}
+
+//toUser - returns the SCIM form of an account
+func (p *Provisioner) toUser(account *types.Account) *types.SCIMUser {
+	active := !account.Disabled
+	created := account.Created
+	name := strings.TrimSpace(account.FirstName + " " + account.LastName)
+
+	user := &types.SCIMUser{
+		Schemas:           []string{types.SCIMUserSchema, types.SCIMEnterpriseUserSchema},
+		ID:                account.ID,
+		ExternalID:        account.ExternalID,
+		UserName:          account.Email,
+		Name:              types.SCIMName{GivenName: account.FirstName, FamilyName: account.LastName, Formatted: name},
+		DisplayName:       name,
+		PreferredLanguage: account.Language,
+		Emails:            []types.SCIMValue{{Value: account.Email, Type: "work", Primary: true}},
+		Roles:             []types.SCIMValue{},
+		Groups:            []types.SCIMMember{},
+		Active:            &active,
+		Enterprise:        &types.SCIMEnterprise{Organization: account.Organization},
+		Meta:              &types.SCIMMeta{ResourceType: "User", Created: &created, Location: p.BaseURL + "/Users/" + account.ID},
+	}
+	if account.Phone != "" {
+		user.PhoneNumbers = []types.SCIMValue{{Value: account.Phone, Type: "work", Primary: true}}
+	}
+	for _, role := range account.Roles {
+		user.Roles = append(user.Roles, types.SCIMValue{Value: role})
+		user.Groups = append(user.Groups, types.SCIMMember{Value: role, Ref: p.BaseURL + "/Groups/" + role, Display: role})
+	}
+
+	return user
+}
+
+//fromUser - copies the details of a user onto an account. Groups and the organization are read only, roles only change when sent by a token that manages admins
+func (p *Provisioner) fromUser(account *types.Account, user *types.SCIMUser, token *types.SCIMToken) error {
+	if strings.TrimSpace(user.UserName) == "" {
+		return types.NewSCIMError(400, types.SCIMInvalidValue, "userName is required")
+	}
+
+	account.Email = strings.TrimSpace(user.UserName)
+	account.ExternalID = user.ExternalID
+	account.Organization = token.Organization
+
+	account.FirstName = strings.TrimSpace(user.Name.GivenName)
+	account.LastName = strings.TrimSpace(user.Name.FamilyName)
+	if account.FirstName == "" && account.LastName == "" {
+		full := user.Name.Formatted
+		if full == "" {
+			full = user.DisplayName
+		}
+		parts := strings.SplitN(strings.TrimSpace(full), " ", 2)
+		account.FirstName = parts[0]
+		if len(parts) > 1 {
+			account.LastName = strings.TrimSpace(parts[1])
+		}
+	}
+
+	account.Language = user.PreferredLanguage
+
+	account.Phone = ""
+	for i, phone := range user.PhoneNumbers {
+		if i == 0 || phone.Primary {
+			account.Phone = phone.Value
+		}
+		if phone.Primary {
+			break
+		}
+	}
+
+	//Without the right to manage admins roles are ignored, rather than failing the sync of every account an identity provider sends them for
+	if user.Roles != nil && token.ManageAdmins {
+		roles := []string{}
+		for _, role := range user.Roles {
+			roles = append(roles, role.Value)
+		}
+		account.Role = types.GetRole(roles)
+	}
+
+	return nil
+}
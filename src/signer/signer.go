@@ -20,10 +20,57 @@ type AccountInfo struct {
 	Roles     []string `json:"roles"`
 }
 
+//Actor - the account acting on behalf of the token's subject (RFC 8693 act claim)
+type Actor struct {
+	Sub   string `json:"sub"`
+	Email string `json:"email"`
+}
+
+//Authentication methods, as amr values (RFC 8176)
+const (
+	MethodPassword = "pwd"
+	//MethodOTP - a code or link that was emailed
+	MethodOTP   = "otp"
+	MethodSMS   = "sms"
+	MethodVoice = "tel"
+	//MethodMFA - logged in on an activated device, which counts as a second factor
+	MethodMFA = "mfa"
+)
+
+//Authentication - when and how the account last proved who it is. Every access token of a session carries it
+type Authentication struct {
+	Time    time.Time
+	Methods []string
+}
+
 //AccessClaims - struct of access claim
 type AccessClaims struct {
 	*jwt.StandardClaims
 	*AccountInfo
+	Act *Actor `json:"act,omitempty"`
+	//AuthTime - unix time the account authenticated, unset on tokens that did not come from a login
+	AuthTime int64    `json:"auth_time,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
+}
+
+//Authentication - returns when and how the token's account authenticated, nil if the token did not come from a login
+func (c *AccessClaims) Authentication() *Authentication {
+	if c.AuthTime == 0 {
+		return nil
+	}
+	return &Authentication{Time: time.Unix(c.AuthTime, 0), Methods: c.AMR}
+}
+
+//MultiFactor - checks the account used a second factor, either with two different methods or on an activated device
+func (c *AccessClaims) MultiFactor() bool {
+	methods := map[string]bool{}
+	for _, method := range c.AMR {
+		if method == MethodMFA {
+			return true
+		}
+		methods[method] = true
+	}
+	return len(methods) > 1
 }
 
 //JWTSigner - struct  to sign jwt
@@ -31,6 +78,10 @@ type JWTSigner struct {
 	signKey             *rsa.PrivateKey
 	verifyKey           *rsa.PublicKey
 	AccessTokenDuration time.Duration
+	//ImpersonationDuration - how long an impersonation token lasts
+	ImpersonationDuration time.Duration
+	//ElevatedDuration - how long a token from re-authenticating lasts
+	ElevatedDuration time.Duration
 }
 
 //SignedResponse - successful response from signed JWT
@@ -75,14 +126,28 @@ func (j *JWTSigner) Init() error {
 		j.AccessTokenDuration = time.Minute * time.Duration(num)
 	}
 
+	num, err = strconv.Atoi(os.Getenv("IMPERSONATION_TOKEN_DURATION"))
+	if err != nil || num <= 0 {
+		j.ImpersonationDuration = time.Minute * 10
+	} else {
+		j.ImpersonationDuration = time.Minute * time.Duration(num)
+	}
+
+	num, err = strconv.Atoi(os.Getenv("STEP_UP_TOKEN_DURATION"))
+	if err != nil || num <= 0 {
+		j.ElevatedDuration = time.Minute * 5
+	} else {
+		j.ElevatedDuration = time.Minute * time.Duration(num)
+	}
+
 	return nil
 }
 
-//SignNewJWT - Signs a new JWT with account info
-func (j *JWTSigner) SignNewJWT(account *AccountInfo) (*SignedResponse, error) {
+//SignNewJWT - Signs a new JWT with account info and how it authenticated
+func (j *JWTSigner) SignNewJWT(account *AccountInfo, authentication *Authentication) (*SignedResponse, error) {
 
 	//Get access token
-	access, err := j.CreateAccessToken(account)
+	access, err := j.CreateAccessToken(account, authentication)
 	if err != nil {
 		return nil, err
 	}
@@ -93,19 +158,34 @@ func (j *JWTSigner) SignNewJWT(account *AccountInfo) (*SignedResponse, error) {
 	return &SignedResponse{AccessToken: access, RefreshToken: refresh}, nil
 }
 
-//CreateAccessToken - create a new access token for the given account
-func (j *JWTSigner) CreateAccessToken(account *AccountInfo) (string, error) {
+//CreateAccessToken - create a new access token for the given account. Refreshed tokens keep the authentication of the session
+func (j *JWTSigner) CreateAccessToken(account *AccountInfo, authentication *Authentication) (string, error) {
+	return j.createAccessToken(account, authentication, time.Now().Add(j.AccessTokenDuration))
+}
+
+//CreateElevatedToken - create a short lived access token for an account that just re-authenticated. There is no refresh token
+func (j *JWTSigner) CreateElevatedToken(account *AccountInfo, authentication *Authentication) (string, error) {
+	return j.createAccessToken(account, authentication, time.Now().Add(j.ElevatedDuration))
+}
+
+//createAccessToken - signs an access token that expires at the time given
+func (j *JWTSigner) createAccessToken(account *AccountInfo, authentication *Authentication, expires time.Time) (string, error) {
 
 	//Initiate tokens
 	accessToken := jwt.New(jwt.GetSigningMethod("RS256"))
 
 	//Create claims for access token
-	accessToken.Claims = &AccessClaims{
-		&jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(j.AccessTokenDuration).Unix(),
+	claims := &AccessClaims{
+		StandardClaims: &jwt.StandardClaims{
+			ExpiresAt: expires.Unix(),
 		},
-		account,
+		AccountInfo: account,
 	}
+	if authentication != nil {
+		claims.AuthTime = authentication.Time.Unix()
+		claims.AMR = authentication.Methods
+	}
+	accessToken.Claims = claims
 
 	//Sign access token with signing key
 	access, err := accessToken.SignedString(j.signKey)
@@ -116,6 +196,24 @@ func (j *JWTSigner) CreateAccessToken(account *AccountInfo) (string, error) {
 	return access, nil
 }
 
+//CreateImpersonationToken - create an access token for the given account acted on by the actor. There is no refresh token
+func (j *JWTSigner) CreateImpersonationToken(account *AccountInfo, actor *Actor, id string, expires time.Time) (string, error) {
+
+	accessToken := jwt.New(jwt.GetSigningMethod("RS256"))
+
+	//The id ties the token to its impersonation so stopping it revokes the token
+	accessToken.Claims = &AccessClaims{
+		StandardClaims: &jwt.StandardClaims{
+			Id:        id,
+			ExpiresAt: expires.Unix(),
+		},
+		AccountInfo: account,
+		Act:         actor,
+	}
+
+	return accessToken.SignedString(j.signKey)
+}
+
 //createRefreshToken - create a new refresh token
 func (j *JWTSigner) createRefreshToken() string {
 	return uuid.New().String()
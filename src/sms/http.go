@@ -0,0 +1,95 @@
+package sms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"utils"
+)
+
+//HTTP - sends messages by posting them as JSON to a gateway, which turns them into texts or calls
+type HTTP struct {
+	Name   string
+	URL    string
+	Token  string
+	Client *http.Client
+	//Channels - the channels the gateway can send over
+	Channels []string
+}
+
+//Init - configure the provider from SMS_{NAME}_URL, SMS_{NAME}_TOKEN, SMS_{NAME}_CHANNELS and SMS_{NAME}_TIMEOUT_SECONDS
+func (h HTTP) Init(name string) (*HTTP, error) {
+	prefix := "SMS_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name)) + "_"
+
+	h.Name = name
+	h.URL = os.Getenv(prefix + "URL")
+	if h.URL == "" {
+		return nil, errors.New(prefix + "URL is required for the " + name + " SMS provider")
+	}
+	h.Token = os.Getenv(prefix + "TOKEN")
+
+	h.Channels = []string{}
+	channels := os.Getenv(prefix + "CHANNELS")
+	if channels == "" {
+		channels = "sms,voice"
+	}
+	for _, channel := range strings.Split(channels, ",") {
+		channel = strings.TrimSpace(channel)
+		if !validChannel(channel) {
+			return nil, errors.New("Invalid " + prefix + "CHANNELS: " + channel)
+		}
+		h.Channels = append(h.Channels, channel)
+	}
+
+	timeout, err := strconv.Atoi(os.Getenv(prefix + "TIMEOUT_SECONDS"))
+	if err != nil || timeout < 1 {
+		timeout = 10
+	}
+	h.Client = &http.Client{Timeout: time.Duration(timeout) * time.Second}
+
+	return &h, nil
+}
+
+//Send - posts {"to", "channel", "body"} to the gateway with the token as a bearer token. Any status other than 2xx is a failure
+func (h *HTTP) Send(message *Message) error {
+	if !utils.Contains(message.Channel, h.Channels) {
+		return errors.New("Channel is not supported: " + message.Channel)
+	}
+
+	payload, err := json.Marshal(map[string]string{"to": message.To, "channel": message.Channel, "body": message.Body})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "JWT_Auth-SMS")
+	if h.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Token)
+	}
+
+	res, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	//Drain so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Gateway returned status %d", res.StatusCode)
+	}
+	return nil
+}
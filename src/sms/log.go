@@ -0,0 +1,30 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+//Log - prints messages instead of sending them, for local development
+type Log struct {
+	Out io.Writer
+
+	mu *sync.Mutex
+}
+
+//Init - print messages to the writer given
+func (l Log) Init(out io.Writer) *Log {
+	l.Out = out
+	l.mu = &sync.Mutex{}
+	return &l
+}
+
+//Send - prints the message
+func (l *Log) Send(message *Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := fmt.Fprintf(l.Out, "%s to %s: %s\n\n", message.Channel, message.To, message.Body)
+	return err
+}
@@ -0,0 +1,41 @@
+package sms
+
+import "sync"
+
+//Memory - keeps messages instead of sending them so tests can read what would have been sent
+type Memory struct {
+	mu       *sync.Mutex
+	messages []Message
+}
+
+//Init - start with no messages
+func (m Memory) Init() *Memory {
+	m.mu = &sync.Mutex{}
+	m.messages = []Message{}
+	return &m
+}
+
+//Send - keeps the message
+func (m *Memory) Send(message *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, *message)
+	return nil
+}
+
+//Sent - returns every message sent so far, oldest first
+func (m *Memory) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message{}, m.messages...)
+}
+
+//Reset - forgets the messages sent so far
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = []Message{}
+}
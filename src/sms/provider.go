@@ -0,0 +1,91 @@
+package sms
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"types"
+)
+
+//Provider - delivers messages by text or phone call. Chosen with SMS_PROVIDERS
+type Provider interface {
+	Send(message *Message) error
+}
+
+//Message - a message ready to be delivered
+type Message struct {
+	//To - the number in E.164 form
+	To string
+	//Channel - types.PhoneChannelSMS or types.PhoneChannelVoice
+	Channel string
+	Body    string
+}
+
+//Fallback - sends through each provider in order until one succeeds
+type Fallback struct {
+	Names     []string
+	Providers []Provider
+}
+
+//Send - sends with the first provider that works. If none do every error is returned
+func (f *Fallback) Send(message *Message) error {
+	failures := []string{}
+	for i, provider := range f.Providers {
+		err := provider.Send(message)
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, f.Names[i]+": "+err.Error())
+	}
+	return errors.New("Every SMS provider failed: " + strings.Join(failures, "; "))
+}
+
+//NewProvider - returns the providers in SMS_PROVIDERS, tried in the order given. log and memory are built in,
+//any other name is an HTTP provider configured by SMS_{NAME}_URL. Without any messages are only logged in development,
+//as the log holds live codes
+func NewProvider() (Provider, error) {
+	list := os.Getenv("SMS_PROVIDERS")
+	if list == "" {
+		if os.Getenv("ENV_TYPE") != "development" {
+			return nil, errors.New("SMS_PROVIDERS must be set outside development")
+		}
+		list = "log"
+	}
+
+	fallback := &Fallback{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		var provider Provider
+		switch name {
+		case "log":
+			provider = Log{}.Init(os.Stdout)
+		case "memory":
+			provider = Memory{}.Init()
+		default:
+			http, err := HTTP{}.Init(name)
+			if err != nil {
+				return nil, err
+			}
+			provider = http
+		}
+		fallback.Names = append(fallback.Names, name)
+		fallback.Providers = append(fallback.Providers, provider)
+	}
+
+	if len(fallback.Providers) == 0 {
+		return nil, errors.New("SMS_PROVIDERS has no providers")
+	}
+	if len(fallback.Providers) == 1 {
+		return fallback.Providers[0], nil
+	}
+	return fallback, nil
+}
+
+//validChannel - checks the channel is one codes can be sent over
+func validChannel(channel string) bool {
+	return channel == types.PhoneChannelSMS || channel == types.PhoneChannelVoice
+}
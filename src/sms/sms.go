@@ -0,0 +1,127 @@
+package sms
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"types"
+)
+
+//Sender - sends one time codes to phone numbers and holds the limits they are sent with
+type Sender struct {
+	Provider Provider
+	//Name - who the code is from, said at the start of each message
+	Name string
+	//CountryCode - calling code added to numbers given without one
+	CountryCode string
+	Digits      int
+	Duration    time.Duration
+	Attempts    int
+	//HourlyLimit and DailyLimit - how many codes one number can be sent
+	HourlyLimit int
+	DailyLimit  int
+}
+
+//Init - start the providers in SMS_PROVIDERS with the limits from the environment
+func (s Sender) Init() (*Sender, error) {
+	provider, err := NewProvider()
+	if err != nil {
+		return nil, err
+	}
+	s.Provider = provider
+	s.Name = os.Getenv("SMS_SENDER_NAME")
+
+	s.CountryCode = strings.TrimPrefix(os.Getenv("SMS_DEFAULT_COUNTRY_CODE"), "+")
+	if s.CountryCode == "" {
+		s.CountryCode = "1"
+	}
+	if !isDigits(s.CountryCode) || len(s.CountryCode) > 3 {
+		return nil, errors.New("Invalid SMS_DEFAULT_COUNTRY_CODE: " + s.CountryCode)
+	}
+
+	s.Digits = envInt("SMS_CODE_DIGITS", 6)
+	if s.Digits < 6 || s.Digits > 8 {
+		s.Digits = 6
+	}
+	s.Duration = time.Duration(envInt("SMS_CODE_MINUTES", 10)) * time.Minute
+	s.Attempts = envInt("SMS_CODE_ATTEMPTS", 5)
+	s.HourlyLimit = envInt("SMS_HOURLY_LIMIT", 5)
+	s.DailyLimit = envInt("SMS_DAILY_LIMIT", 10)
+
+	return &s, nil
+}
+
+//Normalize - returns a phone number in E.164 form. Numbers starting with + or 00 already have a country code,
+//anything else is a national number and gets CountryCode, dropping a leading trunk 0
+func (s *Sender) Normalize(phone string) (string, error) {
+	number := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(phone))
+
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number = number[1:]
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case s.CountryCode == "1" && len(number) == 11 && strings.HasPrefix(number, "1"):
+		//North American numbers are often written with their country code but no +
+	default:
+		number = s.CountryCode + strings.TrimPrefix(number, "0")
+	}
+
+	if !isDigits(number) || number[0] == '0' || len(number) < 8 || len(number) > 15 {
+		return "", errors.New("Invalid phone number: " + phone)
+	}
+	return "+" + number, nil
+}
+
+//SendCode - sends a code to a number, as a text or spoken digit by digit in a call
+func (s *Sender) SendCode(to string, channel string, code string) error {
+	if !validChannel(channel) {
+		return errors.New("Invalid channel: " + channel)
+	}
+
+	minutes := strconv.Itoa(int(s.Duration / time.Minute))
+	from := ""
+	if s.Name != "" {
+		from = s.Name + ": "
+	}
+
+	body := from + "Your code is " + code + ". It expires in " + minutes + " minutes. Do not share it with anyone."
+	if channel == types.PhoneChannelVoice {
+		spoken := strings.Join(strings.Split(code, ""), ", ")
+		body = from + "Your code is " + spoken + ". Again, your code is " + spoken + "."
+	}
+
+	return s.Provider.Send(&Message{To: to, Channel: channel, Body: body})
+}
+
+//Mask - returns the number with all but its last digits hidden, to show which phone a code went to
+func Mask(phone string) string {
+	if len(phone) <= 4 {
+		return phone
+	}
+	return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
+}
+
+//isDigits - checks the string is only 0-9
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+//envInt - returns a positive number from the environment, or the fallback
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -2,10 +2,20 @@ package types
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"regexp"
 	"time"
 )
 
+//PasswordScreener - checks a password against known breach corpora
+type PasswordScreener interface {
+	IsBreached(password string) (bool, error)
+}
+
+//BreachScreener - screener used by CheckPassword. nil disables breach screening
+var BreachScreener PasswordScreener
+
 //Account - struct for account class
 type Account struct {
 	ID        string    `sql:"id" json:"-"`
@@ -19,8 +29,40 @@ type Account struct {
 	Roles     []string  `json:"roles"`
 	Created   time.Time `sql:"created" json:"-"`
 	Disabled  bool      `sql:"disabled" json:"-"`
+
+	Organization string `sql:"organization" json:"organization"`
+	//Language - preferred language for emails as a tag like en or fr-CA, empty for the default
+	Language string `sql:"language" json:"language"`
+	//VerifiedPhone - the phone number in E.164 form that was last proven by a code. Phone is only verified while it is the same number
+	VerifiedPhone string `sql:"verifiedPhone" json:"-"`
+	//ExternalID - id of the account in the system that provisions it over SCIM
+	ExternalID string `sql:"externalId" json:"-"`
+
+	DisabledReason string `sql:"disabledReason" json:"-"`
+	//Deleted - when the account was soft deleted, nil if it is not. It is purged once the grace period is over
+	Deleted *time.Time `sql:"deleted" json:"-"`
+
+	//ImpersonatedBy - the admin acting as the account, only set when it is fetched with an impersonation token
+	ImpersonatedBy *Impersonator `sql:"-" json:"impersonatedBy,omitempty"`
 }
 
+//Fields accounts can be listed by
+const (
+	AccountSortFirstName = "firstName"
+	AccountSortLastName  = "lastName"
+	AccountSortEmail     = "email"
+	AccountSortCreated   = "created"
+)
+
+//AccountSortFields - every field accounts can be listed by
+var AccountSortFields = []string{AccountSortFirstName, AccountSortLastName, AccountSortEmail, AccountSortCreated}
+
+//Account list page sizes
+const (
+	AccountDefaultPageLimit = 50
+	AccountMaxPageLimit     = 500
+)
+
 //CheckName - verify name is valid
 func (account *Account) CheckName() error {
 	if account.FirstName == "" {
@@ -43,6 +85,15 @@ func (account *Account) CheckPassword() error {
 	if !regexp.MustCompile(`.*[a-zA-Z].*`).MatchString(account.Password) {
 		return errors.New("Password must contain a letter")
 	}
+	if BreachScreener != nil {
+		breached, err := BreachScreener.IsBreached(account.Password)
+		if err != nil {
+			//Screening is best effort, dont block passwords when the lookup is unavailable
+			fmt.Fprintln(os.Stderr, "Breach Screening Error: "+err.Error())
+		} else if breached {
+			return errors.New("Password has appeared in a data breach, please choose another")
+		}
+	}
 	return nil
 }
 
@@ -62,6 +113,14 @@ func (account *Account) CheckPhone() error {
 	return nil
 }
 
+//CheckLanguage - verify language is a language tag like en or fr-CA, or empty for the default.
+func (account *Account) CheckLanguage() error {
+	if account.Language != "" && (len(account.Language) > 35 || !regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`).MatchString(account.Language)) {
+		return errors.New("Invalid language: " + account.Language)
+	}
+	return nil
+}
+
 //HideImportant - Hides sensative info on the account
 func (account *Account) HideImportant() {
 	account.Password = ""
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"signer"
+	"time"
+)
+
+//Audit event types
+const (
+	AuditLogin          = "login"
+	AuditRefresh        = "token.refresh"
+	AuditLogout         = "logout"
+	AuditStepUp         = "stepup"
+	AuditMagicLinkSend  = "magiclink.request"
+	AuditMagicLinkLogin = "magiclink.login"
+	AuditLoginCodeSend  = "logincode.request"
+	AuditLoginCodeLogin = "logincode.login"
+	AuditRegister       = "account.register"
+	AuditAccountDelete  = "account.delete"
+	AuditAccountUpdate  = "account.update"
+	AuditAccountList    = "account.list"
+	AuditAccountImport  = "account.import"
+	AuditAccountExport  = "account.export"
+	AuditAccountDisable = "account.disable"
+	AuditAccountEnable  = "account.enable"
+	AuditAccountRestore = "account.restore"
+	AuditAccountPurge   = "account.purge"
+	AuditSettingsUpdate = "settings.update"
+	AuditDeviceActivate = "device.activate"
+	AuditPhoneCodeSend  = "phone.code"
+	AuditPhoneVerify    = "phone.verify"
+	AuditRecoveryStart  = "recovery.start"
+	AuditRecoveryFinish = "recovery.finish"
+	AuditPasswordChange = "password.change"
+	AuditAuditSearch    = "audit.search"
+	AuditAuditExport    = "audit.export"
+	AuditWebhookCreate  = "webhook.create"
+	AuditWebhookDelete  = "webhook.delete"
+	AuditWebhookList    = "webhook.list"
+	AuditWebhookLog     = "webhook.deliveries"
+	AuditWebhookRetry   = "webhook.redeliver"
+	AuditPrivacyExport  = "privacy.export"
+	AuditErasureRequest = "erasure.request"
+	AuditErasureConfirm = "erasure.confirm"
+	AuditErasureList    = "erasure.list"
+	AuditErasureProcess = "erasure.process"
+	AuditInviteCreate   = "invitation.create"
+	AuditInviteList     = "invitation.list"
+	AuditInviteRevoke   = "invitation.revoke"
+	AuditSCIMCreate     = "scim.create"
+	AuditSCIMUpdate     = "scim.update"
+	AuditSCIMDelete     = "scim.delete"
+	AuditSCIMTokenNew   = "scim.token.create"
+	AuditSCIMTokenList  = "scim.token.list"
+	AuditSCIMTokenDrop  = "scim.token.revoke"
+	AuditPATCreate      = "pat.create"
+	AuditPATList        = "pat.list"
+	AuditPATRevoke      = "pat.revoke"
+	AuditImpersonate    = "impersonation.start"
+	AuditImpersonateEnd = "impersonation.stop"
+	AuditEmailList      = "email.list"
+	AuditEmailRetry     = "email.retry"
+	AuditEmailPreview   = "email.preview"
+	AuditEmailTest      = "email.test"
+)
+
+//Audit event outcomes
+const (
+	AuditOutcomeSuccess  = "success"
+	AuditOutcomeFailure  = "failure"
+	AuditOutcomeRejected = "rejected"
+)
+
+//Audit search page sizes
+const (
+	AuditDefaultPageLimit = 50
+	AuditMaxPageLimit     = 500
+)
+
+//AuditEvent - a recorded security event
+type AuditEvent struct {
+	ID         string `sql:"id" json:"id"`
+	Type       string `sql:"type" json:"type"`
+	ActorID    string `sql:"actorId" json:"actorId"`
+	ActorEmail string `sql:"actorEmail" json:"actorEmail"`
+	//ImpersonatorID - the admin who acted as the actor, if the event happened during an impersonation
+	ImpersonatorID string    `sql:"impersonatorId" json:"impersonatorId,omitempty"`
+	TargetID       string    `sql:"targetId" json:"targetId"`
+	IP             string    `sql:"ip" json:"ip"`
+	UserAgent      string    `sql:"userAgent" json:"userAgent"`
+	Outcome        string    `sql:"outcome" json:"outcome"`
+	Detail         string    `sql:"detail" json:"detail"`
+	Created        time.Time `sql:"created" json:"created"`
+}
+
+//SetClient - sets the ip and user agent the event came from
+func (event *AuditEvent) SetClient(client Client) {
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+}
+
+//SetActor - sets the account the event was made by from its access token, and the admin behind it during an impersonation
+func (event *AuditEvent) SetActor(claims *signer.AccessClaims) {
+	event.ActorID = claims.ID
+	event.ActorEmail = claims.Email
+	if claims.Act != nil {
+		event.ImpersonatorID = claims.Act.Sub
+	}
+}
@@ -0,0 +1,98 @@
+package types
+
+import "time"
+
+//Bulk import and export formats
+const (
+	BulkFormatCSV  = "csv"
+	BulkFormatJSON = "json"
+)
+
+//BulkMaxImportRows - most rows a single import can hold
+const BulkMaxImportRows = 10000
+
+//BulkColumns - CSV header used for exports. Imports match columns by name and ignore any they dont use
+var BulkColumns = []string{"id", "email", "firstName", "lastName", "phone", "organization", "roles", "twoFA", "disabled", "created", "passwordHash"}
+
+//BulkAccount - an account row in an import or export. ID, TwoFA, Disabled and Created are only written by exports
+type BulkAccount struct {
+	ID           string    `json:"id,omitempty"`
+	Email        string    `json:"email"`
+	FirstName    string    `json:"firstName"`
+	LastName     string    `json:"lastName"`
+	Phone        string    `json:"phone"`
+	Organization string    `json:"organization"`
+	Roles        []string  `json:"roles"`
+	TwoFA        bool      `json:"twoFA"`
+	Disabled     bool      `json:"disabled"`
+	Created      time.Time `json:"created"`
+	//Password - plain text password, checked against the password rules
+	Password string `json:"password,omitempty"`
+	//PasswordHash - bcrypt hash carried over from another system, used as is
+	PasswordHash string `json:"passwordHash,omitempty"`
+}
+
+//NewBulkAccount - returns the export row for an account. The password hash is only included when asked for
+func NewBulkAccount(account *Account, withHash bool) *BulkAccount {
+	row := &BulkAccount{
+		ID:           account.ID,
+		Email:        account.Email,
+		FirstName:    account.FirstName,
+		LastName:     account.LastName,
+		Phone:        account.Phone,
+		Organization: account.Organization,
+		Roles:        GetRoles(account.Role),
+		TwoFA:        account.TwoFA,
+		Disabled:     account.Disabled,
+		Created:      account.Created,
+	}
+	if withHash {
+		row.PasswordHash = account.Password
+	}
+	return row
+}
+
+//Import row outcomes
+const (
+	ImportCreated = "created"
+	ImportInvited = "invited"
+	//ImportValid - the row passed validation in a dry run
+	ImportValid  = "valid"
+	ImportFailed = "failed"
+)
+
+//ImportResult - what happened to one row of an import
+type ImportResult struct {
+	//Row - 1 based position of the row, not counting a CSV header
+	Row    int    `json:"row"`
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	//ID - id of the account or invitation created
+	ID string `json:"id,omitempty"`
+}
+
+//ImportReport - the outcome of every row of an import
+type ImportReport struct {
+	DryRun  bool           `json:"dryRun"`
+	Created int            `json:"created"`
+	Invited int            `json:"invited"`
+	Valid   int            `json:"valid"`
+	Failed  int            `json:"failed"`
+	Rows    []ImportResult `json:"rows"`
+}
+
+//Add - adds the outcome of a row to the report
+func (report *ImportReport) Add(result ImportResult) {
+	switch result.Status {
+	case ImportCreated:
+		report.Created++
+	case ImportInvited:
+		report.Invited++
+	case ImportValid:
+		report.Valid++
+	default:
+		report.Failed++
+	}
+	report.Rows = append(report.Rows, result)
+}
@@ -4,9 +4,42 @@ import "time"
 
 //Device - device struct
 type Device struct {
-	ID        string    `sql:"id" json:"id"`
-	AccountID string    `sql:"accountId" json:"accountId"`
-	Created   time.Time `sql:"created" json:"created"`
-	Active    bool      `sql:"active" json:"active"`
-	Code      string    `sql:"code" json:"code"`
+	ID        string     `sql:"id" json:"id"`
+	AccountID string     `sql:"accountId" json:"accountId"`
+	Created   time.Time  `sql:"created" json:"created"`
+	Active    bool       `sql:"active" json:"active"`
+	Code      string     `sql:"code" json:"code"`
+	FirstIP   string     `sql:"firstIp" json:"firstIp"`
+	LastIP    string     `sql:"lastIp" json:"lastIp"`
+	UserAgent string     `sql:"userAgent" json:"userAgent"`
+	Browser   string     `sql:"browser" json:"browser"`
+	OS        string     `sql:"os" json:"os"`
+	Country   string     `sql:"country" json:"country"`
+	Location  string     `sql:"location" json:"location"`
+	LastSeen  *time.Time `sql:"lastSeen" json:"lastSeen"`
 }
+
+//Seen - records the client the device was just used from
+func (device *Device) Seen(client *ClientDetails) {
+	now := time.Now()
+	if device.FirstIP == "" {
+		device.FirstIP = client.IP
+	}
+	device.LastIP = client.IP
+	device.UserAgent = client.UserAgent
+	device.Browser = client.Browser
+	device.OS = client.OS
+	device.Country = client.Country
+	device.Location = client.Location
+	device.LastSeen = &now
+}
+
+//ClientDetails - a client with its user agent parsed and its ip located
+type ClientDetails struct {
+	Client
+	Browser  string
+	OS       string
+	Country  string
+	Location string
+}
+
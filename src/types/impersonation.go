@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+//Impersonation - an admin acting as another account through a short lived access token
+type Impersonation struct {
+	ID string `sql:"id" json:"id"`
+	//ActorID - the admin doing the impersonating
+	ActorID  string    `sql:"actorId" json:"actorId"`
+	TargetID string    `sql:"targetId" json:"targetId"`
+	Reason   string    `sql:"reason" json:"reason"`
+	Started  time.Time `sql:"started" json:"started"`
+	Expires  time.Time `sql:"expires" json:"expires"`
+	//Stopped - set when the admin stops early, which revokes the token
+	Stopped *time.Time `sql:"stopped" json:"stopped"`
+}
+
+//Impersonator - the admin an account is being impersonated by, shown on the account
+type Impersonator struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
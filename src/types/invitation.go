@@ -0,0 +1,39 @@
+package types
+
+import "time"
+
+//Registration modes
+const (
+	//RegistrationOpen - anyone can register
+	RegistrationOpen = "open"
+	//RegistrationInvite - only invited emails can register
+	RegistrationInvite = "invite"
+	//RegistrationDomain - emails on an allowed domain, or invited emails, can register
+	RegistrationDomain = "domain"
+	//RegistrationClosed - only admins can create accounts
+	RegistrationClosed = "closed"
+)
+
+//RegistrationModes - every registration mode
+var RegistrationModes = []string{RegistrationOpen, RegistrationInvite, RegistrationDomain, RegistrationClosed}
+
+//Invitation - an admin invitation for an email to register
+type Invitation struct {
+	ID           string    `sql:"id" json:"id"`
+	Email        string    `sql:"email" json:"email"`
+	Token        string    `sql:"token" json:"-"`
+	Role         int       `sql:"role" json:"-"`
+	Roles        []string  `json:"roles"`
+	Organization string    `sql:"organization" json:"organization"`
+	InvitedBy    string    `sql:"invitedBy" json:"invitedBy"`
+	Created      time.Time `sql:"created" json:"created"`
+	Expires      time.Time `sql:"expires" json:"expires"`
+	//Accepted - when the invitation was used to register, nil until then
+	Accepted  *time.Time `sql:"accepted" json:"accepted"`
+	AccountID string     `sql:"accountId" json:"accountId"`
+}
+
+//GetInvitationPermissions - gets the roles the invitation grants
+func (invitation *Invitation) GetInvitationPermissions() {
+	invitation.Roles = GetRoles(invitation.Role)
+}
@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+//LoginCode - a short code emailed to log in without a password. Each account has at most one
+type LoginCode struct {
+	ID        string `sql:"id"`
+	AccountID string `sql:"accountId"`
+	//Hash - sha256 of the code
+	Hash string `sql:"hash"`
+	//Attempts - how many times the code has been tried, right or wrong
+	Attempts int       `sql:"attempts"`
+	Created  time.Time `sql:"created"`
+	Expires  time.Time `sql:"expires"`
+}
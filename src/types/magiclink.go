@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+//MagicLink - a single use link emailed to log in without a password. It only works in the browser that asked for it
+type MagicLink struct {
+	ID        string `sql:"id"`
+	AccountID string `sql:"accountId"`
+	//Hash - sha256 of the token in the link
+	Hash string `sql:"hash"`
+	//NonceHash - sha256 of the nonce cookie given to the browser that asked for the link
+	NonceHash string    `sql:"nonceHash"`
+	Created   time.Time `sql:"created"`
+	Expires   time.Time `sql:"expires"`
+}
@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+//Outbox email statuses
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailDead    = "dead"
+)
+
+//OutboxEmail - an email queued to be sent by the outbox worker
+type OutboxEmail struct {
+	ID string `sql:"id" json:"id"`
+	//AccountID - the account the email is about, empty for invitations
+	AccountID string `sql:"accountId" json:"accountId"`
+	From      string `sql:"sender" json:"from"`
+	To        string `sql:"recipient" json:"to"`
+	Subject   string `sql:"subject" json:"subject"`
+	//Body - the html to send. It can hold one time links and codes, so it is never returned and is cleared once sent
+	Body string `sql:"body" json:"-"`
+	//Text - the plain text alternative of the body, treated the same way
+	Text        string    `sql:"textBody" json:"-"`
+	Status      string    `sql:"status" json:"status"`
+	Attempts    int       `sql:"attempts" json:"attempts"`
+	NextAttempt time.Time `sql:"nextAttempt" json:"nextAttempt"`
+	LastError   string    `sql:"lastError" json:"lastError"`
+	Created     time.Time `sql:"created" json:"created"`
+	Updated     time.Time `sql:"updated" json:"updated"`
+}
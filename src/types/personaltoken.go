@@ -0,0 +1,60 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+//Personal access token scopes
+const (
+	ScopeAccountRead  = "account:read"
+	ScopeAccountWrite = "account:write"
+	ScopeAdminRead    = "admin:read"
+	ScopeAdminWrite   = "admin:write"
+	//ScopeSession - never granted to a token, endpoints that ask for it only accept a login session
+	ScopeSession = ""
+)
+
+//Scopes - every scope a personal access token can be given
+var Scopes = []string{ScopeAccountRead, ScopeAccountWrite, ScopeAdminRead, ScopeAdminWrite}
+
+//PersonalTokenPrefix - starts every personal access token so they can be told apart from JWTs
+const PersonalTokenPrefix = "pat_"
+
+//Personal access token lifetimes in days
+const (
+	PersonalTokenDefaultDays = 30
+	PersonalTokenMaxDays     = 365
+)
+
+//PersonalToken - long lived token an account uses for scripts instead of its password
+type PersonalToken struct {
+	ID        string `sql:"id" json:"id"`
+	AccountID string `sql:"accountId" json:"-"`
+	Name      string `sql:"name" json:"name"`
+	//Hash - sha256 of the secret part of the token
+	Hash string `sql:"hash" json:"-"`
+	//Scope - the scopes separated by spaces, as stored
+	Scope  string   `sql:"scopes" json:"-"`
+	Scopes []string `sql:"-" json:"scopes"`
+	//Token - the full token, only returned when it is created
+	Token    string     `json:"token,omitempty"`
+	Created  time.Time  `sql:"created" json:"created"`
+	Expires  time.Time  `sql:"expires" json:"expires"`
+	LastUsed *time.Time `sql:"lastUsed" json:"lastUsed"`
+}
+
+//GetScopes - fills Scopes from the stored scope
+func (token *PersonalToken) GetScopes() {
+	token.Scopes = strings.Fields(token.Scope)
+}
+
+//HasScope - checks if the token was given a scope. Write scopes include read
+func (token *PersonalToken) HasScope(scope string) bool {
+	for _, have := range strings.Fields(token.Scope) {
+		if have == scope || (strings.HasSuffix(have, ":write") && strings.TrimSuffix(have, ":write")+":read" == scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package types
+
+import "time"
+
+//Phone code purposes other than a device ID, which is the purpose of codes that activate that device
+const (
+	PhoneCodeVerify = "verify"
+	PhoneCodeStepUp = "stepup"
+)
+
+//Phone code channels
+const (
+	PhoneChannelSMS   = "sms"
+	PhoneChannelVoice = "voice"
+)
+
+//PhoneCode - a short code sent by text message or phone call. Used codes are kept a day so sends to a number can be counted
+type PhoneCode struct {
+	ID        string `sql:"id"`
+	AccountID string `sql:"accountId"`
+	//Phone - the number it was sent to in E.164 form
+	Phone string `sql:"phone"`
+	//Purpose - PhoneCodeVerify, or the ID of the device it activates
+	Purpose string `sql:"purpose"`
+	Channel string `sql:"channel"`
+	//Hash - sha256 of the code
+	Hash string `sql:"hash"`
+	//Attempts - how many times the code has been tried, right or wrong
+	Attempts int       `sql:"attempts"`
+	Used     bool      `sql:"used"`
+	Created  time.Time `sql:"created"`
+	Expires  time.Time `sql:"expires"`
+}
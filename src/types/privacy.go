@@ -0,0 +1,138 @@
+package types
+
+import "time"
+
+//Erasure request statuses
+const (
+	//ErasureUnconfirmed - waiting for the account owner to confirm by email
+	ErasureUnconfirmed = "unconfirmed"
+	//ErasurePending - confirmed and waiting in the admin queue
+	ErasurePending   = "pending"
+	ErasureCompleted = "completed"
+	ErasureRejected  = "rejected"
+)
+
+//ErasureRequest - a request to erase everything stored about an account
+type ErasureRequest struct {
+	ID        string    `sql:"id" json:"id"`
+	AccountID string    `sql:"accountId" json:"accountId"`
+	Token     string    `sql:"token" json:"-"`
+	Status    string    `sql:"status" json:"status"`
+	Note      string    `sql:"note" json:"note"`
+	HandledBy string    `sql:"handledBy" json:"handledBy"`
+	Created   time.Time `sql:"created" json:"created"`
+	Updated   time.Time `sql:"updated" json:"updated"`
+}
+
+//DataExport - everything stored about an account
+type DataExport struct {
+	Exported        time.Time        `json:"exported"`
+	Account         ExportAccount    `json:"account"`
+	Devices         []ExportDevice   `json:"devices"`
+	Sessions        []ExportSession  `json:"sessions"`
+	AuditEvents     []AuditEvent     `json:"auditEvents"`
+	ErasureRequests []ErasureRequest `json:"erasureRequests"`
+	PersonalTokens  []PersonalToken  `json:"personalTokens"`
+}
+
+//ExportAccount - every account field except the password hash
+type ExportAccount struct {
+	ID             string     `json:"id"`
+	FirstName      string     `json:"firstName"`
+	LastName       string     `json:"lastName"`
+	Phone          string     `json:"phone"`
+	Email          string     `json:"email"`
+	Organization   string     `json:"organization"`
+	Roles          []string   `json:"roles"`
+	TwoFA          bool       `json:"twoFA"`
+	Disabled       bool       `json:"disabled"`
+	DisabledReason string     `json:"disabledReason"`
+	Deleted        *time.Time `json:"deleted"`
+	Created        time.Time  `json:"created"`
+}
+
+//ExportDevice - a login device without its activation code
+type ExportDevice struct {
+	ID        string     `json:"id"`
+	Active    bool       `json:"active"`
+	Created   time.Time  `json:"created"`
+	FirstIP   string     `json:"firstIp"`
+	LastIP    string     `json:"lastIp"`
+	UserAgent string     `json:"userAgent"`
+	Browser   string     `json:"browser"`
+	OS        string     `json:"os"`
+	Country   string     `json:"country"`
+	Location  string     `json:"location"`
+	LastSeen  *time.Time `json:"lastSeen"`
+}
+
+//ExportSession - a refresh token without the token itself
+type ExportSession struct {
+	DeviceID  string     `json:"deviceId"`
+	Created   time.Time  `json:"created"`
+	FirstIP   string     `json:"firstIp"`
+	LastIP    string     `json:"lastIp"`
+	UserAgent string     `json:"userAgent"`
+	Browser   string     `json:"browser"`
+	OS        string     `json:"os"`
+	Country   string     `json:"country"`
+	Location  string     `json:"location"`
+	LastSeen  *time.Time `json:"lastSeen"`
+}
+
+//NewDataExport - creates an export of the account, its devices and sessions
+func NewDataExport(account *Account, devices *[]Device, tokens *[]RefreshToken) *DataExport {
+	export := &DataExport{
+		Exported: time.Now(),
+		Account: ExportAccount{
+			ID:             account.ID,
+			FirstName:      account.FirstName,
+			LastName:       account.LastName,
+			Phone:          account.Phone,
+			Email:          account.Email,
+			Organization:   account.Organization,
+			Roles:          GetRoles(account.Role),
+			TwoFA:          account.TwoFA,
+			Disabled:       account.Disabled,
+			DisabledReason: account.DisabledReason,
+			Deleted:        account.Deleted,
+			Created:        account.Created,
+		},
+		Devices:         []ExportDevice{},
+		Sessions:        []ExportSession{},
+		AuditEvents:     []AuditEvent{},
+		ErasureRequests: []ErasureRequest{},
+	}
+
+	for _, device := range *devices {
+		export.Devices = append(export.Devices, ExportDevice{
+			ID:        device.ID,
+			Active:    device.Active,
+			Created:   device.Created,
+			FirstIP:   device.FirstIP,
+			LastIP:    device.LastIP,
+			UserAgent: device.UserAgent,
+			Browser:   device.Browser,
+			OS:        device.OS,
+			Country:   device.Country,
+			Location:  device.Location,
+			LastSeen:  device.LastSeen,
+		})
+	}
+	for _, token := range *tokens {
+		export.Sessions = append(export.Sessions, ExportSession{
+			DeviceID:  token.DeviceID,
+			Created:   token.Created,
+			FirstIP:   token.FirstIP,
+			LastIP:    token.LastIP,
+			UserAgent: token.UserAgent,
+			Browser:   token.Browser,
+			OS:        token.OS,
+			Country:   token.Country,
+			Location:  token.Location,
+			LastSeen:  token.LastSeen,
+		})
+	}
+
+	return export
+}
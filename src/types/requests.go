@@ -1,15 +1,126 @@
 package types
 
+import "time"
+
+//Client - details about who sent a request
+type Client struct {
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
 //Login - details required to login
 type Login struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	DeviceID string
+	Client
+}
+
+//MagicLinkRequest - email to send a magic login link to
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+	Client
+}
+
+//MagicLoginRequest - a magic login link being used
+type MagicLoginRequest struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+	//Nonce - from the cookie set on the browser that requested the link
+	Nonce    string `json:"-"`
+	DeviceID string `json:"-"`
+	Client
+}
+
+//LoginCodeRequest - email to send a login code to
+type LoginCodeRequest struct {
+	Email string `json:"email"`
+	Client
+}
+
+//CodeLoginRequest - an emailed login code being used
+type CodeLoginRequest struct {
+	Email    string `json:"email"`
+	Code     string `json:"code"`
+	DeviceID string `json:"-"`
+	Client
+}
+
+//AccountSearchRequest - filters, sorting and paging for listing accounts. Empty fields match everything
+type AccountSearchRequest struct {
+	//Roles - any of these roles, a 0 role matches every account
+	Roles        []int     `json:"roles"`
+	Disabled     *bool     `json:"disabled"`
+	TwoFA        *bool     `json:"twoFA"`
+	Organization string    `json:"organization"`
+	CreatedSince time.Time `json:"createdSince"`
+	CreatedUntil time.Time `json:"createdUntil"`
+	//Deleted - list only soft deleted accounts, they are left out otherwise
+	Deleted bool `json:"deleted"`
+	//Query - free text matched against name, email and phone
+	Query  string `json:"query"`
+	Sort   string `json:"sort"`
+	Desc   bool   `json:"desc"`
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
+
+//RegisterRequest - details of the new account and the invitation it registers with, if any
+type RegisterRequest struct {
+	Account
+	InvitationID    string `json:"invitationId"`
+	InvitationToken string `json:"invitationToken"`
+}
+
+//InvitationRequest - email to invite and the roles and organization its account starts with
+type InvitationRequest struct {
+	Email        string   `json:"email"`
+	Roles        []string `json:"roles"`
+	Organization string   `json:"organization"`
+}
+
+//InvitationsRequest - filters for listing invitations
+type InvitationsRequest struct {
+	//Pending - list only invitations that have not been accepted
+	Pending bool `json:"pending"`
+	Limit   int  `json:"limit"`
+}
+
+//InvitationIDRequest - Id of the invitation being revoked
+type InvitationIDRequest struct {
+	ID string `json:"id"`
+}
+
+//ImportAccountsRequest - accounts to create in bulk. CSV is sent as text in Data, JSON as the Accounts list
+type ImportAccountsRequest struct {
+	Format   string        `json:"format"`
+	Data     string        `json:"data"`
+	Accounts []BulkAccount `json:"accounts"`
+	//DryRun - validate every row and report without creating anything
+	DryRun bool `json:"dryRun"`
+	//Invite - email each row an invitation with its roles and organization instead of creating the account
+	Invite bool `json:"invite"`
+}
+
+//ExportAccountsRequest - accounts to export and how
+type ExportAccountsRequest struct {
+	Format string `json:"format"`
+	//Search - filters and sort for the accounts exported. Paging is ignored
+	Search AccountSearchRequest `json:"search"`
+	//PasswordHashes - include bcrypt hashes so the accounts can be imported elsewhere
+	PasswordHashes bool `json:"passwordHashes"`
 }
 
-//GetAccountsRequest - type of account wanted
-type GetAccountsRequest struct {
-	Roles []int `json:"roles"`
+//SCIMTokenRequest - organization a SCIM token can provision accounts for
+type SCIMTokenRequest struct {
+	Organization string `json:"organization"`
+	Description  string `json:"description"`
+	ManageAdmins bool   `json:"manageAdmins"`
+}
+
+//SCIMTokenIDRequest - Id of the SCIM token being revoked
+type SCIMTokenIDRequest struct {
+	ID string `json:"id"`
 }
 
 //DeleteAccountRequest - Id of the account being deletes
@@ -17,21 +128,75 @@ type DeleteAccountRequest struct {
 	ID string `json:"id"`
 }
 
+//DisableAccountRequest - account being disabled and why
+type DisableAccountRequest struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+//ErasureConfirmRequest - confirms an erasure request with the token from the email
+type ErasureConfirmRequest struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+	Client
+}
+
+//ErasureRequestsRequest - filters for the erasure request queue
+type ErasureRequestsRequest struct {
+	Status string `json:"status"`
+	Limit  int    `json:"limit"`
+}
+
+//ErasureDecisionRequest - an admin approving or rejecting an erasure request
+type ErasureDecisionRequest struct {
+	ID      string `json:"id"`
+	Approve bool   `json:"approve"`
+	Note    string `json:"note"`
+}
+
+//AccountIDRequest - Id of the account being enabled or restored
+type AccountIDRequest struct {
+	ID string `json:"id"`
+}
+
 //ActivateDevice - device activation struct
 type ActivateDevice struct {
 	Code     string
 	DeviceID string
+	Client
+}
+
+//PhoneCodeRequest - how to send a phone code, by sms or voice. DeviceID is set when the code activates a device
+type PhoneCodeRequest struct {
+	Channel  string `json:"channel"`
+	DeviceID string `json:"-"`
+	Client
+}
+
+//ReauthenticateRequest - the password, or a code sent to the verified phone, to re-authenticate with
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+	Client
+}
+
+//PhoneVerifyRequest - a code sent to the phone of the account
+type PhoneVerifyRequest struct {
+	Code string `json:"code"`
+	Client
 }
 
 //RecoveryRequest - struct for creating a recovery request
 type RecoveryRequest struct {
 	Email string `json:"email"`
+	Client
 }
 
 //FinalRecoveryRequest - struct for finishing a recovery
 type FinalRecoveryRequest struct {
 	ID       string `json:"id"`
 	Password string `json:"password"`
+	Client
 }
 
 //VerifyBrokerRequest - struct to verifiy a broker
@@ -44,3 +209,86 @@ type UpdateAccountPassword struct {
 	OldPassword string `json:"oldPassword"`
 	NewPassword string `json:"newPassword"`
 }
+
+//AuditSearchRequest - filters for searching the audit log. Empty fields match everything
+type AuditSearchRequest struct {
+	Type           string    `json:"type"`
+	ActorID        string    `json:"actorId"`
+	ImpersonatorID string    `json:"impersonatorId"`
+	TargetID       string    `json:"targetId"`
+	Outcome        string    `json:"outcome"`
+	IP             string    `json:"ip"`
+	Since          time.Time `json:"since"`
+	Until          time.Time `json:"until"`
+	Cursor         string    `json:"cursor"`
+	Limit          int       `json:"limit"`
+}
+
+//WebhookRequest - struct to create a webhook subscription
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Client
+}
+
+//WebhookIDRequest - id of a webhook subscription or delivery
+type WebhookIDRequest struct {
+	ID string `json:"id"`
+	Client
+}
+
+//WebhookDeliveriesRequest - filters for the webhook delivery log
+type WebhookDeliveriesRequest struct {
+	SubscriptionID string `json:"subscriptionId"`
+	Status         string `json:"status"`
+	Limit          int    `json:"limit"`
+}
+
+//OutboxRequest - filters for the email outbox
+type OutboxRequest struct {
+	Status string `json:"status"`
+	Limit  int    `json:"limit"`
+}
+
+//OutboxIDRequest - id of the outbox email being sent again
+type OutboxIDRequest struct {
+	ID string `json:"id"`
+}
+
+//EmailPreviewRequest - the email template to render and the values to render it with. Values not given use samples
+type EmailPreviewRequest struct {
+	Template     string     `json:"template"`
+	Language     string     `json:"language"`
+	Organization string     `json:"organization"`
+	Email        string     `json:"email"`
+	Name         string     `json:"name"`
+	Link         string     `json:"link"`
+	Code         string     `json:"code"`
+	Expires      *time.Time `json:"expires"`
+	Blocked      bool       `json:"blocked"`
+}
+
+//EmailTestRequest - an email template to render and send to an address
+type EmailTestRequest struct {
+	EmailPreviewRequest
+	To string `json:"to"`
+}
+
+//PersonalTokenRequest - struct to create a personal access token
+type PersonalTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	//ExpiresDays - how many days the token lasts, PersonalTokenDefaultDays if not set
+	ExpiresDays int `json:"expiresDays"`
+}
+
+//PersonalTokenIDRequest - id of the personal access token being revoked
+type PersonalTokenIDRequest struct {
+	ID string `json:"id"`
+}
+
+//ImpersonateRequest - the account an admin wants to act as and why
+type ImpersonateRequest struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
@@ -1,6 +1,9 @@
 package types
 
-import "signer"
+import (
+	"signer"
+	"time"
+)
 
 //GenericResponse - Simple response
 type GenericResponse struct {
@@ -25,9 +28,49 @@ type AccessTokenResponse struct {
 	AccessToken string `json:"accessToken"`
 }
 
-//AllUsersResponse - return success with data
-type AllUsersResponse struct {
-	Accounts *[]Account `json:"accounts"`
+//PhoneCodeResponse - where a phone code was sent, with all but the end of the number hidden
+type PhoneCodeResponse struct {
+	Phone   string    `json:"phone"`
+	Channel string    `json:"channel"`
+	Expires time.Time `json:"expires"`
+}
+
+//AccountsResponse - a page of accounts and how many match in total
+type AccountsResponse struct {
+	Accounts   *[]Account `json:"accounts"`
+	NextCursor string     `json:"nextCursor"`
+	Total      int        `json:"total"`
+}
+
+//AuditEventsResponse - a page of audit events
+type AuditEventsResponse struct {
+	Events     *[]AuditEvent `json:"events"`
+	NextCursor string        `json:"nextCursor"`
+}
+
+//ErasureRequestsResponse - returns erasure requests
+type ErasureRequestsResponse struct {
+	Requests *[]ErasureRequest `json:"requests"`
+}
+
+//InvitationsResponse - returns invitations
+type InvitationsResponse struct {
+	Invitations *[]Invitation `json:"invitations"`
+}
+
+//SCIMTokensResponse - returns SCIM tokens
+type SCIMTokensResponse struct {
+	Tokens *[]SCIMToken `json:"tokens"`
+}
+
+//WebhooksResponse - returns webhook subscriptions
+type WebhooksResponse struct {
+	Webhooks *[]WebhookSubscription `json:"webhooks"`
+}
+
+//WebhookDeliveriesResponse - returns webhook deliveries
+type WebhookDeliveriesResponse struct {
+	Deliveries *[]WebhookDelivery `json:"deliveries"`
 }
 
 //ReasonResponse - return response with a reason
@@ -53,3 +96,27 @@ type ErrorResponseBody struct {
 	ErrorCode      int    `json:"errorCode"`
 	ErrorMsg       string `json:"errorMsg"`
 }
+
+//PersonalTokensResponse - returns personal access tokens
+type PersonalTokensResponse struct {
+	Tokens *[]PersonalToken `json:"tokens"`
+}
+
+//OutboxResponse - returns outbox emails
+type OutboxResponse struct {
+	Emails *[]OutboxEmail `json:"emails"`
+}
+
+//EmailPreviewResponse - a rendered email
+type EmailPreviewResponse struct {
+	From    string `json:"from"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+//ImpersonationResponse - the access token to act as an account with. It cannot be refreshed
+type ImpersonationResponse struct {
+	AccessToken string    `json:"accessToken"`
+	Expires     time.Time `json:"expires"`
+}
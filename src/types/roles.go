@@ -9,3 +9,13 @@ func GetRoles(role int) []string {
 		return []string{"DEFAULT"}
 	}
 }
+
+//GetRole - returns the permission level that grants the roles given
+func GetRole(roles []string) int {
+	for _, role := range roles {
+		if role == "ADMIN" {
+			return 999
+		}
+	}
+	return 100
+}
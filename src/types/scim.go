@@ -0,0 +1,170 @@
+package types
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+//SCIM schema and message URNs
+const (
+	SCIMUserSchema            = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMEnterpriseUserSchema  = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SCIMGroupSchema           = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMServiceProviderSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIMResourceTypeSchema    = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SCIMSchemaSchema          = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SCIMListSchema            = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchSchema           = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema           = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+//SCIM error types
+const (
+	SCIMInvalidFilter = "invalidFilter"
+	SCIMInvalidValue  = "invalidValue"
+	SCIMInvalidPath   = "invalidPath"
+	SCIMInvalidSyntax = "invalidSyntax"
+	SCIMUniqueness    = "uniqueness"
+	SCIMMutability    = "mutability"
+	SCIMNoTarget      = "noTarget"
+)
+
+//SCIM list page sizes
+const (
+	SCIMDefaultPageLimit = 100
+	SCIMMaxPageLimit     = 500
+)
+
+//SCIMToken - bearer token a provisioning client uses to manage the accounts of one organization
+type SCIMToken struct {
+	ID           string `sql:"id" json:"id"`
+	Organization string `sql:"organization" json:"organization"`
+	//Hash - sha256 of the secret part of the token
+	Hash        string `sql:"hash" json:"-"`
+	Description string `sql:"description" json:"description"`
+	//ManageAdmins - the token can promote accounts to ADMIN and demote them. ADMIN is not limited to an organization, so only an admin can give a token this
+	ManageAdmins bool `sql:"manageAdmins" json:"manageAdmins"`
+	//Token - the full token, only returned when it is created
+	Token    string     `json:"token,omitempty"`
+	Created  time.Time  `sql:"created" json:"created"`
+	LastUsed *time.Time `sql:"lastUsed" json:"lastUsed"`
+}
+
+//SCIMUser - a SCIM User resource
+type SCIMUser struct {
+	Schemas           []string        `json:"schemas"`
+	ID                string          `json:"id,omitempty"`
+	ExternalID        string          `json:"externalId,omitempty"`
+	UserName          string          `json:"userName"`
+	Name              SCIMName        `json:"name"`
+	DisplayName       string          `json:"displayName,omitempty"`
+	Emails            []SCIMValue     `json:"emails,omitempty"`
+	PreferredLanguage string          `json:"preferredLanguage,omitempty"`
+	PhoneNumbers      []SCIMValue     `json:"phoneNumbers,omitempty"`
+	Roles             []SCIMValue     `json:"roles,omitempty"`
+	Groups            []SCIMMember    `json:"groups,omitempty"`
+	Active            *bool           `json:"active,omitempty"`
+	Password          string          `json:"password,omitempty"`
+	Enterprise        *SCIMEnterprise `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta              *SCIMMeta       `json:"meta,omitempty"`
+}
+
+//SCIMName - the name of a SCIM user
+type SCIMName struct {
+	GivenName  string `json:"givenName"`
+	FamilyName string `json:"familyName"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+//SCIMValue - one value of a multi valued attribute such as emails
+type SCIMValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+//SCIMEnterprise - the enterprise user extension
+type SCIMEnterprise struct {
+	Organization string `json:"organization"`
+}
+
+//SCIMGroup - a SCIM Group resource. Groups are the account roles
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members,omitempty"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+//SCIMMember - a reference from a group to a user, or a user to a group
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+//SCIMMeta - resource metadata
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	Location     string     `json:"location"`
+}
+
+//SCIMListResponse - a page of resources
+type SCIMListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+//SCIMListRequest - filter and paging for listing resources
+type SCIMListRequest struct {
+	Filter string
+	//StartIndex - 1 based index of the first result
+	StartIndex int
+	Count      int
+}
+
+//SCIMPatchRequest - a PATCH request body
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+//SCIMPatchOperation - one change in a PATCH request
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+//SCIMError - a SCIM error response
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+	//StatusCode - the HTTP status to send
+	StatusCode int `json:"-"`
+}
+
+//NewSCIMError - returns a SCIM error with the HTTP status given
+func NewSCIMError(status int, scimType string, detail string) *SCIMError {
+	return &SCIMError{
+		Schemas:    []string{SCIMErrorSchema},
+		Status:     strconv.Itoa(status),
+		ScimType:   scimType,
+		Detail:     detail,
+		StatusCode: status,
+	}
+}
+
+//Error - returns the detail of the error
+func (e *SCIMError) Error() string {
+	return e.Detail
+}
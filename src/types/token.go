@@ -4,14 +4,38 @@ import "time"
 
 //RefreshToken - Refresh token struct
 type RefreshToken struct {
-	ID        string    `sql:"id" json:"id"`
-	AccountID string    `sql:"accountId" json:"accountId"`
-	DeviceID  string    `sql:"deviceId" json:"deviceId"`
-	Created   time.Time `sql:"created" json:"created"`
+	ID        string     `sql:"id" json:"id"`
+	AccountID string     `sql:"accountId" json:"accountId"`
+	DeviceID  string     `sql:"deviceId" json:"deviceId"`
+	Created   time.Time  `sql:"created" json:"created"`
+	FirstIP   string     `sql:"firstIp" json:"firstIp"`
+	LastIP    string     `sql:"lastIp" json:"lastIp"`
+	UserAgent string     `sql:"userAgent" json:"userAgent"`
+	Browser   string     `sql:"browser" json:"browser"`
+	OS        string     `sql:"os" json:"os"`
+	Country   string     `sql:"country" json:"country"`
+	Location  string     `sql:"location" json:"location"`
+	LastSeen  *time.Time `sql:"lastSeen" json:"lastSeen"`
+}
+
+//Seen - records the client the session was just used from
+func (token *RefreshToken) Seen(client *ClientDetails) {
+	now := time.Now()
+	if token.FirstIP == "" {
+		token.FirstIP = client.IP
+	}
+	token.LastIP = client.IP
+	token.UserAgent = client.UserAgent
+	token.Browser = client.Browser
+	token.OS = client.OS
+	token.Country = client.Country
+	token.Location = client.Location
+	token.LastSeen = &now
 }
 
 //AuthTokens - AuthTokens struct
 type AuthTokens struct {
 	AccessToken  string
 	RefreshToken string
+	Client
 }
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+//Webhook event types
+const (
+	WebhookAccountCreated  = "account.created"
+	WebhookAccountUpdated  = "account.updated"
+	WebhookAccountDisabled = "account.disabled"
+	WebhookAccountEnabled  = "account.enabled"
+	WebhookAccountDeleted  = "account.deleted"
+	WebhookAccountRestored = "account.restored"
+	WebhookAccountPurged   = "account.purged"
+	WebhookAccountErased   = "account.erased"
+	WebhookDeviceActivated = "device.activated"
+	WebhookAllEvents       = "*"
+)
+
+//Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+//WebhookEvents - every event a subscription can ask for
+var WebhookEvents = []string{WebhookAccountCreated, WebhookAccountUpdated, WebhookAccountDisabled, WebhookAccountEnabled, WebhookAccountDeleted, WebhookAccountRestored, WebhookAccountPurged, WebhookAccountErased, WebhookDeviceActivated}
+
+//WebhookSubscription - an endpoint that wants to be told about events
+type WebhookSubscription struct {
+	ID         string    `sql:"id" json:"id"`
+	URL        string    `sql:"url" json:"url"`
+	Secret     string    `sql:"secret" json:"secret,omitempty"`
+	Events     string    `sql:"events" json:"-"`
+	EventTypes []string  `json:"events"`
+	Active     bool      `sql:"active" json:"active"`
+	Created    time.Time `sql:"created" json:"created"`
+}
+
+//GetEventTypes - fills EventTypes from the stored events
+func (sub *WebhookSubscription) GetEventTypes() {
+	sub.EventTypes = []string{}
+	for _, e := range strings.Split(sub.Events, ",") {
+		if e != "" {
+			sub.EventTypes = append(sub.EventTypes, e)
+		}
+	}
+}
+
+//SetEventTypes - fills the stored events from EventTypes
+func (sub *WebhookSubscription) SetEventTypes() {
+	sub.Events = strings.Join(sub.EventTypes, ",")
+}
+
+//Wants - returns true if the subscription wants the given event
+func (sub *WebhookSubscription) Wants(event string) bool {
+	for _, e := range strings.Split(sub.Events, ",") {
+		if e == event || e == WebhookAllEvents {
+			return true
+		}
+	}
+	return false
+}
+
+//HideImportant - Hides the signing secret
+func (sub *WebhookSubscription) HideImportant() {
+	sub.Secret = ""
+}
+
+//WebhookDelivery - a queued or completed delivery of an event to a subscription
+type WebhookDelivery struct {
+	ID             string    `sql:"id" json:"id"`
+	SubscriptionID string    `sql:"subscriptionId" json:"subscriptionId"`
+	AccountID      string    `sql:"accountId" json:"accountId"`
+	Event          string    `sql:"event" json:"event"`
+	Payload        string    `sql:"payload" json:"payload"`
+	Status         string    `sql:"status" json:"status"`
+	Attempts       int       `sql:"attempts" json:"attempts"`
+	NextAttempt    time.Time `sql:"nextAttempt" json:"nextAttempt"`
+	ResponseCode   int       `sql:"responseCode" json:"responseCode"`
+	LastError      string    `sql:"lastError" json:"lastError"`
+	Created        time.Time `sql:"created" json:"created"`
+	Updated        time.Time `sql:"updated" json:"updated"`
+}
+
+//WebhookPayload - body sent to a webhook endpoint
+type WebhookPayload struct {
+	ID      string      `json:"id"`
+	Event   string      `json:"event"`
+	Created time.Time   `json:"created"`
+	Data    interface{} `json:"data"`
+}
+
+//WebhookAccount - account details sent in webhook payloads
+type WebhookAccount struct {
+	ID        string    `json:"id"`
+	FirstName string    `json:"firstName"`
+	LastName  string    `json:"lastName"`
+	Phone     string    `json:"phone"`
+	Email     string    `json:"email"`
+	Roles     []string  `json:"roles"`
+	Disabled  bool      `json:"disabled"`
+	Created   time.Time `json:"created"`
+
+	Organization   string     `json:"organization"`
+	DisabledReason string     `json:"disabledReason,omitempty"`
+	Deleted        *time.Time `json:"deleted,omitempty"`
+}
+
+//NewWebhookAccount - returns the account details that are safe to send to other services
+func NewWebhookAccount(account *Account) *WebhookAccount {
+	return &WebhookAccount{
+		ID:        account.ID,
+		FirstName: account.FirstName,
+		LastName:  account.LastName,
+		Phone:     account.Phone,
+		Email:     account.Email,
+		Roles:     GetRoles(account.Role),
+		Disabled:  account.Disabled,
+		Created:   account.Created,
+
+		Organization:   account.Organization,
+		DisabledReason: account.DisabledReason,
+		Deleted:        account.Deleted,
+	}
+}
+
+//WebhookDevice - device details sent in webhook payloads
+type WebhookDevice struct {
+	ID        string    `json:"id"`
+	AccountID string    `json:"accountId"`
+	Active    bool      `json:"active"`
+	Created   time.Time `json:"created"`
+}
+
+//NewWebhookDevice - returns the device details that are safe to send to other services
+func NewWebhookDevice(device *Device) *WebhookDevice {
+	return &WebhookDevice{
+		ID:        device.ID,
+		AccountID: device.AccountID,
+		Active:    device.Active,
+		Created:   device.Created,
+	}
+}
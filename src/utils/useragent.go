@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+//userAgentBrowsers - product tokens checked in order, as most browsers also claim to be the ones before them
+var userAgentBrowsers = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Vivaldi/", "Vivaldi"},
+	{"YaBrowser/", "Yandex"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+	{"MSIE ", "Internet Explorer"},
+	{"Trident/", "Internet Explorer"},
+	{"curl/", "curl"},
+	{"Go-http-client/", "Go"},
+	{"okhttp/", "OkHttp"},
+}
+
+//userAgentSystems - operating system tokens checked in order, iOS and Android also claim to be macOS and Linux
+var userAgentSystems = []struct {
+	token string
+	name  string
+}{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Windows", "Windows"},
+	{"Macintosh", "macOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+var userAgentVersion = regexp.MustCompile(`^\d+`)
+
+//ParseUserAgent - returns the browser with its major version and the operating system of a user agent. Empty when not recognised
+func ParseUserAgent(userAgent string) (browser string, os string) {
+	for _, b := range userAgentBrowsers {
+		i := strings.Index(userAgent, b.token)
+		if i < 0 {
+			continue
+		}
+
+		//Trident reports its own version, not the browser's
+		browser = b.name
+		if version := userAgentVersion.FindString(userAgent[i+len(b.token):]); version != "" && b.token != "Trident/" {
+			browser += " " + version
+		}
+		break
+	}
+
+	for _, s := range userAgentSystems {
+		if strings.Contains(userAgent, s.token) {
+			os = s.name
+			break
+		}
+	}
+
+	return browser, os
+}
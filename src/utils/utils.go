@@ -1,6 +1,10 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
 	"math/rand"
 	"time"
 
@@ -28,6 +32,28 @@ func RandomCode() string {
 	return string(b)
 }
 
+//RandomSecret - returns a cryptographically random hex string of n bytes
+func RandomSecret(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+//RandomDigits - returns a cryptographically random code of n digits
+func RandomDigits(n int) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		digit, err := crand.Int(crand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + digit.Int64())
+	}
+	return string(b), nil
+}
+
 //HashPassword - returns a has of the given password.
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
@@ -40,6 +66,12 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+//IsPasswordHash - checks if the string is a bcrypt hash, such as one imported from another system
+func IsPasswordHash(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
 //Schedule - set an interval timer
 func Schedule(what func(), delay time.Duration) chan bool {
 	stop := make(chan bool)
@@ -68,11 +100,22 @@ func Contains(a string, list []string) bool {
 	return false
 }
 
-//IsExpired - checks if JWT had any validation issues.
+//ErrInvalidToken - a personal access token or impersonation token that is unknown, expired, revoked or belongs to an account that cannot log in
+var ErrInvalidToken = errors.New("access token is invalid")
+
+//IsExpired - checks if JWT had any validation issues, or the personal access or impersonation token used was invalid.
 func IsExpired(err error) bool {
 	if _, ok := err.(*jwt.ValidationError); ok {
 		return true
 	}
 
-	return false
+	return err == ErrInvalidToken
+}
+
+//ErrStepUpRequired - the access token is valid but its login is not recent or strong enough for the request
+var ErrStepUpRequired = errors.New("a more recent login is required")
+
+//IsStepUpRequired - checks if the request needs the account to re-authenticate first
+func IsStepUpRequired(err error) bool {
+	return err == ErrStepUpRequired
 }
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"dao"
+	"db"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+	"types"
+	"utils"
+)
+
+//Dispatcher - delivers queued webhook events with retries
+type Dispatcher struct {
+	DB          db.Store
+	Client      *http.Client
+	MaxAttempts int
+	RetryBase   time.Duration
+	RetryMax    time.Duration
+}
+
+//Init - start delivering queued webhooks in the background
+func (d Dispatcher) Init(db db.Store) *Dispatcher {
+	d.DB = db
+
+	attempts, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		attempts = 8
+	}
+	d.MaxAttempts = attempts
+
+	base, err := strconv.Atoi(os.Getenv("WEBHOOK_RETRY_SECONDS"))
+	if err != nil || base < 1 {
+		base = 30
+	}
+	d.RetryBase = time.Duration(base) * time.Second
+	d.RetryMax = 6 * time.Hour
+
+	timeout, err := strconv.Atoi(os.Getenv("WEBHOOK_TIMEOUT_SECONDS"))
+	if err != nil || timeout < 1 {
+		timeout = 10
+	}
+	d.Client = &http.Client{Timeout: time.Duration(timeout) * time.Second}
+
+	dispatcher := &d
+
+	//Setup interval to send due deliveries
+	utils.Schedule(dispatcher.DeliverDue, 15*time.Second)
+
+	return dispatcher
+}
+
+//DeliverDue - attempts every delivery that is due
+func (d *Dispatcher) DeliverDue() {
+	ctx := context.Background()
+
+	deliveries, err := dao.WebhookDAO{}.GetDueDeliveries(ctx, 100, d.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Webhook Error: "+err.Error())
+		return
+	}
+
+	for i := range *deliveries {
+		delivery := &(*deliveries)[i]
+
+		//Hold the delivery for longer than a request can take so a slow endpoint isnt sent it twice
+		claimed, err := dao.WebhookDAO{}.ClaimDelivery(ctx, delivery, time.Now().Add(2*d.Client.Timeout), d.DB)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Webhook Error: "+err.Error())
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		d.attempt(ctx, delivery)
+
+		if err := (dao.WebhookDAO{}).UpdateDelivery(ctx, delivery, d.DB); err != nil {
+			fmt.Fprintln(os.Stderr, "Webhook Error: "+err.Error())
+		}
+	}
+}
+
+//attempt - sends a delivery once and records the outcome on it
+func (d *Dispatcher) attempt(ctx context.Context, delivery *types.WebhookDelivery) {
+	delivery.Attempts++
+
+	sub, err := dao.WebhookDAO{}.GetSubscription(ctx, delivery.SubscriptionID, d.DB)
+	if err != nil {
+		d.retry(delivery, 0, err.Error())
+		return
+	}
+
+	//Subscription was removed or turned off, nobody is left to deliver to
+	if sub == nil || !sub.Active {
+		delivery.Status = types.DeliveryDead
+		delivery.LastError = "Subscription is no longer active"
+		return
+	}
+
+	code, err := d.send(ctx, sub, delivery)
+	if err != nil {
+		d.retry(delivery, code, err.Error())
+		return
+	}
+
+	delivery.Status = types.DeliveryDelivered
+	delivery.ResponseCode = code
+	delivery.LastError = ""
+}
+
+//send - posts the signed payload to the subscription url
+func (d *Dispatcher) send(ctx context.Context, sub *types.WebhookSubscription, delivery *types.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "JWT_Auth-Webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(sub.Secret, timestamp, delivery.Payload))
+
+	res, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	//Drain so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("Endpoint returned status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+//retry - schedules the next attempt with exponential back off, or dead letters the delivery once out of attempts
+func (d *Dispatcher) retry(delivery *types.WebhookDelivery, code int, reason string) {
+	delivery.ResponseCode = code
+	delivery.LastError = reason
+
+	if delivery.Attempts >= d.MaxAttempts {
+		delivery.Status = types.DeliveryDead
+		return
+	}
+
+	delay := d.RetryBase << uint(delivery.Attempts-1)
+	if delay > d.RetryMax || delay <= 0 {
+		delay = d.RetryMax
+	}
+
+	delivery.Status = types.DeliveryPending
+	delivery.NextAttempt = time.Now().Add(delay)
+}
+
+//Sign - returns the hex HMAC-SHA256 of "timestamp.payload" using the subscription secret.
+//Receivers should recompute this and reject stale timestamps.
+func Sign(secret string, timestamp string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}